@@ -0,0 +1,212 @@
+// Package achievements unlocks achievements when the memory of the emulated
+// system meets their conditions. Conditions use the rcheevos format of
+// RetroAchievements, like "0xH1234=5_d0xH1234=4.10.", and are evaluated each
+// frame. Achievements come from local definition files, no network is used,
+// and unlocks are saved for each player.
+package achievements
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/libretro/ludo/utils"
+)
+
+// Memory is the memory of the emulated system. Addresses are offsets in the
+// memory regions of the core put end to end, like cheats.Memory.
+type Memory interface {
+	Read(address uint, size int, bigEndian bool) (uint32, bool)
+}
+
+// Achievement is an entry of a definition file
+type Achievement struct {
+	ID          int
+	Title       string
+	Description string
+	Points      int
+	MemAddr     string // Condition string in the rcheevos format
+}
+
+// Set is the content of a definition file, with the fields of the
+// RetroAchievements patch data
+type Set struct {
+	Title        string
+	Achievements []Achievement
+}
+
+// Path returns the definition file of a game in a directory
+func Path(dir, gamePath string) string {
+	return filepath.Join(dir, utils.FileName(gamePath)+".json")
+}
+
+// Load reads a definition file
+func Load(path string) (Set, error) {
+	var set Set
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return set, err
+	}
+	err = json.Unmarshal(b, &set)
+	return set, err
+}
+
+// State is the progress of an achievement
+type State int
+
+// The states of an achievement
+const (
+	// Waiting achievements had their conditions met when the game started,
+	// they become active once the conditions are false
+	Waiting State = iota
+	Active
+	Unlocked
+)
+
+// Status is an achievement and its progress
+type Status struct {
+	Achievement
+	State State
+}
+
+type tracked struct {
+	Achievement
+	state   State
+	trigger *trigger
+}
+
+// Engine evaluates the achievements of a game
+type Engine struct {
+	achievements []*tracked
+	memrefs      []*memref
+}
+
+// NewEngine prepares the achievements of a set. The achievements in unlocked
+// are not evaluated again, and achievements with invalid conditions are
+// skipped.
+func NewEngine(set Set, unlocked map[int]time.Time) *Engine {
+	p := newParser()
+	e := &Engine{}
+	for _, a := range set.Achievements {
+		t, err := p.parseTrigger(a.MemAddr)
+		if err != nil {
+			log.Printf("[Achievements]: Skipping %q: %v\n", a.Title, err)
+			continue
+		}
+		state := Waiting
+		if _, ok := unlocked[a.ID]; ok {
+			state = Unlocked
+		}
+		e.achievements = append(e.achievements, &tracked{Achievement: a, state: state, trigger: t})
+	}
+	for _, ref := range p.memrefs {
+		e.memrefs = append(e.memrefs, ref)
+	}
+	return e
+}
+
+// Do evaluates the achievements for a frame and returns the ones unlocked
+func (e *Engine) Do(m Memory) []Achievement {
+	for _, ref := range e.memrefs {
+		ref.update(m)
+	}
+
+	unlocked := []Achievement{}
+	for _, a := range e.achievements {
+		switch a.state {
+		case Waiting:
+			if a.trigger.test() {
+				a.trigger.resetHits()
+			} else {
+				a.state = Active
+			}
+		case Active:
+			if a.trigger.test() {
+				a.state = Unlocked
+				unlocked = append(unlocked, a.Achievement)
+			}
+		}
+	}
+	return unlocked
+}
+
+// List returns the achievements and their progress
+func (e *Engine) List() []Status {
+	list := []Status{}
+	for _, a := range e.achievements {
+		list = append(list, Status{Achievement: a.Achievement, State: a.state})
+	}
+	return list
+}
+
+// Unlocks are the achievements unlocked by a player, by game and by ID
+type Unlocks map[string]map[int]time.Time
+
+// unlocksPath returns the file of the unlocks of a player
+func unlocksPath(dir, player string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(player)
+	return filepath.Join(dir, name+".json")
+}
+
+// LoadUnlocks reads the unlocks of a player. A player without unlocks has
+// an empty list.
+func LoadUnlocks(dir, player string) (Unlocks, error) {
+	u := Unlocks{}
+	b, err := os.ReadFile(unlocksPath(dir, player))
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	err = json.Unmarshal(b, &u)
+	return u, err
+}
+
+// Add records an achievement unlocked in a game
+func (u Unlocks) Add(game string, id int, at time.Time) {
+	if u[game] == nil {
+		u[game] = map[int]time.Time{}
+	}
+	u[game][id] = at
+}
+
+// Merge adds the unlocks of other
+func (u Unlocks) Merge(other Unlocks) {
+	for game, ids := range other {
+		for id, at := range ids {
+			u.Add(game, id, at)
+		}
+	}
+}
+
+// Save writes the unlocks of a player. The file is replaced atomically, so
+// a crash while saving never leaves a half-written history.
+func (u Unlocks) Save(dir, player string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".unlocks-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), unlocksPath(dir, player))
+}
@@ -0,0 +1,212 @@
+package achievements
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ram is a synthetic memory for the tests
+type ram []byte
+
+func (r ram) Read(address uint, size int, bigEndian bool) (uint32, bool) {
+	if address >= uint(len(r)) {
+		return 0, false
+	}
+	return uint32(r[address]), true
+}
+
+// run evaluates a condition string over frames, each frame writing some bytes
+// of memory, and returns the frames where the trigger was true
+func run(t *testing.T, memaddr string, frames []map[uint]byte) []int {
+	t.Helper()
+	p := newParser()
+	tr, err := p.parseTrigger(memaddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := make(ram, 16)
+	got := []int{}
+	for i, writes := range frames {
+		for address, v := range writes {
+			mem[address] = v
+		}
+		for _, ref := range p.memrefs {
+			ref.update(mem)
+		}
+		if tr.test() {
+			got = append(got, i)
+		}
+	}
+	return got
+}
+
+func TestTrigger(t *testing.T) {
+	tests := []struct {
+		name    string
+		memaddr string
+		frames  []map[uint]byte
+		want    []int
+	}{
+		{
+			name:    "Compares memory to a constant",
+			memaddr: "0xH0001=5",
+			frames:  []map[uint]byte{{1: 4}, {1: 5}, {}, {1: 6}},
+			want:    []int{1, 2},
+		},
+		{
+			name:    "Reads 16 bits little endian and hex constants",
+			memaddr: "0x 0002=h1234",
+			frames:  []map[uint]byte{{2: 0x34}, {3: 0x12}},
+			want:    []int{1},
+		},
+		{
+			name:    "Reads bits, nibbles and big endian values",
+			memaddr: "0xM0000=1_0xU0001=10_0xI0002=258",
+			frames:  []map[uint]byte{{0: 1, 1: 0xa0, 2: 1, 3: 2}, {0: 2}},
+			want:    []int{0},
+		},
+		{
+			name:    "Compares to the previous frame with delta",
+			memaddr: "0xH0001=3_d0xH0001=2",
+			frames:  []map[uint]byte{{1: 2}, {1: 3}, {}, {1: 2}, {1: 3}},
+			want:    []int{1, 4},
+		},
+		{
+			name:    "Compares to the last different value with prior",
+			memaddr: "0xH0001=3_p0xH0001=1",
+			frames:  []map[uint]byte{{1: 1}, {1: 3}, {}, {}},
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "Needs the hits of a hit count",
+			memaddr: "0xH0001=1.3.",
+			frames:  []map[uint]byte{{1: 1}, {1: 0}, {1: 1}, {}, {1: 0}},
+			want:    []int{3, 4},
+		},
+		{
+			name:    "ResetIf clears the hits",
+			memaddr: "0xH0001=1.3._R:0xH0002=1",
+			frames:  []map[uint]byte{{1: 1}, {}, {2: 1}, {2: 0}, {}, {}},
+			want:    []int{5},
+		},
+		{
+			name:    "PauseIf stops counting hits",
+			memaddr: "0xH0001=1.2._P:0xH0002=1",
+			frames:  []map[uint]byte{{1: 1, 2: 1}, {}, {2: 0}, {}},
+			want:    []int{3},
+		},
+		{
+			name:    "AddSource adds values before comparing",
+			memaddr: "A:0xH0001_A:0xH0002*2_0xH0003=10",
+			frames:  []map[uint]byte{{1: 2, 2: 3, 3: 1}, {3: 2}, {1: 3}},
+			want:    []int{1},
+		},
+		{
+			name:    "AndNext and OrNext combine conditions",
+			memaddr: "O:0xH0001=1_N:0xH0002=1_0xH0003=1",
+			frames:  []map[uint]byte{{3: 1}, {1: 1}, {1: 0, 2: 1}, {2: 0}, {1: 1, 3: 0}},
+			want:    []int{1, 2},
+		},
+		{
+			name:    "One alternative group must be true",
+			memaddr: "0xH0001=1S0xH0002=1S0xS0003=1",
+			frames:  []map[uint]byte{{1: 1}, {2: 1}, {2: 0, 3: 0x40}, {1: 0}},
+			want:    []int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := run(t, tt.memaddr, tt.frames)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Rejects invalid conditions", func(t *testing.T) {
+		for _, memaddr := range []string{"0xHzz=1", "X:0xH0001=1", "0xH0001=1.a.", "A:0xH0001"} {
+			if _, err := newParser().parseTrigger(memaddr); err == nil {
+				t.Errorf("parseTrigger(%q) got = nil, want an error", memaddr)
+			}
+		}
+	})
+}
+
+func TestEngine(t *testing.T) {
+	set := Set{Achievements: []Achievement{
+		{ID: 1, Title: "Five", MemAddr: "0xH0001=5"},
+		{ID: 2, Title: "Already done", MemAddr: "0xH0001=5"},
+		{ID: 3, Title: "Broken", MemAddr: "0xHzz=5"},
+		{ID: 4, Title: "Started true", MemAddr: "0xH0002=0"},
+	}}
+	e := NewEngine(set, map[int]time.Time{2: time.Now()})
+	mem := make(ram, 4)
+
+	if got := e.Do(mem); len(got) != 0 {
+		t.Errorf("got = %v, want no unlock on the first frame", got)
+	}
+	mem[2] = 1
+	e.Do(mem)
+	mem[1] = 5
+	mem[2] = 0
+	got := e.Do(mem)
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 4 {
+		t.Errorf("got = %v, want achievements 1 and 4", got)
+	}
+	if got := e.Do(mem); len(got) != 0 {
+		t.Errorf("got = %v, want no unlock twice", got)
+	}
+
+	states := map[int]State{}
+	for _, s := range e.List() {
+		states[s.ID] = s.State
+	}
+	want := map[int]State{1: Unlocked, 2: Unlocked, 4: Unlocked}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("got = %v, want %v", states, want)
+	}
+}
+
+func TestUnlocks(t *testing.T) {
+	dir := t.TempDir()
+	u, err := LoadUnlocks(dir, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	u.Add("Sonic", 7, at)
+	if err := u.Save(dir, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadUnlocks(dir, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got["Sonic"][7].Equal(at) {
+		t.Errorf("got = %v, want %v", got, u)
+	}
+	other, _ := LoadUnlocks(dir, "bob")
+	if len(other) != 0 {
+		t.Errorf("got = %v, want no unlocks for another player", other)
+	}
+
+	t.Run("Reports corrupted files", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "carol.json"), []byte(`{"Sonic": {"7": "2026`), 0644)
+		if _, err := LoadUnlocks(dir, "carol"); err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+	})
+
+	t.Run("Merges unlocks", func(t *testing.T) {
+		u := Unlocks{"Sonic": {1: at}}
+		u.Merge(Unlocks{"Sonic": {2: at}, "Tetris": {3: at}})
+		want := Unlocks{"Sonic": {1: at, 2: at}, "Tetris": {3: at}}
+		if !reflect.DeepEqual(u, want) {
+			t.Errorf("got = %v, want %v", u, want)
+		}
+	})
+}
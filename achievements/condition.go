@@ -0,0 +1,542 @@
+package achievements
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// size is the kind of memory value an operand reads
+type size int
+
+// The memory sizes of the rcheevos format
+const (
+	size8 size = iota
+	size16
+	size24
+	size32
+	size16BE
+	size24BE
+	size32BE
+	sizeBit0
+	sizeBit1
+	sizeBit2
+	sizeBit3
+	sizeBit4
+	sizeBit5
+	sizeBit6
+	sizeBit7
+	sizeLower4
+	sizeUpper4
+	sizeBitCount
+)
+
+// sizes maps the letters following 0x to their size. No letter is 16 bits.
+var sizes = map[byte]size{
+	'H': size8,
+	' ': size16,
+	'W': size24,
+	'X': size32,
+	'I': size16BE,
+	'J': size24BE,
+	'G': size32BE,
+	'M': sizeBit0,
+	'N': sizeBit1,
+	'O': sizeBit2,
+	'P': sizeBit3,
+	'Q': sizeBit4,
+	'R': sizeBit5,
+	'S': sizeBit6,
+	'T': sizeBit7,
+	'L': sizeLower4,
+	'U': sizeUpper4,
+	'K': sizeBitCount,
+}
+
+// bytes returns the number of bytes read for a size
+func (s size) bytes() int {
+	switch s {
+	case size16, size16BE:
+		return 2
+	case size24, size24BE:
+		return 3
+	case size32, size32BE:
+		return 4
+	}
+	return 1
+}
+
+// decode turns the bytes read in memory into a value
+func (s size) decode(b []byte) uint32 {
+	switch s {
+	case size16, size24, size32:
+		v := uint32(0)
+		for i := len(b) - 1; i >= 0; i-- {
+			v = v<<8 | uint32(b[i])
+		}
+		return v
+	case size16BE, size24BE, size32BE:
+		v := uint32(0)
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return v
+	case sizeLower4:
+		return uint32(b[0] & 0x0f)
+	case sizeUpper4:
+		return uint32(b[0] >> 4)
+	case sizeBitCount:
+		return uint32(bits.OnesCount8(b[0]))
+	}
+	if s >= sizeBit0 && s <= sizeBit7 {
+		return uint32(b[0]>>(s-sizeBit0)) & 1
+	}
+	return uint32(b[0])
+}
+
+// mask returns the bits used by a size, to invert values
+func (s size) mask() uint32 {
+	switch s.bytes() {
+	case 2:
+		return 0xffff
+	case 3:
+		return 0xffffff
+	case 4:
+		return 0xffffffff
+	}
+	switch s {
+	case size8:
+		return 0xff
+	case sizeLower4, sizeUpper4:
+		return 0x0f
+	case sizeBitCount:
+		return 0x0f
+	}
+	return 1
+}
+
+// memref is a value of the memory, shared by the operands reading it, with
+// its value on the previous frame and the last value that was different
+type memref struct {
+	address uint
+	size    size
+	value   uint32
+	prev    uint32
+	prior   uint32
+	read    bool
+}
+
+// update reads the memory for a new frame
+func (m *memref) update(mem Memory) {
+	b := make([]byte, m.size.bytes())
+	for i := range b {
+		v, ok := mem.Read(m.address+uint(i), 1, false)
+		if !ok {
+			return
+		}
+		b[i] = byte(v)
+	}
+	v := m.size.decode(b)
+	if !m.read {
+		m.value, m.prev, m.prior, m.read = v, v, v, true
+		return
+	}
+	m.prev = m.value
+	if v != m.value {
+		m.prior = m.value
+	}
+	m.value = v
+}
+
+// kind tells how an operand gets its value
+type kind int
+
+// The kinds of operands
+const (
+	constant kind = iota
+	current
+	delta
+	prior
+	bcd
+	invert
+)
+
+type operand struct {
+	kind  kind
+	value uint32 // For constants
+	ref   *memref
+}
+
+// get returns the value of the operand on this frame
+func (o operand) get() uint32 {
+	switch o.kind {
+	case current:
+		return o.ref.value
+	case delta:
+		return o.ref.prev
+	case prior:
+		return o.ref.prior
+	case bcd:
+		v, n := uint32(0), uint32(1)
+		for x := o.ref.value; x > 0; x >>= 4 {
+			v += (x & 0x0f) * n
+			n *= 10
+		}
+		return v
+	case invert:
+		return ^o.ref.value & o.ref.size.mask()
+	}
+	return o.value
+}
+
+// condition is a comparison of two operands, or a single operand for the
+// AddSource and SubSource flags
+type condition struct {
+	flag   byte // 0, or one of the letters of the flags like R for ResetIf
+	left   operand
+	op     string // A comparison, an arithmetic operator or empty
+	right  operand
+	target uint32 // Hits needed for the condition to be true, 0 for none
+	hits   uint32
+}
+
+// flags are the condition flags supported
+const flags = "RPABCNOMT"
+
+// value returns the left operand combined with the right one, for the
+// conditions adding or subtracting their value to the next one
+func (c *condition) value() uint32 {
+	l, r := c.left.get(), c.right.get()
+	switch c.op {
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	case "&":
+		return l & r
+	}
+	return l
+}
+
+// compare tests the condition, with add added to the left operand
+func (c *condition) compare(add uint32) bool {
+	l, r := c.left.get()+add, c.right.get()
+	switch c.op {
+	case "=":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return l != 0
+}
+
+// group is a list of conditions that must all be true
+type group []*condition
+
+// modifier tells if a condition only modifies the next one
+func modifier(flag byte) bool {
+	return strings.IndexByte("ABCNO", flag) >= 0
+}
+
+// chains splits a group into chains of modifier conditions ending with a
+// condition without modifier flag
+func (g group) chains() [][]*condition {
+	chains := [][]*condition{}
+	chain := []*condition{}
+	for _, c := range g {
+		chain = append(chain, c)
+		if !modifier(c.flag) {
+			chains = append(chains, chain)
+			chain = []*condition{}
+		}
+	}
+	return chains
+}
+
+// testChain evaluates a chain of conditions and tells if its last condition
+// is satisfied, with its hit target if any
+func testChain(chain []*condition) bool {
+	add := uint32(0)
+	addHits := uint32(0)
+	combine := byte(0)
+	prevResult := false
+	satisfied := false
+	for _, c := range chain {
+		switch c.flag {
+		case 'A':
+			add += c.value()
+			continue
+		case 'B':
+			add -= c.value()
+			continue
+		}
+
+		r := c.compare(add)
+		add = 0
+		switch combine {
+		case 'N':
+			r = r && prevResult
+		case 'O':
+			r = r || prevResult
+		}
+		combine = 0
+		if r && (c.target == 0 || c.hits < c.target) {
+			c.hits++
+		}
+
+		switch c.flag {
+		case 'N', 'O':
+			combine = c.flag
+			prevResult = r
+			continue
+		case 'C':
+			addHits += c.hits
+			continue
+		}
+
+		satisfied = r
+		if c.target > 0 {
+			satisfied = c.hits+addHits >= c.target
+		}
+	}
+	return satisfied
+}
+
+// test evaluates the group for a frame. The PauseIf conditions are tested
+// first, the other conditions don't count hits while the group is paused.
+func (g group) test() (ok, reset, paused bool) {
+	chains := g.chains()
+	for _, chain := range chains {
+		if chain[len(chain)-1].flag == 'P' && testChain(chain) {
+			paused = true
+		}
+	}
+	if paused {
+		return false, false, true
+	}
+
+	ok = true
+	for _, chain := range chains {
+		switch chain[len(chain)-1].flag {
+		case 'P':
+		case 'R':
+			if testChain(chain) {
+				reset = true
+			}
+		default:
+			if !testChain(chain) {
+				ok = false
+			}
+		}
+	}
+	return ok, reset, false
+}
+
+// resetHits clears the hit counts of the group
+func (g group) resetHits() {
+	for _, c := range g {
+		c.hits = 0
+	}
+}
+
+// trigger is a parsed condition string: a core group and alternative groups,
+// one of which must be true along with the core
+type trigger struct {
+	core group
+	alts []group
+}
+
+// test evaluates the trigger for a frame. A ResetIf condition clears all the
+// hit counts.
+func (t *trigger) test() bool {
+	ok, reset, _ := t.core.test()
+	altOK := len(t.alts) == 0
+	for _, alt := range t.alts {
+		r, rs, _ := alt.test()
+		altOK = altOK || r
+		reset = reset || rs
+	}
+	if reset {
+		t.resetHits()
+		return false
+	}
+	return ok && altOK
+}
+
+// resetHits clears the hit counts of all the groups
+func (t *trigger) resetHits() {
+	t.core.resetHits()
+	for _, alt := range t.alts {
+		alt.resetHits()
+	}
+}
+
+// parser reads condition strings, sharing the memory references between the
+// triggers of a game
+type parser struct {
+	memrefs map[[2]uint]*memref
+}
+
+func newParser() *parser {
+	return &parser{memrefs: map[[2]uint]*memref{}}
+}
+
+// parseTrigger parses a condition string like "0xH1234=5_d0xH1234=4.10.",
+// where _ separates conditions and S separates the alternative groups
+func (p *parser) parseTrigger(s string) (*trigger, error) {
+	t := &trigger{}
+	for i, part := range splitGroups(s) {
+		g := group{}
+		if part != "" {
+			for _, cs := range strings.Split(part, "_") {
+				c, err := p.parseCondition(cs)
+				if err != nil {
+					return nil, err
+				}
+				g = append(g, c)
+			}
+			if modifier(g[len(g)-1].flag) {
+				return nil, fmt.Errorf("%q ends with a modifier condition", part)
+			}
+		}
+		if i == 0 {
+			t.core = g
+		} else {
+			t.alts = append(t.alts, g)
+		}
+	}
+	return t, nil
+}
+
+// splitGroups splits a condition string on the S separating its groups,
+// leaving the S of the bit 6 size like in 0xS1234
+func splitGroups(s string) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'S' && !(i >= 2 && strings.EqualFold(s[i-2:i], "0x")) {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// comparisons and arithmetic operators, longest first
+var operators = []string{"!=", "<=", ">=", "=", "<", ">", "*", "/", "&"}
+
+func (p *parser) parseCondition(s string) (*condition, error) {
+	c := &condition{}
+	orig := s
+
+	if len(s) > 2 && s[1] == ':' {
+		if strings.IndexByte(flags, s[0]) < 0 {
+			return nil, fmt.Errorf("unsupported flag in %q", orig)
+		}
+		c.flag = s[0]
+		s = s[2:]
+	}
+
+	// Hit target like .10. at the end
+	if strings.HasSuffix(s, ".") {
+		i := strings.LastIndex(s[:len(s)-1], ".")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid hit count in %q", orig)
+		}
+		n, err := strconv.ParseUint(s[i+1:len(s)-1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hit count in %q", orig)
+		}
+		c.target = uint32(n)
+		s = s[:i]
+	}
+
+	left, right := s, ""
+	for _, op := range operators {
+		if i := strings.Index(s, op); i > 0 {
+			left, c.op, right = s[:i], op, s[i+len(op):]
+			break
+		}
+	}
+
+	var err error
+	if c.left, err = p.parseOperand(left); err != nil {
+		return nil, fmt.Errorf("%v in %q", err, orig)
+	}
+	if c.op != "" {
+		if c.right, err = p.parseOperand(right); err != nil {
+			return nil, fmt.Errorf("%v in %q", err, orig)
+		}
+	}
+	return c, nil
+}
+
+// parseOperand reads a memory value like 0xH1234 or d0x1234, or a constant
+// like 12 or hFF
+func (p *parser) parseOperand(s string) (operand, error) {
+	o := operand{kind: current}
+	if s == "" {
+		return o, fmt.Errorf("missing operand")
+	}
+
+	switch s[0] {
+	case 'd':
+		o.kind, s = delta, s[1:]
+	case 'p':
+		o.kind, s = prior, s[1:]
+	case 'b':
+		o.kind, s = bcd, s[1:]
+	case '~':
+		o.kind, s = invert, s[1:]
+	case 'h', 'H':
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil {
+			return o, fmt.Errorf("invalid constant %q", s)
+		}
+		return operand{kind: constant, value: uint32(v)}, nil
+	}
+
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		if o.kind != current {
+			return o, fmt.Errorf("invalid memory value %q", s)
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return o, fmt.Errorf("invalid constant %q", s)
+		}
+		return operand{kind: constant, value: uint32(v)}, nil
+	}
+
+	s = s[2:]
+	sz := size16
+	if s != "" {
+		if z, ok := sizes[strings.ToUpper(s[:1])[0]]; ok {
+			sz, s = z, s[1:]
+		}
+	}
+	address, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return o, fmt.Errorf("invalid address %q", s)
+	}
+
+	key := [2]uint{uint(address), uint(sz)}
+	ref, ok := p.memrefs[key]
+	if !ok {
+		ref = &memref{address: uint(address), size: sz}
+		p.memrefs[key] = ref
+	}
+	o.ref = ref
+	return o, nil
+}
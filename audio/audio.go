@@ -3,6 +3,7 @@
 package audio
 
 import (
+	"bytes"
 	"log"
 	"path/filepath"
 	"time"
@@ -29,6 +30,24 @@ var (
 // Effects are sound effects
 var Effects map[string]*Effect
 
+// capture records the game audio instead of playing it, for headless runs
+var capture *bytes.Buffer
+
+// Capture records the game audio in memory instead of playing it. It doesn't
+// need an audio device and can be called instead of Init.
+func Capture() {
+	capture = &bytes.Buffer{}
+}
+
+// Captured returns the audio recorded since Capture as interleaved signed
+// 16-bit stereo PCM, and its sample rate
+func Captured() ([]byte, int32) {
+	if capture == nil {
+		return nil, rate
+	}
+	return capture.Bytes(), rate
+}
+
 // SetVolume sets the audio volume
 func SetVolume(vol float32) {
 	source.SetGain(vol)
@@ -56,6 +75,9 @@ func Init() {
 // volume and the source for the games.
 func Reconfigure(r int32) {
 	rate = r
+	if capture != nil {
+		return
+	}
 	numBuffers = 4
 
 	log.Printf("[OpenAL]: Using %v buffers of %v bytes.\n", numBuffers, bufSize)
@@ -112,6 +134,11 @@ func fillInternalBuf(buf []byte) int32 {
 func write(buf []byte, size int32) int32 {
 	written := int32(0)
 
+	if capture != nil {
+		capture.Write(buf[:min(size, int32(len(buf)))])
+		return size
+	}
+
 	if state.FastForward {
 		return size
 	}
@@ -0,0 +1,61 @@
+// Package catalog describes the games offered on the kiosk, and the parental
+// control rules deciding which of them may be shown at a given time.
+package catalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rating is the minimum age a game is suitable for, PEGI style
+type Rating int
+
+// Common age ratings
+const (
+	AllAges Rating = 0
+	Age7    Rating = 7
+	Age12   Rating = 12
+	Age16   Rating = 16
+	Age18   Rating = 18
+)
+
+func (r Rating) String() string {
+	if r <= AllAges {
+		return "All ages"
+	}
+	return fmt.Sprintf("%d+", int(r))
+}
+
+// Game is an entry of the kiosk catalog
+type Game struct {
+	Name      string   // Name displayed on the game tile
+	GamePath  string   // Path of the ROM, empty for cores running without a game
+	CorePath  string   // Path of the libretro core running the ROM
+	ImagePath string   // Picture used on the game tile
+	Rating    Rating   // Minimum age of the players
+	Tags      []string // Content descriptors like "violence" or "horror"
+	Cheats    []string // Descriptions of cheats of the game's .cht file to enable, like an easy mode
+}
+
+// HasTag tells if the game carries a content tag, ignoring case
+func (g Game) HasTag(tag string) bool {
+	for _, t := range g.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Catalog is the ordered list of games offered on the kiosk
+type Catalog []Game
+
+// Find returns the game with the given name
+func (c Catalog) Find(name string) (Game, bool) {
+	for _, g := range c {
+		if g.Name == name {
+			return g, true
+		}
+	}
+	return Game{}, false
+}
@@ -0,0 +1,127 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/pelletier/go-toml"
+)
+
+// Rule changes the maximum rating during a time window. Windows ending before
+// they start wrap around midnight, so From "21:00" To "06:00" covers the night.
+type Rule struct {
+	From      string `toml:"from"` // Start of the window, as HH:MM
+	To        string `toml:"to"`   // End of the window, as HH:MM
+	MaxRating Rating `toml:"max_rating"`
+}
+
+// Policy decides which catalog games may be shown
+type Policy struct {
+	MaxRating   Rating   `toml:"max_rating"`
+	BlockedTags []string `toml:"blocked_tags"`
+	Rules       []Rule   `toml:"rules"`
+}
+
+// DefaultPolicy lets every game through
+var DefaultPolicy = Policy{MaxRating: Age18}
+
+// StrictPolicy only lets the games for all ages through. It is used when the
+// configuration can't be read, so a broken file never widens access.
+var StrictPolicy = Policy{MaxRating: AllAges}
+
+// PolicyFiles are the layers of parental control configuration, applied in
+// order. The venue-wide file is provisioned on every cabinet, the second file
+// lets the operator override it on a single cabinet.
+var PolicyFiles = []string{
+	"/etc/ludo-parental.toml",
+	filepath.Join(xdg.ConfigHome, "ludo", "parental.toml"),
+}
+
+// LoadPolicy reads the policy layers that exist. Keys present in a layer
+// override the ones from the previous layers. On error, StrictPolicy is
+// returned.
+func LoadPolicy(paths ...string) (Policy, error) {
+	p := DefaultPolicy
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return StrictPolicy, err
+		}
+		if err := toml.Unmarshal(b, &p); err != nil {
+			return StrictPolicy, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, r := range p.Rules {
+		if _, err := parseClock(r.From); err != nil {
+			return StrictPolicy, err
+		}
+		if _, err := parseClock(r.To); err != nil {
+			return StrictPolicy, err
+		}
+	}
+	return p, nil
+}
+
+// MaxRatingAt returns the maximum rating in effect at the given time. The
+// last matching rule wins.
+func (p Policy) MaxRatingAt(t time.Time) Rating {
+	max := p.MaxRating
+	for _, r := range p.Rules {
+		if r.active(t) {
+			max = r.MaxRating
+		}
+	}
+	return max
+}
+
+// Allows tells if a game can be shown at the given time
+func (p Policy) Allows(g Game, t time.Time) bool {
+	if g.Rating > p.MaxRatingAt(t) {
+		return false
+	}
+	for _, tag := range p.BlockedTags {
+		if g.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the games allowed at the given time
+func (p Policy) Filter(c Catalog, t time.Time) Catalog {
+	allowed := Catalog{}
+	for _, g := range c {
+		if p.Allows(g, t) {
+			allowed = append(allowed, g)
+		}
+	}
+	return allowed
+}
+
+func (r Rule) active(t time.Time) bool {
+	from, err1 := parseClock(r.From)
+	to, err2 := parseClock(r.To)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if from <= to {
+		return now >= from && now < to
+	}
+	return now >= from || now < to
+}
+
+// parseClock converts HH:MM into a duration since midnight
+func parseClock(s string) (time.Duration, error) {
+	c, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return time.Duration(c.Hour())*time.Hour + time.Duration(c.Minute())*time.Minute, nil
+}
@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func at(clock string) time.Time {
+	t, _ := time.Parse("15:04", clock)
+	return t
+}
+
+func TestPolicy_Allows(t *testing.T) {
+	family := Game{Name: "Family", Rating: AllAges}
+	mature := Game{Name: "Mature", Rating: Age18}
+	spooky := Game{Name: "Spooky", Rating: Age12, Tags: []string{"Horror"}}
+
+	p := Policy{
+		MaxRating:   Age12,
+		BlockedTags: []string{"horror"},
+		Rules:       []Rule{{From: "21:00", To: "06:00", MaxRating: Age18}},
+	}
+
+	tests := []struct {
+		name string
+		game Game
+		time string
+		want bool
+	}{
+		{"Allows family games during the day", family, "14:00", true},
+		{"Hides mature games during the day", mature, "14:00", false},
+		{"Shows mature games after 21:00", mature, "21:00", true},
+		{"Shows mature games after midnight", mature, "02:30", true},
+		{"Hides mature games once the window is over", mature, "06:00", false},
+		{"Hides blocked tags regardless of case", spooky, "14:00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Allows(tt.game, at(tt.time))
+			if got != tt.want {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Filter(t *testing.T) {
+	c := Catalog{
+		{Name: "A", Rating: AllAges},
+		{Name: "B", Rating: Age16},
+		{Name: "C", Rating: Age7},
+	}
+
+	t.Run("Keeps the catalog order", func(t *testing.T) {
+		got := Policy{MaxRating: Age7}.Filter(c, at("12:00"))
+		want := Catalog{c[0], c[2]}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	venue := filepath.Join(dir, "venue.toml")
+	cabinet := filepath.Join(dir, "cabinet.toml")
+	os.WriteFile(venue, []byte(`
+max_rating = 12
+blocked_tags = ["gambling"]
+
+[[rules]]
+from = "21:00"
+to = "06:00"
+max_rating = 18
+`), 0644)
+	os.WriteFile(cabinet, []byte("max_rating = 7\n"), 0644)
+
+	t.Run("Applies the cabinet override on top of the venue policy", func(t *testing.T) {
+		got, err := LoadPolicy(venue, cabinet, filepath.Join(dir, "missing.toml"))
+		want := Policy{
+			MaxRating:   Age7,
+			BlockedTags: []string{"gambling"},
+			Rules:       []Rule{{From: "21:00", To: "06:00", MaxRating: Age18}},
+		}
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, %v, want %v", got, err, want)
+		}
+	})
+
+	t.Run("Lets everything through without configuration", func(t *testing.T) {
+		got, err := LoadPolicy(filepath.Join(dir, "missing.toml"))
+		if err != nil || !reflect.DeepEqual(got, DefaultPolicy) {
+			t.Errorf("got = %v, %v, want %v", got, err, DefaultPolicy)
+		}
+	})
+
+	t.Run("Rejects malformed times", func(t *testing.T) {
+		bad := filepath.Join(dir, "bad.toml")
+		os.WriteFile(bad, []byte("[[rules]]\nfrom = \"9pm\"\nto = \"06:00\"\nmax_rating = 18\n"), 0644)
+		got, err := LoadPolicy(bad)
+		if err == nil || !reflect.DeepEqual(got, StrictPolicy) {
+			t.Errorf("got = %v, %v, want %v and an error", got, err, StrictPolicy)
+		}
+	})
+
+	t.Run("Doesn't widen access when a file is malformed", func(t *testing.T) {
+		bad := filepath.Join(dir, "typo.toml")
+		os.WriteFile(bad, []byte("max_rating = 18\nblocked_tags = [\"gambling\"\n"), 0644)
+		got, err := LoadPolicy(venue, bad)
+		if err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+		rated := Game{Name: "Rated", Rating: Age12}
+		for _, clock := range []string{"14:00", "23:00"} {
+			if got.Allows(rated, at(clock)) {
+				t.Errorf("got = %v, want %s hidden at %s", got, rated.Name, clock)
+			}
+		}
+	})
+}
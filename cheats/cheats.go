@@ -0,0 +1,372 @@
+// Package cheats reads and writes RetroArch .cht cheat files. Cheat codes
+// like Game Genie or Action Replay are handed to the core, while RAM cheats
+// are applied by Ludo, writing a value at an address of the emulated memory
+// every frame, so they also work with cores lacking cheat support.
+package cheats
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/utils"
+)
+
+// Handler tells who applies a cheat
+type Handler int
+
+// The cheat handlers of the .cht format
+const (
+	HandlerCore Handler = 0 // The code is passed to the core
+	HandlerRAM  Handler = 1 // Ludo writes the value in the memory
+)
+
+// Memory search sizes of the .cht format, the smaller bit sizes aren't
+// supported
+const (
+	searchSize8  = 3
+	searchSize16 = 4
+	searchSize32 = 5
+)
+
+// cheatTypeSet is the only RAM cheat type supported: set the value each frame
+const cheatTypeSet = 1
+
+// Cheat is an entry of a cheat file
+type Cheat struct {
+	Desc    string
+	Code    string // Game Genie, Action Replay… codes, for HandlerCore
+	Enabled bool
+	Handler Handler
+
+	// RAM cheats, for HandlerRAM
+	Address   uint   // Offset in the memory regions of the core put end to end
+	Value     uint32 // Written at Address each frame
+	Size      int    // In bytes: 1, 2 or 4
+	BigEndian bool
+
+	// Unsupported RAM cheats, like conditional, incremental or sub-byte
+	// ones, are never applied but are kept to be written back
+	Unsupported bool
+
+	keys map[string]string // raw values of the entry in the file, by key
+}
+
+// List is the content of a cheat file
+type List []Cheat
+
+// knownKeys are the keys of an entry Ludo understands, in the order they are
+// written. The other keys are written back as read, after them.
+var knownKeys = []string{
+	"desc", "code", "enable", "handler",
+	"address", "value", "memory_search_size", "cheat_type", "big_endian",
+}
+
+// unquote removes the quotes around a value
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// entryKey splits a key like cheat12_desc into 12 and desc
+func entryKey(key string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(key, "cheat")
+	if !ok {
+		return 0, "", false
+	}
+	num, name, ok := strings.Cut(rest, "_")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 0 {
+		return 0, "", false
+	}
+	return n, name, true
+}
+
+// Parse reads a .cht cheat file. Every entry is kept, with all its keys, so
+// the file can be written back without losing anything.
+func Parse(r io.Reader) (List, error) {
+	count := ""
+	entries := map[int]map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key, raw := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if key == "cheats" {
+			count = unquote(raw)
+			continue
+		}
+		if n, name, ok := entryKey(key); ok {
+			if entries[n] == nil {
+				entries[n] = map[string]string{}
+			}
+			entries[n][name] = raw
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid cheat count %q", count)
+	}
+
+	list := List{}
+	for i := 0; i < n; i++ {
+		keys := entries[i]
+		if keys == nil {
+			keys = map[string]string{}
+		}
+		get := func(key string) string {
+			return unquote(keys[key])
+		}
+		c := Cheat{
+			Desc:      get("desc"),
+			Code:      get("code"),
+			Enabled:   get("enable") == "true",
+			BigEndian: get("big_endian") == "true",
+			Size:      1,
+			keys:      keys,
+		}
+		if h, err := strconv.Atoi(get("handler")); err == nil {
+			c.Handler = Handler(h)
+		}
+		if c.Handler == HandlerRAM {
+			address, _ := strconv.ParseUint(get("address"), 10, 64)
+			value, _ := strconv.ParseUint(get("value"), 10, 32)
+			c.Address = uint(address)
+			c.Value = uint32(value)
+			switch get("memory_search_size") {
+			case "", strconv.Itoa(searchSize8):
+			case strconv.Itoa(searchSize16):
+				c.Size = 2
+			case strconv.Itoa(searchSize32):
+				c.Size = 4
+			default:
+				c.Unsupported = true
+			}
+			if t := get("cheat_type"); t != "" && t != strconv.Itoa(cheatTypeSet) {
+				c.Unsupported = true
+			}
+			if r := get("repeat_count"); r != "" && r != "0" && r != "1" {
+				c.Unsupported = true
+			}
+		}
+		if c.Desc == "" {
+			c.Desc = defaultDesc(i)
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// defaultDesc names the cheats without description
+func defaultDesc(index int) string {
+	return fmt.Sprintf("Cheat %d", index+1)
+}
+
+// rawKeys returns the keys of the entry at index to write. Keys read from
+// the file keep their raw value unless the cheat was changed, and keys
+// missing or empty in the file are only set when they aren't the default.
+func (c Cheat) rawKeys(index int) map[string]string {
+	keys := map[string]string{}
+	for k, v := range c.keys {
+		keys[k] = v
+	}
+	set := func(key, value, def string, quoted bool) {
+		old, ok := keys[key]
+		if ok && unquote(old) == value || unquote(old) == "" && value == def {
+			return
+		}
+		if quoted {
+			value = strconv.Quote(value)
+		}
+		keys[key] = value
+	}
+
+	set("desc", c.Desc, defaultDesc(index), true)
+	set("code", c.Code, "", true)
+	set("enable", strconv.FormatBool(c.Enabled), "false", false)
+	set("handler", strconv.Itoa(int(c.Handler)), "0", false)
+	if c.Handler != HandlerRAM || c.Unsupported {
+		return keys
+	}
+	size := searchSize8
+	switch c.Size {
+	case 2:
+		size = searchSize16
+	case 4:
+		size = searchSize32
+	}
+	set("address", strconv.FormatUint(uint64(c.Address), 10), "", false)
+	set("value", strconv.FormatUint(uint64(c.Value), 10), "", false)
+	set("memory_search_size", strconv.Itoa(size), "", false)
+	set("cheat_type", strconv.Itoa(cheatTypeSet), "", false)
+	set("big_endian", strconv.FormatBool(c.BigEndian), "false", false)
+	return keys
+}
+
+// Write writes a list of cheats in the .cht format. The keys Ludo doesn't
+// understand are written back as they were read.
+func Write(w io.Writer, list List) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "cheats = %d\n", len(list))
+	for i, c := range list {
+		fmt.Fprintln(b)
+		keys := c.rawKeys(i)
+		for _, k := range knownKeys {
+			if v, ok := keys[k]; ok {
+				fmt.Fprintf(b, "cheat%d_%s = %s\n", i, k, v)
+				delete(keys, k)
+			}
+		}
+		others := []string{}
+		for k := range keys {
+			others = append(others, k)
+		}
+		sort.Strings(others)
+		for _, k := range others {
+			fmt.Fprintf(b, "cheat%d_%s = %s\n", i, k, keys[k])
+		}
+	}
+	return b.Flush()
+}
+
+// Path returns the cheat file of a game in a cheats directory
+func Path(dir, gamePath string) string {
+	return filepath.Join(dir, utils.FileName(gamePath)+".cht")
+}
+
+// Load reads a cheat file
+func Load(path string) (List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Save writes a cheat file, creating its directory if needed
+func Save(path string, list List) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return Write(file, list)
+}
+
+// Enable turns on the cheats with the given descriptions and returns the
+// descriptions that matched no cheat
+func (l List) Enable(descs []string) []string {
+	missing := []string{}
+	for _, desc := range descs {
+		found := false
+		for i := range l {
+			if strings.EqualFold(l[i].Desc, desc) {
+				l[i].Enabled = true
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, desc)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Memory is the memory of the emulated system, as the list of the memory
+// regions of the core. Addresses are offsets in the regions put end to end,
+// like in RetroArch.
+type Memory [][]byte
+
+// slice returns the bytes at an address, when they are in a single region
+func (m Memory) slice(address uint, size int) []byte {
+	for _, region := range m {
+		if address < uint(len(region)) {
+			if address+uint(size) > uint(len(region)) {
+				return nil
+			}
+			return region[address : address+uint(size)]
+		}
+		address -= uint(len(region))
+	}
+	return nil
+}
+
+// Read returns the value of size bytes at an address
+func (m Memory) Read(address uint, size int, bigEndian bool) (uint32, bool) {
+	b := m.slice(address, size)
+	if b == nil {
+		return 0, false
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	switch size {
+	case 1:
+		return uint32(b[0]), true
+	case 2:
+		return uint32(order.Uint16(b)), true
+	case 4:
+		return order.Uint32(b), true
+	}
+	return 0, false
+}
+
+// Write sets size bytes at an address
+func (m Memory) Write(address uint, size int, value uint32, bigEndian bool) bool {
+	b := m.slice(address, size)
+	if b == nil {
+		return false
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	switch size {
+	case 1:
+		b[0] = byte(value)
+	case 2:
+		order.PutUint16(b, uint16(value))
+	case 4:
+		order.PutUint32(b, value)
+	default:
+		return false
+	}
+	return true
+}
+
+// Apply writes the enabled RAM cheats in the memory
+func (l List) Apply(m Memory) {
+	for _, c := range l {
+		if c.Enabled && c.Handler == HandlerRAM && !c.Unsupported {
+			m.Write(c.Address, c.Size, c.Value, c.BigEndian)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package cheats
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withoutKeys drops the raw keys of the cheats, to compare their fields
+func withoutKeys(list List) List {
+	got := List{}
+	for _, c := range list {
+		c.keys = nil
+		got = append(got, c)
+	}
+	return got
+}
+
+func TestLoad(t *testing.T) {
+	list, err := Load("testdata/Super Mario World (USA).cht")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := List{
+		{Desc: "Infinite Lives", Code: "7E0DBE:09", Size: 1},
+		{Desc: "Start with 99 coins", Enabled: true, Handler: HandlerRAM, Address: 3601, Value: 99, Size: 1},
+		{Desc: "Timer", Handler: HandlerRAM, Address: 2, Value: 4660, Size: 2, BigEndian: true},
+		{Desc: "Increase score", Handler: HandlerRAM, Address: 8, Value: 1, Size: 1, Unsupported: true},
+		{Desc: "Cheat 5", Handler: HandlerRAM, Address: 16, Value: 1, Size: 1, Unsupported: true},
+	}
+	if got := withoutKeys(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	t.Run("Writes back the file unchanged", func(t *testing.T) {
+		file, err := os.ReadFile("testdata/Super Mario World (USA).cht")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, list); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != string(file) {
+			t.Errorf("got = %v, want %v", buf.String(), string(file))
+		}
+	})
+
+	t.Run("Only changes the toggled cheat", func(t *testing.T) {
+		file, err := os.ReadFile("testdata/Super Mario World (USA).cht")
+		if err != nil {
+			t.Fatal(err)
+		}
+		toggled := append(List{}, list...)
+		toggled[3].Enabled = true
+		var buf bytes.Buffer
+		if err := Write(&buf, toggled); err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Replace(string(file), "cheat3_desc = \"Increase score\"\n",
+			"cheat3_desc = \"Increase score\"\ncheat3_enable = true\n", 1)
+		if buf.String() != want {
+			t.Errorf("got = %v, want %v", buf.String(), want)
+		}
+	})
+
+	t.Run("Writes back new cheats", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Write(&buf, want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(withoutKeys(got[:3]), want[:3]) {
+			t.Errorf("got = %v, want %v", got[:3], want[:3])
+		}
+	})
+}
+
+func TestPath(t *testing.T) {
+	got := Path("/cheats", "/roms/Super Mario World (USA).zip")
+	want := filepath.Join("/cheats", "Super Mario World (USA).cht")
+	if got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestList_Enable(t *testing.T) {
+	list := List{{Desc: "Infinite Lives"}, {Desc: "Moon Jump"}}
+	missing := list.Enable([]string{"infinite lives", "Easy Mode"})
+	if !list[0].Enabled || list[1].Enabled {
+		t.Errorf("got = %v, want only the first cheat enabled", list)
+	}
+	if !reflect.DeepEqual(missing, []string{"Easy Mode"}) {
+		t.Errorf("got = %v, want %v", missing, []string{"Easy Mode"})
+	}
+}
+
+func TestList_Apply(t *testing.T) {
+	wram := make([]byte, 4)
+	sram := make([]byte, 4)
+	m := Memory{wram, sram}
+
+	list := List{
+		{Enabled: true, Handler: HandlerRAM, Address: 1, Value: 0x63, Size: 1},
+		{Enabled: true, Handler: HandlerRAM, Address: 4, Value: 0x1234, Size: 2, BigEndian: true},
+		{Enabled: true, Handler: HandlerRAM, Address: 6, Value: 0xBEEF, Size: 2},
+		{Enabled: false, Handler: HandlerRAM, Address: 0, Value: 0xFF, Size: 1},
+		{Enabled: true, Handler: HandlerRAM, Address: 3, Value: 0xFFFF, Size: 2},
+		{Enabled: true, Handler: HandlerCore, Code: "7E0DBE:09"},
+		{Enabled: true, Handler: HandlerRAM, Address: 0, Value: 0xFF, Size: 1, Unsupported: true},
+	}
+	list.Apply(m)
+
+	if !reflect.DeepEqual(wram, []byte{0, 0x63, 0, 0}) {
+		t.Errorf("got = %x, want %x", wram, []byte{0, 0x63, 0, 0})
+	}
+	if !reflect.DeepEqual(sram, []byte{0x12, 0x34, 0xEF, 0xBE}) {
+		t.Errorf("got = %x, want %x", sram, []byte{0x12, 0x34, 0xEF, 0xBE})
+	}
+
+	t.Run("Reads values back", func(t *testing.T) {
+		if v, ok := m.Read(4, 2, true); !ok || v != 0x1234 {
+			t.Errorf("got = %x, %v, want %x, %v", v, ok, 0x1234, true)
+		}
+		if _, ok := m.Read(8, 1, false); ok {
+			t.Errorf("got = %v, want %v", ok, false)
+		}
+	})
+}
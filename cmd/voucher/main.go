@@ -0,0 +1,42 @@
+// Command voucher prints signed voucher codes for the arcade cabinets.
+// It runs offline: the cabinets only need to share the same secret, set as
+// voucher_secret in their settings.toml.
+//
+//	voucher -secret s3cr3t -minutes 10 -count 50 -first 1000
+//	voucher -secret s3cr3t -percent 50 -count 10
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/libretro/ludo/voucher"
+)
+
+func main() {
+	secret := flag.String("secret", os.Getenv("LUDO_VOUCHER_SECRET"), "shared signing secret")
+	minutes := flag.Int("minutes", 0, "free minutes granted by each code")
+	percent := flag.Int("percent", 0, "discount percentage granted by each code")
+	count := flag.Int("count", 1, "number of codes to generate")
+	first := flag.Uint("first", 1, "serial number of the first code")
+	flag.Parse()
+
+	v := voucher.Voucher{Kind: voucher.Minutes, Value: *minutes}
+	if *percent != 0 {
+		if *minutes != 0 {
+			log.Fatal("use either -minutes or -percent, not both")
+		}
+		v = voucher.Voucher{Kind: voucher.Percent, Value: *percent}
+	}
+
+	for i := 0; i < *count; i++ {
+		v.Serial = uint32(*first) + uint32(i)
+		code, err := voucher.Generate([]byte(*secret), v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s\t%s\t#%d\n", code, v, v.Serial)
+	}
+}
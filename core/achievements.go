@@ -0,0 +1,96 @@
+package core
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/libretro/ludo/achievements"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// achievementEngine evaluates the achievements of the current game, it is nil
+// when the game has none
+var achievementEngine *achievements.Engine
+
+// pendingUnlocks are the unlocks not saved yet, because the file of the
+// player couldn't be read. They are saved with the next unlocks.
+var pendingUnlocks = achievements.Unlocks{}
+
+// profile returns the profile achievements are unlocked for, the one of the
+// kiosk session or the player of the settings
+func profile() string {
+	if state.Profile != "" {
+		return state.Profile
+	}
+	return settings.Current.PlayerName
+}
+
+// loadAchievements reads the achievement definitions of the game
+func loadAchievements(gamePath string) {
+	achievementEngine = nil
+	if gamePath == "" {
+		return
+	}
+	set, err := achievements.Load(achievements.Path(settings.Current.AchievementsDirectory, gamePath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("[Core]: Can't load achievements:", err)
+		}
+		return
+	}
+	unlocks, err := achievements.LoadUnlocks(settings.Current.UnlocksDirectory, profile())
+	if err != nil {
+		log.Println("[Core]: Can't load unlocked achievements:", err)
+	}
+	achievementEngine = achievements.NewEngine(set, unlocks[utils.FileName(gamePath)])
+	log.Printf("[Core]: %d achievements loaded\n", len(achievementEngine.List()))
+}
+
+// Achievements returns the achievements of the current game and their
+// progress
+func Achievements() []achievements.Status {
+	if achievementEngine == nil {
+		return nil
+	}
+	return achievementEngine.List()
+}
+
+// doAchievements evaluates the achievements after a frame. They are not
+// evaluated while rewinding, playing back a movie or with cheats enabled.
+func doAchievements() {
+	if achievementEngine == nil || state.Rewind || playing != nil {
+		return
+	}
+	for _, c := range Cheats {
+		if c.Enabled {
+			return
+		}
+	}
+
+	unlocked := achievementEngine.Do(coreMemory())
+	if len(unlocked) == 0 {
+		return
+	}
+	for _, a := range unlocked {
+		ntf.DisplayAndLog(ntf.Success, "Achievements", "Unlocked %s (%d points).", a.Title, a.Points)
+		pendingUnlocks.Add(utils.FileName(state.GamePath), a.ID, time.Now())
+	}
+
+	// A file that can't be read is left untouched rather than replaced by the
+	// new unlocks alone
+	unlocks, err := achievements.LoadUnlocks(settings.Current.UnlocksDirectory, profile())
+	if err != nil {
+		log.Println("[Core]: Can't load unlocked achievements, not saving them:", err)
+		return
+	}
+	unlocks.Merge(pendingUnlocks)
+	if err := unlocks.Save(settings.Current.UnlocksDirectory, profile()); err != nil {
+		log.Println("[Core]: Can't save unlocked achievements:", err)
+		return
+	}
+	pendingUnlocks = achievements.Unlocks{}
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"log"
+	"os"
+	"unsafe"
+
+	"github.com/libretro/ludo/cheats"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+)
+
+// Cheats are the cheats of the current game, from its cheat file
+var Cheats cheats.List
+
+// loadCheats reads the cheat file of the game and hands the enabled codes to
+// the core
+func loadCheats(gamePath string) {
+	Cheats = nil
+	if gamePath == "" {
+		return
+	}
+	list, err := cheats.Load(cheats.Path(settings.Current.CheatsDirectory, gamePath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("[Core]: Can't load cheats:", err)
+		}
+		return
+	}
+	Cheats = list
+	applyCoreCheats()
+}
+
+// EnableCheats turns on cheats of the current game by description, like the
+// easy mode cheats of the kiosk catalog. The cheat file is left untouched.
+func EnableCheats(descs []string) {
+	for _, desc := range Cheats.Enable(descs) {
+		log.Println("[Core]: Cheat not found:", desc)
+	}
+	applyCoreCheats()
+}
+
+// ToggleCheat enables or disables a cheat of the current game and saves the
+// cheat file. The entries and keys Ludo doesn't understand are kept.
+func ToggleCheat(index int) error {
+	Cheats[index].Enabled = !Cheats[index].Enabled
+	applyCoreCheats()
+	return cheats.Save(cheats.Path(settings.Current.CheatsDirectory, state.GamePath), Cheats)
+}
+
+// applyCoreCheats hands the codes of the enabled cheats to the core, at their
+// index in the cheat file. The RAM cheats are applied each frame by
+// applyRAMCheats.
+func applyCoreCheats() {
+	state.Core.CheatReset()
+	for i, c := range Cheats {
+		if c.Enabled && c.Handler == cheats.HandlerCore && c.Code != "" {
+			state.Core.CheatSet(uint(i), true, c.Code)
+		}
+	}
+}
+
+// applyRAMCheats writes the values of the enabled RAM cheats in the memory
+// of the core
+func applyRAMCheats() {
+	for _, c := range Cheats {
+		if c.Enabled && c.Handler == cheats.HandlerRAM && !c.Unsupported {
+			Cheats.Apply(coreMemory())
+			return
+		}
+	}
+}
+
+// coreMemory returns the memory regions of the core, from its memory map or
+// its system RAM
+func coreMemory() cheats.Memory {
+	m := cheats.Memory{}
+	for _, d := range state.Core.MemoryMap {
+		if d.Ptr == nil || d.Len == 0 {
+			continue
+		}
+		m = append(m, unsafe.Slice((*byte)(unsafe.Add(d.Ptr, d.Offset)), d.Len))
+	}
+	if len(m) > 0 {
+		return m
+	}
+	if ptr := state.Core.GetMemoryData(libretro.MemorySystemRAM); ptr != nil {
+		size := state.Core.GetMemorySize(libretro.MemorySystemRAM)
+		m = append(m, unsafe.Slice((*byte)(ptr), size))
+	}
+	return m
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/state"
+)
+
+// portDevices is the device chosen for each port of the loaded core
+var portDevices = joypads()
+
+// defaultDevices are offered on the ports the core doesn't describe
+var defaultDevices = []libretro.ControllerDescription{
+	{Desc: "RetroPad", ID: libretro.DeviceJoypad},
+	{Desc: "RetroPad w/ Analog", ID: libretro.DeviceAnalog},
+	{Desc: "Mouse", ID: libretro.DeviceMouse},
+	{Desc: "Lightgun", ID: libretro.DeviceLightgun},
+}
+
+func joypads() (devices [input.MaxPlayers]uint32) {
+	for i := range devices {
+		devices[i] = libretro.DeviceJoypad
+	}
+	return
+}
+
+// PortDevices lists the devices that can be plugged on a port, as described
+// by the core with EnvironmentSetControllerInfo
+func PortDevices(port int) []libretro.ControllerDescription {
+	devices := []libretro.ControllerDescription{{Desc: "None", ID: libretro.DeviceNone}}
+	if state.Core != nil && port < len(state.Core.ControllerInfo) && len(state.Core.ControllerInfo[port]) > 0 {
+		return append(devices, state.Core.ControllerInfo[port]...)
+	}
+	return append(devices, defaultDevices...)
+}
+
+// PortDevice returns the device plugged on a port
+func PortDevice(port int) uint32 {
+	return portDevices[port]
+}
+
+// PortDeviceName returns the name of the device plugged on a port
+func PortDeviceName(port int) string {
+	for _, d := range PortDevices(port) {
+		if d.ID == portDevices[port] {
+			return d.Desc
+		}
+	}
+	return fmt.Sprintf("Device %d", portDevices[port])
+}
+
+// SetPortDevice plugs a device on a port. Unplugged seats get it back when
+// they are plugged again.
+func SetPortDevice(port int, device uint32) {
+	portDevices[port] = device
+	if state.CoreRunning && !input.Unplugged[port] {
+		setControllerPortDevice(port, device)
+	}
+}
+
+// applyPortDevices tells a newly loaded game about the devices of each port.
+// Devices the core doesn't support anymore are replaced by a RetroPad.
+func applyPortDevices() {
+	for port := range portDevices {
+		supported := false
+		for _, d := range PortDevices(port) {
+			supported = supported || d.ID == portDevices[port]
+		}
+		if !supported {
+			portDevices[port] = libretro.DeviceJoypad
+		}
+		setControllerPortDevice(port, portDevices[port])
+	}
+}
+
+// setControllerPortDevice plugs a device on both instances of the core
+func setControllerPortDevice(port int, device uint32) {
+	state.Core.SetControllerPortDevice(uint(port), device)
+	if secondary != nil {
+		secondary.SetControllerPortDevice(uint(port), device)
+	}
+}
@@ -11,12 +11,14 @@ import (
 	"strings"
 
 	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/discs"
 	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/libretro"
 	"github.com/libretro/ludo/options"
 	"github.com/libretro/ludo/patch"
 	"github.com/libretro/ludo/savefiles"
 	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/vfs"
 	"github.com/libretro/ludo/video"
 
 	"github.com/mholt/archiver/v3"
@@ -27,6 +29,31 @@ var vid *video.Video
 // Options holds the settings for the current core
 var Options *options.Options
 
+// optionsGame is the game whose option overrides apply, set before the game
+// is loaded so that the core sees them in retro_load_game
+var optionsGame string
+
+// UpdateOptionsDisplay lets the core hide or show options after one of them
+// changed. It returns true if the visibility of an option changed.
+func UpdateOptionsDisplay() bool {
+	if state.Core == nil || state.Core.UpdateOptionsDisplay == nil {
+		return false
+	}
+	return state.Core.UpdateOptionsDisplay()
+}
+
+// setOptionsGame applies the option overrides of the content directory and
+// of a game, or removes them for an empty path
+func setOptionsGame(gamePath string) {
+	optionsGame = gamePath
+	if Options == nil {
+		return
+	}
+	if err := Options.SetGame(gamePath); err != nil {
+		log.Println(err)
+	}
+}
+
 // Init is there mainly for dependency injection.
 // Call Init before calling other functions of this package.
 func Init(v *video.Video) {
@@ -47,13 +74,16 @@ func Load(sofile string) error {
 	if err != nil {
 		return err
 	}
-	state.Core.SetEnvironment(environment)
+	warnFirmware(sofile)
+	portDevices = joypads()
+	sandboxFiles("")
+	state.Core.SetEnvironment(environmentFor(state.Core))
 	state.Core.Init()
-	state.Core.SetVideoRefresh(vid.Refresh)
+	state.Core.SetVideoRefresh(videoRefresh)
 	state.Core.SetInputPoll(func() {})
-	state.Core.SetInputState(input.State)
-	state.Core.SetAudioSample(audio.Sample)
-	state.Core.SetAudioSampleBatch(audio.SampleBatch)
+	state.Core.SetInputState(inputState)
+	state.Core.SetAudioSample(audioSample)
+	state.Core.SetAudioSampleBatch(audioSampleBatch)
 
 	// Append the library name to the window title.
 	si := state.Core.GetSystemInfo()
@@ -71,6 +101,14 @@ func Load(sofile string) error {
 	return nil
 }
 
+// extPrefered gives priority to some extensions when picking the file of an
+// archive to pass to the core (use lower case to be case insensitive)
+var extPrefered = map[string]int{
+	".cue": 1,
+	".m3u": 2,
+	".pbp": 3,
+}
+
 // unarchiveGame unarchives a rom to tmpdir and returns the path and size of the extracted ROM.
 // In case the archive contains more than one file, they are all extracted and the
 // first one or a better match (cue for CDrom) is passed to the libretro core.
@@ -106,12 +144,6 @@ func unarchiveGame(filename string) (string, int64, error) {
 	}
 
 	extPriority := 0 // current priority
-	// Give priority of some extensions (use upper case to be case insensitive)
-	extPrefered := make(map[string]int)
-	extPrefered[".cue"] = 1
-	extPrefered[".m3u"] = 2
-	extPrefered[".pbp"] = 3
-
 	err = archiver.Walk(filename, func(f archiver.File) error {
 		fname := f.Name()
 		ext := filepath.Ext(fname)
@@ -131,7 +163,10 @@ func unarchiveGame(filename string) (string, int64, error) {
 		}
 		return nil
 	})
-	return path, size, err
+	if err != nil {
+		return path, size, err
+	}
+	return path, size, discs.ValidateFile(path)
 }
 
 // LoadGame loads a game. A core has to be loaded first.
@@ -148,15 +183,83 @@ func LoadGame(gamePath string) error {
 
 	si := state.Core.GetSystemInfo()
 
-	gi, err := getGameInfo(gamePath, si.BlockExtract)
+	// The discs of a playlist are inserted one by one in cores that can't
+	// read it
+	contentPath := gamePath
+	playlist := playlistDiscs(gamePath, si)
+	if len(playlist) > 0 {
+		if err := discs.ValidateFile(gamePath); err != nil {
+			return err
+		}
+		contentPath = playlist[0]
+	}
+
+	gi, err := gameInfo(contentPath, si)
 	if err != nil {
 		return err
 	}
 
+	state.Core.HWRenderCallback = nil
+	sandboxFiles(gamePath)
+	setOptionsGame(gamePath)
+	if len(playlist) == 0 {
+		restoreDisk(gamePath)
+	}
+	ok := state.Core.LoadGame(*gi)
+	if !ok {
+		state.CoreRunning = false
+		setOptionsGame("")
+		return errors.New("failed to load the game")
+	}
+	if len(playlist) > 1 {
+		appendDiscs(playlist[1:])
+	}
+
+	state.Subsystem = nil
+	state.SubsystemPaths = nil
+	gameLoaded(gamePath, si)
+
+	return nil
+}
+
+// LoadNoGame starts a core that supports running without a game, like a game
+// engine or a game built in the core. A core has to be loaded first.
+func LoadNoGame() error {
+	if !state.Core.SupportsNoGame {
+		return errors.New("the core needs a game")
+	}
+
+	UnloadGame()
+
+	si := state.Core.GetSystemInfo()
+
+	state.Core.HWRenderCallback = nil
+	sandboxFiles()
+	setOptionsGame("")
+	if !state.Core.LoadNoGame() {
+		state.CoreRunning = false
+		return errors.New("failed to start the core")
+	}
+
+	state.Subsystem = nil
+	state.SubsystemPaths = nil
+	gameLoaded("", si)
+
+	return nil
+}
+
+// gameInfo prepares the libretro.GameInfo of a game, with its content in
+// memory and patched when the core doesn't need the full path
+func gameInfo(gamePath string, si libretro.SystemInfo) (*libretro.GameInfo, error) {
+	gi, err := getGameInfo(gamePath, si.BlockExtract, si.NeedFullpath)
+	if err != nil {
+		return nil, err
+	}
+
 	if !si.NeedFullpath {
-		bytes, err := os.ReadFile(gi.Path)
+		bytes, err := readGame(gi.Path)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if patched, _ := patch.Try(gamePath, bytes); patched != nil {
@@ -166,17 +269,19 @@ func LoadGame(gamePath string) error {
 			gi.SetData(bytes)
 		}
 	}
+	return gi, nil
+}
 
-	ok := state.Core.LoadGame(*gi)
-	if !ok {
-		state.CoreRunning = false
-		return errors.New("failed to load the game")
-	}
-
+// gameLoaded starts the game the core just loaded
+func gameLoaded(gamePath string, si libretro.SystemInfo) {
 	avi := state.Core.GetSystemAVInfo()
 
 	vid.Geom = avi.Geometry
 
+	if state.Core.HWRenderCallback != nil {
+		vid.InitHWRender(state.Core.HWRenderCallback, int32(avi.Geometry.MaxWidth), int32(avi.Geometry.MaxHeight))
+	}
+
 	// Append the library name to the window title.
 	if len(si.LibraryName) > 0 {
 		vid.SetTitle("Ludo - " + si.LibraryName)
@@ -191,17 +296,19 @@ func LoadGame(gamePath string) error {
 	state.CoreRunning = true
 	state.FastForward = false
 	state.GamePath = gamePath
+	resetRunAhead()
+	initRewind()
 
-	state.Core.SetControllerPortDevice(0, libretro.DeviceJoypad)
-	state.Core.SetControllerPortDevice(1, libretro.DeviceJoypad)
-	state.Core.SetControllerPortDevice(2, libretro.DeviceJoypad)
-	state.Core.SetControllerPortDevice(3, libretro.DeviceJoypad)
-	state.Core.SetControllerPortDevice(4, libretro.DeviceJoypad)
+	applyPortDevices()
 
-	log.Println("[Core]: Game loaded: " + gamePath)
+	if gamePath == "" {
+		log.Println("[Core]: Started without a game")
+	} else {
+		log.Println("[Core]: Game loaded: " + gamePath)
+	}
 	savefiles.LoadSRAM()
-
-	return nil
+	loadCheats(gamePath)
+	loadAchievements(gamePath)
 }
 
 // Unload unloads a libretro core
@@ -218,17 +325,30 @@ func Unload() {
 // UnloadGame unloads a game.
 func UnloadGame() {
 	if state.CoreRunning {
+		StopNetplay()
+		if err := StopMovie(); err != nil {
+			log.Println("[Movie]:", err)
+		}
 		savefiles.SaveSRAM()
+		rememberDisk()
+		vid.DeinitHWRender()
+		unloadSecondary()
 		state.Core.UnloadGame()
+		input.StopRumble()
+		setOptionsGame("")
+		Cheats = nil
+		achievementEngine = nil
 		state.GamePath = ""
+		state.Subsystem = nil
+		state.SubsystemPaths = nil
 		state.CoreRunning = false
 		vid.ResetPitch()
 		vid.ResetRot()
 	}
 }
 
-// getGameInfo opens a rom and return the libretro.GameInfo needed to launch it
-func getGameInfo(filename string, blockExtract bool) (*libretro.GameInfo, error) {
+// getGameInfo opens a rom and return the libretro.GameInfo needed to launch it.
+func getGameInfo(filename string, blockExtract, needFullpath bool) (*libretro.GameInfo, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -239,6 +359,21 @@ func getGameInfo(filename string, blockExtract bool) (*libretro.GameInfo, error)
 		return nil, err
 	}
 
+	// Games loaded in memory are read from inside the archives, and so are
+	// the ones of cores opening their content through the VFS interface.
+	// Other cores get an extracted file.
+	if !blockExtract && (!needFullpath || state.Core != nil && state.Core.VFS) &&
+		strings.ToLower(filepath.Ext(filename)) == ".zip" {
+		entry, size, err := archiveEntry(filename)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateArchiveEntry(filename, entry); err != nil {
+			return nil, err
+		}
+		return &libretro.GameInfo{Path: vfs.ArchivePath(filename, entry), Size: size}, nil
+	}
+
 	if !blockExtract {
 		switch filepath.Ext(filename) {
 		case ".zip", ".zst", ".rar", ".tar":
@@ -250,5 +385,19 @@ func getGameInfo(filename string, blockExtract bool) (*libretro.GameInfo, error)
 		}
 	}
 
+	if err := discs.ValidateFile(filename); err != nil {
+		return nil, err
+	}
 	return &libretro.GameInfo{Path: filename, Size: fi.Size()}, nil
 }
+
+// hasExtension tells if ext is one of the valid extensions of a core,
+// separated by "|"
+func hasExtension(extensions, ext string) bool {
+	for _, e := range strings.Split(extensions, "|") {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -65,6 +65,8 @@ func Test_getGameInfo(t *testing.T) {
 	type args struct {
 		filename     string
 		blockExtract bool
+		needFullpath bool
+		vfs          bool
 	}
 	tests := []struct {
 		name    string
@@ -83,7 +85,7 @@ func Test_getGameInfo(t *testing.T) {
 		},
 		{
 			name: "Returns the right path and size for a zipped ROM",
-			args: args{filename: "testdata/Polar Rescue (USA).zip", blockExtract: false},
+			args: args{filename: "testdata/Polar Rescue (USA).zip", blockExtract: false, needFullpath: true},
 			want: &libretro.GameInfo{
 				Path: filepath.Join(os.TempDir(), "Polar Rescue (USA).vec"),
 				Size: 8192,
@@ -108,6 +110,24 @@ func Test_getGameInfo(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Returns an archive path for games loaded in memory",
+			args: args{filename: "testdata/Polar Rescue (USA).zip"},
+			want: &libretro.GameInfo{
+				Path: "testdata/Polar Rescue (USA).zip#Polar Rescue (USA).vec",
+				Size: 8192,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Returns an archive path for VFS cores needing the full path",
+			args: args{filename: "testdata/Polar Rescue (USA).zip", needFullpath: true, vfs: true},
+			want: &libretro.GameInfo{
+				Path: "testdata/Polar Rescue (USA).zip#Polar Rescue (USA).vec",
+				Size: 8192,
+			},
+			wantErr: false,
+		},
 		{
 			name:    "Returns an error when a file doesn't exists",
 			args:    args{filename: "testdata/Polar Rescue (USA)2.zip", blockExtract: true},
@@ -124,9 +144,11 @@ func Test_getGameInfo(t *testing.T) {
 			wantErr: false,
 		},
 	}
+	defer func(c *libretro.Core) { state.Core = c }(state.Core)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getGameInfo(tt.args.filename, tt.args.blockExtract)
+			state.Core = &libretro.Core{VFS: tt.args.vfs}
+			got, err := getGameInfo(tt.args.filename, tt.args.blockExtract, tt.args.needFullpath)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getGameInfo() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -188,6 +210,24 @@ func Test_unarchiveGame(t *testing.T) {
 	}
 }
 
+func Test_archiveEntry(t *testing.T) {
+	t.Run("Picks the file of the archive", func(t *testing.T) {
+		entry, size, err := archiveEntry("testdata/Polar Rescue (USA).zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != "Polar Rescue (USA).vec" || size != 8192 {
+			t.Errorf("got = %v %v, want %v %v", entry, size, "Polar Rescue (USA).vec", 8192)
+		}
+	})
+
+	t.Run("Returns an error if the file is not a zip", func(t *testing.T) {
+		if _, _, err := archiveEntry("testdata/Polar Rescue (USA).vec"); err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+	})
+}
+
 func Test_coreLoadGame(t *testing.T) {
 	state.Verbose = true
 
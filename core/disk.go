@@ -0,0 +1,144 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libretro/ludo/discs"
+	"github.com/libretro/ludo/history"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// DiskLabel returns a human readable name for a disk image of the current
+// game, from the core label or the image file name
+func DiskLabel(index uint) string {
+	dcc := state.Core.DiskControlCallback
+	if dcc.GetImageLabel != nil {
+		if label := dcc.GetImageLabel(index); label != "" {
+			return label
+		}
+	}
+	if dcc.GetImagePath != nil {
+		if path := dcc.GetImagePath(index); path != "" {
+			return utils.FileName(path)
+		}
+	}
+	return fmt.Sprintf("Disk %d", index+1)
+}
+
+// SwapDisk ejects the current disk image and inserts the one at index
+func SwapDisk(index uint) error {
+	dcc := state.Core.DiskControlCallback
+	if index >= dcc.GetNumImages() {
+		return fmt.Errorf("no disk %d", index+1)
+	}
+	dcc.SetEjectState(true)
+	dcc.SetImageIndex(index)
+	dcc.SetEjectState(false)
+	rememberDisk()
+	return nil
+}
+
+// AppendDisk adds a file as a new disk image of the current game and returns
+// its index. The new image is not inserted.
+func AppendDisk(path string) (uint, error) {
+	dcc := state.Core.DiskControlCallback
+	if dcc.AddImageIndex == nil || dcc.ReplaceImageIndex == nil {
+		return 0, errors.New("the core can't add disks")
+	}
+
+	gi, err := gameInfo(path, state.Core.GetSystemInfo())
+	if err != nil {
+		return 0, err
+	}
+
+	// Disk images can only be changed while the tray is open
+	ejected := dcc.GetEjectState()
+	if !ejected {
+		dcc.SetEjectState(true)
+		defer dcc.SetEjectState(false)
+	}
+
+	if !dcc.AddImageIndex() {
+		return 0, errors.New("failed to add a disk")
+	}
+	index := dcc.GetNumImages() - 1
+	if !dcc.ReplaceImageIndex(index, gi) {
+		dcc.ReplaceImageIndex(index, nil)
+		return 0, errors.New("failed to insert " + filepath.Base(path))
+	}
+	return index, nil
+}
+
+// restoreDisk asks the core to start the game on the disk inserted the last
+// time it was played. It has to be called before loading the game.
+func restoreDisk(gamePath string) {
+	if state.Core.DiskControlCallback == nil || state.Core.DiskControlCallback.SetInitialImage == nil {
+		return
+	}
+	game, ok := history.Find(gamePath)
+	if !ok || game.DiskPath == "" {
+		return
+	}
+	if state.Core.DiskControlCallback.SetInitialImage(game.DiskIndex, game.DiskPath) {
+		log.Printf("[Core]: Restoring disk %d: %s\n", game.DiskIndex+1, game.DiskPath)
+	}
+}
+
+// rememberDisk saves the disk inserted in the current game in the history
+func rememberDisk() {
+	dcc := state.Core.DiskControlCallback
+	if state.GamePath == "" || dcc == nil || dcc.GetImagePath == nil {
+		return
+	}
+	index := dcc.GetImageIndex()
+	if path := dcc.GetImagePath(index); path != "" {
+		history.SetDisk(state.GamePath, index, path)
+	}
+}
+
+// playlistDiscs returns the discs of an m3u playlist when the core can't read
+// playlists but can add disks. The first disc is loaded as the game and the
+// others are added with the disk control interface.
+func playlistDiscs(gamePath string, si libretro.SystemInfo) []string {
+	if strings.ToLower(filepath.Ext(gamePath)) != ".m3u" {
+		return nil
+	}
+	if hasExtension(si.ValidExtensions, "m3u") {
+		return nil
+	}
+	dcc := state.Core.DiskControlCallback
+	if dcc == nil || dcc.AddImageIndex == nil || dcc.ReplaceImageIndex == nil {
+		return nil
+	}
+
+	file, err := os.Open(gamePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	list, err := discs.ParseM3U(file)
+	if err != nil || len(list) == 0 {
+		return nil
+	}
+	paths := []string{}
+	for _, disc := range list {
+		paths = append(paths, filepath.Join(filepath.Dir(gamePath), filepath.FromSlash(disc.Path)))
+	}
+	return paths
+}
+
+// appendDiscs adds the discs following the first one of a playlist
+func appendDiscs(paths []string) {
+	for _, path := range paths {
+		if _, err := AppendDisk(path); err != nil {
+			log.Println("[Core]: Can't add disk:", err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/libretro"
 	"github.com/libretro/ludo/options"
 	"github.com/libretro/ludo/settings"
@@ -75,6 +76,18 @@ func environmentGetSaveDirectory(data unsafe.Pointer) bool {
 	return true
 }
 
+// newOptions creates the options of the core, with the overrides of the
+// game being played
+func newOptions(vars []options.VariableInterface) (*options.Options, error) {
+	o, err := options.New(vars)
+	if optionsGame != "" {
+		if gerr := o.SetGame(optionsGame); gerr != nil {
+			log.Println(gerr)
+		}
+	}
+	return o, err
+}
+
 func environmentSetVariables(data unsafe.Pointer) bool {
 	variables := libretro.GetVariables(data)
 
@@ -85,7 +98,7 @@ func environmentSetVariables(data unsafe.Pointer) bool {
 	}
 
 	var err error
-	Options, err = options.New(pass)
+	Options, err = newOptions(pass)
 	if err != nil {
 		log.Println(err)
 		return false
@@ -103,7 +116,7 @@ func environmentSetCoreOptions(data unsafe.Pointer) bool {
 	}
 
 	var err error
-	Options, err = options.New(pass)
+	Options, err = newOptions(pass)
 	if err != nil {
 		log.Println(err)
 		return false
@@ -121,28 +134,110 @@ func environmentSetCoreOptionsIntl(data unsafe.Pointer) bool {
 	}
 
 	var err error
-	Options, err = options.New(pass)
+	Options, err = newOptions(pass)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// environmentSetCoreOptionsV2 returns true to tell the core that we display
+// option categories, even when the saved options can't be loaded
+func environmentSetCoreOptionsV2(categories []libretro.CoreOptionCategory, definitions []libretro.CoreOptionV2Definition) bool {
+	pass := []options.VariableInterface{}
+	for _, cod := range definitions {
+		cod := cod
+		pass = append(pass, &cod)
+	}
+
+	var err error
+	Options, err = newOptions(pass)
 	if err != nil {
 		log.Println(err)
+	}
+
+	cats := []options.Category{}
+	for _, c := range categories {
+		cats = append(cats, options.Category{Key: c.Key, Desc: c.Desc, Info: c.Info})
+	}
+	Options.SetCategories(cats)
+	return true
+}
+
+func environmentSetCoreOptionsDisplay(data unsafe.Pointer) bool {
+	if Options == nil {
+		return false
+	}
+	Options.SetVisible(libretro.GetCoreOptionDisplay(data))
+	return true
+}
+
+// environmentSetHWRender accepts the OpenGL contexts we are able to host.
+// The framebuffer object is created once the game is loaded.
+func environmentSetHWRender(c *libretro.Core, data unsafe.Pointer) bool {
+	hw := libretro.GetHWRenderCallback(data)
+	if !vid.SupportsHWRender(hw) {
+		log.Printf("[Env]: Unsupported hardware context: %d %d.%d\n", hw.ContextType, hw.VersionMajor, hw.VersionMinor)
 		return false
 	}
+	c.BindHWRenderCallback(data, vid.CurrentFramebuffer, vid.ProcAddress)
 	return true
 }
 
-func environment(cmd uint32, data unsafe.Pointer) bool {
+// environmentFor returns the environment callback of an instance of the core
+func environmentFor(c *libretro.Core) func(uint32, unsafe.Pointer) bool {
+	return func(cmd uint32, data unsafe.Pointer) bool {
+		if c != state.Core {
+			if ok, handled := secondaryEnvironment(cmd, data); handled {
+				return ok
+			}
+		}
+		return environment(c, cmd, data)
+	}
+}
+
+// secondaryEnvironment answers the calls of a second instance of the core
+// that would otherwise disturb the main instance. The options belong to the
+// main instance, and the frames run on the second instance are not shown to
+// the player until the last one.
+func secondaryEnvironment(cmd uint32, data unsafe.Pointer) (ok bool, handled bool) {
+	switch cmd {
+	case libretro.EnvironmentSetVariables,
+		libretro.EnvironmentSetCoreOptions,
+		libretro.EnvironmentSetCoreOptionsIntl,
+		libretro.EnvironmentSetCoreOptionsV2,
+		libretro.EnvironmentSetCoreOptionsV2Intl,
+		libretro.EnvironmentSetCoreOptionsDisplay,
+		libretro.EnvironmentSetCoreOptionsUpdateDisplay:
+		return true, true
+	case libretro.EnvironmentGetVariableUpdate:
+		libretro.SetBool(data, secondaryOptionsUpdated)
+		secondaryOptionsUpdated = false
+		return true, true
+	case libretro.EnvironmentShutdown,
+		libretro.EnvironmentSetHWRender,
+		libretro.EnvironmentGetRumbleInterface,
+		libretro.EnvironmentGetLEDInterface:
+		return false, true
+	}
+	return false, false
+}
+
+func environment(c *libretro.Core, cmd uint32, data unsafe.Pointer) bool {
 	switch cmd {
 	case libretro.EnvironmentSetRotation:
 		return vid.SetRotation(*(*uint)(data))
 	case libretro.EnvironmentGetUsername:
 		return environmentGetUsername(data)
 	case libretro.EnvironmentGetLogInterface:
-		state.Core.BindLogCallback(data, logCallback)
+		c.BindLogCallback(data, logCallback)
 	case libretro.EnvironmentGetPerfInterface:
-		state.Core.BindPerfCallback(data, getTimeUsec)
+		c.BindPerfCallback(data, getTimeUsec)
 	case libretro.EnvironmentSetFrameTimeCallback:
-		state.Core.SetFrameTimeCallback(data)
+		c.SetFrameTimeCallback(data)
 	case libretro.EnvironmentSetAudioCallback:
-		state.Core.SetAudioCallback(data)
+		c.SetAudioCallback(data)
 	case libretro.EnvironmentGetCanDupe:
 		libretro.SetBool(data, true)
 	case libretro.EnvironmentSetPixelFormat:
@@ -154,33 +249,67 @@ func environment(cmd uint32, data unsafe.Pointer) bool {
 	case libretro.EnvironmentShutdown:
 		vid.SetShouldClose(true)
 	case libretro.EnvironmentGetCoreOptionsVersion:
-		libretro.SetUint(data, 1)
+		libretro.SetUint(data, 2)
 	case libretro.EnvironmentSetCoreOptions:
 		return environmentSetCoreOptions(data)
 	case libretro.EnvironmentSetCoreOptionsIntl:
 		return environmentSetCoreOptionsIntl(data)
+	case libretro.EnvironmentSetCoreOptionsV2:
+		return environmentSetCoreOptionsV2(libretro.GetCoreOptionsV2(data))
+	case libretro.EnvironmentSetCoreOptionsV2Intl:
+		return environmentSetCoreOptionsV2(libretro.GetCoreOptionsV2Intl(data))
+	case libretro.EnvironmentSetCoreOptionsDisplay:
+		return environmentSetCoreOptionsDisplay(data)
+	case libretro.EnvironmentSetCoreOptionsUpdateDisplay:
+		c.SetCoreOptionsUpdateDisplayCallback(data)
 	case libretro.EnvironmentGetVariable:
 		return environmentGetVariable(data)
 	case libretro.EnvironmentSetVariables:
 		return environmentSetVariables(data)
 	case libretro.EnvironmentGetVariableUpdate:
 		libretro.SetBool(data, Options.Updated)
+		secondaryOptionsUpdated = secondaryOptionsUpdated || Options.Updated
 		Options.Updated = false
+	case libretro.EnvironmentSetInputDescriptors:
+		c.InputDescriptors = libretro.GetInputDescriptors(data)
+	case libretro.EnvironmentSetControllerInfo:
+		c.ControllerInfo = libretro.GetControllerInfo(data)
+	case libretro.EnvironmentSetSupportNoGame:
+		c.SupportsNoGame = *(*bool)(data)
+	case libretro.EnvironmentSetSubsystemInfo:
+		c.Subsystems = libretro.GetSubsystemInfo(data)
+	case libretro.EnvironmentGetVFSInterface:
+		return environmentGetVFSInterface(data)
+	case libretro.EnvironmentGetRumbleInterface:
+		c.BindRumbleInterface(data, input.SetRumbleState)
+	case libretro.EnvironmentGetLEDInterface:
+		c.BindLEDInterface(data, input.SetLEDState)
+	case libretro.EnvironmentGetSensorInterface:
+		c.BindSensorInterface(data, input.SetSensorState, input.SensorInput)
 	case libretro.EnvironmentSetMemoryMaps:
-		state.Core.MemoryMap = libretro.GetMemoryMap(data)
+		c.MemoryMap = libretro.GetMemoryMap(data)
 	case libretro.EnvironmentSetGeometry:
 		vid.Geom = libretro.GetGeometry(data)
 	case libretro.EnvironmentSetSystemAVInfo:
 		avi := libretro.GetSystemAVInfo(data)
 		vid.Geom = avi.Geometry
+		vid.ResizeHWRender(int32(avi.Geometry.MaxWidth), int32(avi.Geometry.MaxHeight))
+	case libretro.EnvironmentSetHWRender:
+		return environmentSetHWRender(c, data)
+	case libretro.EnvironmentGetPrefferedHWRender:
+		libretro.SetUint(data, uint(libretro.HWContextOpenGL))
+	case libretro.EnvironmentGetAudioVideoEnable:
+		libretro.SetUint(data, audioVideoEnable())
 	case libretro.EnvironmentGetFastforwarding:
 		libretro.SetBool(data, state.FastForward)
 	case libretro.EnvironmentGetLanguage:
 		libretro.SetUint(data, 0)
 	case libretro.EnvironmentGetDiskControlInterfaceVersion:
-		libretro.SetUint(data, 0)
+		libretro.SetUint(data, 1)
 	case libretro.EnvironmentSetDiskControlInterface:
-		state.Core.SetDiskControlCallback(data)
+		c.SetDiskControlCallback(data)
+	case libretro.EnvironmentSetDiskControlExtInterface:
+		c.SetDiskControlExtCallback(data)
 	default:
 		//log.Println("[Env]: Not implemented:", cmd)
 		return false
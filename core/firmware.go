@@ -0,0 +1,16 @@
+package core
+
+import (
+	"github.com/libretro/ludo/firmware"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/settings"
+)
+
+// warnFirmware warns about the BIOS files of the core that are missing from
+// the system directory, or don't match the known checksums
+func warnFirmware(sofile string) {
+	summary := firmware.Summary(firmware.Check(sofile, settings.Current.SystemDirectory))
+	if summary != "" {
+		ntf.DisplayAndLog(ntf.Warning, "Core", "BIOS problem. %s", summary)
+	}
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"errors"
+	"log"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/movie"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// recording is the movie being recorded and recordingPath its file
+var recording *movie.Movie
+var recordingPath string
+
+// playing is the movie being played back, playingFrame the number of its
+// frames already run, and playingErr the desynchronization that stopped it
+var playing *movie.Movie
+var playingFrame int
+var playingErr error
+
+// MoviePath returns the movie file of the current game
+func MoviePath() string {
+	return movie.Path(settings.Current.MoviesDirectory, state.ContentPath())
+}
+
+// MovieRecording tells if the inputs are being recorded
+func MovieRecording() bool {
+	return recording != nil
+}
+
+// MoviePlaying tells if a movie is being played back
+func MoviePlaying() bool {
+	return playing != nil
+}
+
+// serializeState returns the current savestate of the core, for the movies
+// and the netplay sessions
+func serializeState() ([]byte, error) {
+	size := state.Core.SerializeSize()
+	if size == 0 {
+		return nil, errors.New("the core doesn't support savestates")
+	}
+	return state.Core.Serialize(size)
+}
+
+// RecordMovie starts recording the inputs of the current game, from its
+// current state, to path. The movie is written by StopMovie.
+func RecordMovie(path string) error {
+	if NetplayActive() {
+		return errors.New("movies can't be recorded during netplay")
+	}
+	s, err := serializeState()
+	if err != nil {
+		return err
+	}
+	StopMovie()
+	recording = movie.New(utils.FileName(state.CorePath), utils.FileName(state.GamePath), s)
+	recordingPath = path
+	return nil
+}
+
+// PlayMovie restores the state a movie starts from and plays back its
+// inputs in place of the ones of the players
+func PlayMovie(path string) error {
+	if NetplayActive() {
+		return errors.New("movies can't be played during netplay")
+	}
+	m, err := movie.Load(path)
+	if err != nil {
+		return err
+	}
+	if m.Core != utils.FileName(state.CorePath) || m.Game != utils.FileName(state.GamePath) {
+		return errors.New("the movie was recorded with another game or core")
+	}
+	if err := state.Core.Unserialize(m.State, uint(len(m.State))); err != nil {
+		return err
+	}
+	StopMovie()
+	// Frames already recorded or run ahead must not leak into the playback
+	runAhead.Reset()
+	initRewind()
+	playing = m
+	playingFrame = 0
+	playingErr = nil
+	return nil
+}
+
+// StopMovie stops the recording or the playback. It writes the movie being
+// recorded, and returns the error that interrupted the last playback, if any.
+func StopMovie() error {
+	if recording != nil {
+		err := recording.Save(recordingPath)
+		log.Printf("[Movie]: Recorded %d frames to %s\n", len(recording.Frames), recordingPath)
+		recording = nil
+		return err
+	}
+	playing = nil
+	err := playingErr
+	playingErr = nil
+	return err
+}
+
+// moviePlay feeds the inputs of the next frame of the movie being played
+// back. The hotkeys of the players stay live.
+func moviePlay() {
+	if playing == nil {
+		return
+	}
+	if playingFrame >= len(playing.Frames) {
+		playing = nil
+		ntf.DisplayAndLog(ntf.Info, "Movie", "Playback ended.")
+		return
+	}
+	f := playing.Frames[playingFrame]
+	for p := 0; p < movie.Ports; p++ {
+		for id := uint32(0); id <= libretro.DeviceIDJoypadR3; id++ {
+			input.NewState[p][id] = int16(f.Buttons[p] >> id & 1)
+		}
+		input.NewAnalogState[p] = f.Analog[p]
+	}
+}
+
+// movieFrame records the inputs of the frame just run, or checks that the
+// playback is still in sync
+func movieFrame() {
+	if recording != nil {
+		recordFrame()
+	}
+	if playing != nil {
+		playingFrame++
+		if !movie.HashDue(playingFrame) {
+			return
+		}
+		s, err := serializeState()
+		if err == nil {
+			err = playing.Check(playingFrame, s)
+		}
+		if err != nil {
+			playing = nil
+			playingErr = err
+			ntf.DisplayAndLog(ntf.Error, "Movie", "Playback stopped: %s.", err)
+		}
+	}
+}
+
+// recordFrame adds the inputs seen by the core to the movie being recorded
+func recordFrame() {
+	var f movie.Frame
+	for p := uint(0); p < movie.Ports; p++ {
+		for id := uint(0); id <= uint(libretro.DeviceIDJoypadR3); id++ {
+			if inputState(p, libretro.DeviceJoypad, 0, id) != 0 {
+				f.Buttons[p] |= 1 << id
+			}
+		}
+		for i := uint(0); i < 2; i++ {
+			for axis := uint(0); axis < 2; axis++ {
+				f.Analog[p][i][axis] = inputState(p, libretro.DeviceAnalog, i, axis)
+			}
+		}
+	}
+	recording.Frames = append(recording.Frames, f)
+	if n := len(recording.Frames); movie.HashDue(n) {
+		if s, err := serializeState(); err == nil {
+			recording.SetHash(n, s)
+		}
+	}
+}
+
+// stopMovieRewind stops the movie when the game is rewound, since the frames
+// undone are already recorded or played back
+func stopMovieRewind() {
+	if recording == nil && playing == nil {
+		return
+	}
+	if err := StopMovie(); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Movie", err.Error())
+		return
+	}
+	ntf.DisplayAndLog(ntf.Warning, "Movie", "Movie stopped by rewind.")
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/netplay"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+var netplaySession *netplay.Session
+var netplayInputs [netplay.Players]uint16
+
+// netplayConnect cancels the pending HostNetplay or JoinNetplay
+var netplayConnect context.CancelFunc
+
+// netplayResult is how the goroutines of HostNetplay and JoinNetplay hand
+// over the connection to the main thread
+type netplayResult struct {
+	peer  *netplay.Peer
+	state []byte // state sent by the host, for the player joining
+	err   error
+}
+
+var netplayResults = make(chan netplayResult, 1)
+
+// NetplayGameID identifies the running game. Both players must run the same
+// game with the same core.
+func NetplayGameID() string {
+	return utils.FileName(state.CorePath) + "/" + utils.FileName(state.ContentPath())
+}
+
+// NetplayActive tells if a netplay session is running or being set up
+func NetplayActive() bool {
+	return netplaySession != nil || netplayConnect != nil
+}
+
+// HostNetplay announces the running game on the LAN and waits in the
+// background for a player to join
+func HostNetplay() {
+	ctx, cancel := context.WithCancel(context.Background())
+	netplayConnect = cancel
+	game := NetplayGameID()
+	name, _ := os.Hostname()
+
+	go netplay.Announce(ctx, fmt.Sprintf("255.255.255.255:%d", netplay.LobbyPort), netplay.Announcement{
+		Name: name,
+		Game: game,
+		Port: netplay.DefaultPort,
+	})
+	go func() {
+		defer cancel()
+		hostCtx, hostCancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer hostCancel()
+		p, err := netplay.Host(hostCtx, fmt.Sprintf(":%d", netplay.DefaultPort), game)
+		if ctx.Err() != nil {
+			return
+		}
+		netplayResults <- netplayResult{peer: p, err: err}
+	}()
+	ntf.DisplayAndLog(ntf.Info, "Netplay", "Waiting for a player to join.")
+}
+
+// JoinNetplay looks for a player hosting the running game on the LAN and
+// joins them in the background
+func JoinNetplay() {
+	ctx, cancel := context.WithCancel(context.Background())
+	netplayConnect = cancel
+	game := NetplayGameID()
+
+	go func() {
+		defer cancel()
+		hosts, err := netplay.Discover(fmt.Sprintf(":%d", netplay.LobbyPort), 3*time.Second)
+		var p *netplay.Peer
+		if err == nil {
+			err = fmt.Errorf("nobody is hosting %s on the network", utils.FileName(state.ContentPath()))
+			for _, h := range hosts {
+				if h.Game == game {
+					joinCtx, joinCancel := context.WithTimeout(ctx, 5*time.Second)
+					p, err = netplay.Join(joinCtx, h.Addr, game)
+					joinCancel()
+					break
+				}
+			}
+		}
+		var s []byte
+		if err == nil {
+			if s, err = p.ReceiveState(ctx); err != nil {
+				p.Close()
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		netplayResults <- netplayResult{p, s, err}
+	}()
+	ntf.DisplayAndLog(ntf.Info, "Netplay", "Looking for a game to join.")
+}
+
+// StopNetplay leaves the netplay session
+func StopNetplay() {
+	if netplayConnect != nil {
+		netplayConnect()
+		netplayConnect = nil
+	}
+	if netplaySession != nil {
+		netplaySession.Close()
+		netplaySession = nil
+	}
+}
+
+// startNetplay starts the session once connected. The host sends its state
+// and the other player loads it, so they begin from the same state.
+func startNetplay(r netplayResult) {
+	netplayConnect = nil
+	if r.err == nil {
+		StopMovie()
+		r.err = syncNetplayState(r)
+	}
+	if r.err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Netplay", r.err.Error())
+		return
+	}
+	netplayInputs = [netplay.Players]uint16{}
+	netplaySession = netplay.NewSession(r.peer, state.Core, netplay.Config{
+		SetInputs:    func(inputs [netplay.Players]uint16) { netplayInputs = inputs },
+		SetAVEnabled: func(enabled bool) { avEnabled = enabled },
+	})
+	ntf.DisplayAndLog(ntf.Success, "Netplay", "Connected, you are player %d.", r.peer.Local+1)
+}
+
+// syncNetplayState sends the state of the host to the other player, or loads
+// the one received from the host
+func syncNetplayState(r netplayResult) error {
+	var err error
+	if r.peer.Local == 0 {
+		var s []byte
+		if s, err = serializeState(); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err = r.peer.SendState(ctx, s)
+			cancel()
+		}
+	} else {
+		err = state.Core.Unserialize(r.state, uint(len(r.state)))
+		runAhead.Reset()
+		initRewind()
+	}
+	if err != nil {
+		r.peer.Close()
+	}
+	return err
+}
+
+// runNetplayFrame runs a frame in sync with the other player, using the
+// input of the first local joypad
+func runNetplayFrame() {
+	var local uint16
+	for id := uint32(0); id <= libretro.DeviceIDJoypadR3; id++ {
+		if input.NewState[0][id] != 0 {
+			local |= 1 << id
+		}
+	}
+	if _, err := netplaySession.Update(local); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Netplay", "Session ended: %s.", err)
+		StopNetplay()
+	}
+}
+
+// inputState is the input callback of the core. During netplay, the joypads
+// of the players come from the session.
+func inputState(port uint, device uint32, index uint, id uint) int16 {
+	if netplaySession == nil || port >= netplay.Players || device != libretro.DeviceJoypad {
+		return input.State(port, device, index, id)
+	}
+	if index > 0 || id > uint(libretro.DeviceIDJoypadR3) {
+		return 0
+	}
+	return int16(netplayInputs[port] >> id & 1)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"github.com/libretro/ludo/rewind"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+)
+
+var rewindBuffer *rewind.Buffer
+var rewindFrame int
+
+// RewindEnabled tells if the game can be rewound. The kiosk only allows it
+// if the operator said so, since it lets players undo their mistakes.
+func RewindEnabled() bool {
+	return settings.Current.RewindEnabled &&
+		(!state.Kiosk || settings.Current.KioskAllowRewind)
+}
+
+// initRewind starts a new history for a newly loaded game
+func initRewind() {
+	rewindBuffer = nil
+	rewindFrame = 0
+	if !RewindEnabled() || state.Core.SerializeSize() == 0 {
+		return
+	}
+	rewindBuffer = rewind.New(settings.Current.RewindBufferSize << 20)
+}
+
+// rewindStep restores the previous state of the history. It returns false
+// when rewind is unavailable.
+func rewindStep() bool {
+	if rewindBuffer == nil || !RewindEnabled() {
+		return false
+	}
+	if s := rewindBuffer.Pop(); s != nil {
+		state.Core.Unserialize(s, uint(len(s)))
+	}
+	return true
+}
+
+// rewindRecord adds the current state to the history every
+// RewindGranularity frames
+func rewindRecord() {
+	if rewindBuffer == nil {
+		return
+	}
+	rewindFrame++
+	if rewindFrame < settings.Current.RewindGranularity {
+		return
+	}
+	rewindFrame = 0
+	size := state.Core.SerializeSize()
+	s, err := state.Core.Serialize(size)
+	if err != nil {
+		return
+	}
+	rewindBuffer.Push(s)
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/libretro"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/runahead"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// avEnabled is false while frames are run ahead, to hide their audio and video
+var avEnabled = true
+
+var runAhead = &runahead.Runner{
+	SetAVEnabled: func(enabled bool) { avEnabled = enabled },
+}
+
+// secondary is the second instance of the core running the frames ahead, for
+// the cores listed in settings.Current.RunAheadSecondInstance
+var secondary *libretro.Core
+
+// secondaryOptionsUpdated remembers the option changes consumed by the main
+// instance until the second instance asks for them
+var secondaryOptionsUpdated bool
+
+// RunAheadFrames returns the number of frames run ahead for the current core
+func RunAheadFrames() int {
+	return settings.Current.RunAheadFrames[utils.FileName(state.CorePath)]
+}
+
+// SetRunAheadFrames sets the number of frames run ahead for the current core
+func SetRunAheadFrames(frames int) {
+	if settings.Current.RunAheadFrames == nil {
+		settings.Current.RunAheadFrames = map[string]int{}
+	}
+	name := utils.FileName(state.CorePath)
+	if frames <= 0 {
+		delete(settings.Current.RunAheadFrames, name)
+	} else {
+		settings.Current.RunAheadFrames[name] = frames
+	}
+	runAhead.Reset()
+}
+
+// RunFrame runs the current core for one video frame, ahead of time if
+// run-ahead is enabled for this core. While rewinding, the frame is run from
+// the previous state of the rewind history instead. During netplay, the
+// frame is run in sync with the other player, without the RAM cheats and
+// movies. The inputs of the frame are recorded in, or played back from, the
+// current movie. Achievements are evaluated after the frame.
+func RunFrame() {
+	defer doAchievements()
+	select {
+	case r := <-netplayResults:
+		startNetplay(r)
+	default:
+	}
+	if netplaySession != nil {
+		runNetplayFrame()
+		return
+	}
+	moviePlay()
+	applyRAMCheats()
+	if state.Rewind && rewindStep() {
+		stopMovieRewind()
+		state.Core.Run()
+		return
+	}
+	runAhead.Frames = RunAheadFrames()
+	if err := runAhead.Run(state.Core); err != nil {
+		ntf.DisplayAndLog(ntf.Warning, "Core", "Run-ahead disabled: %s.", err)
+	}
+	rewindRecord()
+	movieFrame()
+}
+
+// resetRunAhead prepares run-ahead for a newly loaded game
+func resetRunAhead() {
+	runAhead.Reset()
+	unloadSecondary()
+	name := utils.FileName(state.CorePath)
+	if settings.Current.RunAheadSecondInstance[name] {
+		if err := loadSecondary(); err != nil {
+			ntf.DisplayAndLog(ntf.Warning, "Core", "Run-ahead will roll back the game: %s.", err)
+		}
+	}
+}
+
+// loadSecondary loads the current game on a second instance of the core
+func loadSecondary() error {
+	if state.Core.HWRenderCallback != nil {
+		return errors.New("hardware rendered cores can't run a second instance")
+	}
+	if state.Subsystem != nil {
+		return errors.New("subsystem games can't run a second instance")
+	}
+
+	c, err := libretro.Load(state.CorePath)
+	if err != nil {
+		return err
+	}
+	c.SetEnvironment(environmentFor(c))
+	c.Init()
+	c.SetVideoRefresh(videoRefresh)
+	c.SetInputPoll(func() {})
+	c.SetInputState(inputState)
+	c.SetAudioSample(audioSample)
+	c.SetAudioSampleBatch(audioSampleBatch)
+
+	if err := loadSecondaryContent(c); err != nil {
+		c.Deinit()
+		return err
+	}
+
+	secondaryOptionsUpdated = false
+	secondary = c
+	runAhead.Secondary = c
+	return nil
+}
+
+// loadSecondaryContent loads the current game, if any, on the second instance
+func loadSecondaryContent(c *libretro.Core) error {
+	if state.GamePath == "" {
+		if !c.LoadNoGame() {
+			return errors.New("failed to start the core")
+		}
+		return nil
+	}
+	gi, err := gameInfo(state.GamePath, c.GetSystemInfo())
+	if err != nil {
+		return err
+	}
+	if !c.LoadGame(*gi) {
+		return errors.New("failed to load the game")
+	}
+	return nil
+}
+
+// unloadSecondary closes the second instance of the core, if any
+func unloadSecondary() {
+	if secondary == nil {
+		return
+	}
+	runAhead.Secondary = nil
+	secondary.UnloadGame()
+	secondary.Deinit()
+	secondary = nil
+}
+
+// audioVideoEnable answers EnvironmentGetAudioVideoEnable. Bit 0 enables
+// video, bit 1 audio, and bit 2 tells that savestates stay in memory.
+func audioVideoEnable() uint {
+	var flags uint
+	if avEnabled {
+		flags |= 1 | 2
+	}
+	if RunAheadFrames() > 0 {
+		flags |= 4
+	}
+	return flags
+}
+
+// The callbacks below drop the audio and video of the frames run ahead, for
+// cores ignoring EnvironmentGetAudioVideoEnable
+
+func videoRefresh(data unsafe.Pointer, width int32, height int32, pitch int32) {
+	if avEnabled {
+		vid.Refresh(data, width, height, pitch)
+	}
+}
+
+func audioSample(left int16, right int16) {
+	if avEnabled {
+		audio.Sample(left, right)
+	}
+}
+
+func audioSampleBatch(buf []byte, size int32) int32 {
+	if avEnabled {
+		return audio.SampleBatch(buf, size)
+	}
+	return size
+}
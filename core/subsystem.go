@@ -0,0 +1,99 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/patch"
+	"github.com/libretro/ludo/state"
+)
+
+// Subsystem returns the subsystem of the current core called ident
+func Subsystem(ident string) (libretro.SubsystemInfo, bool) {
+	if state.Core == nil {
+		return libretro.SubsystemInfo{}, false
+	}
+	for _, s := range state.Core.Subsystems {
+		if s.Ident == ident {
+			return s, true
+		}
+	}
+	return libretro.SubsystemInfo{}, false
+}
+
+// LoadSubsystem loads a game made of several ROMs, like a Game Boy game in
+// the Super Game Boy BIOS. paths follow the order of subsystem.ROMs, optional
+// ROMs are skipped with an empty path.
+func LoadSubsystem(subsystem libretro.SubsystemInfo, paths []string) error {
+	if len(paths) != len(subsystem.ROMs) {
+		return fmt.Errorf("%s needs %d files", subsystem.Desc, len(subsystem.ROMs))
+	}
+
+	mainPath := ""
+	for i, rom := range subsystem.ROMs {
+		if paths[i] == "" {
+			if rom.Required {
+				return fmt.Errorf("%s is missing", rom.Desc)
+			}
+			continue
+		}
+		if _, err := os.Stat(paths[i]); err != nil {
+			return err
+		}
+		if mainPath == "" {
+			mainPath = paths[i]
+		}
+	}
+	if mainPath == "" {
+		return errors.New("no content to load")
+	}
+
+	UnloadGame()
+
+	gis := make([]libretro.GameInfo, len(paths))
+	for i, rom := range subsystem.ROMs {
+		if paths[i] == "" {
+			continue
+		}
+
+		gi, err := getGameInfo(paths[i], rom.BlockExtract, rom.NeedFullpath)
+		if err != nil {
+			return err
+		}
+
+		if !rom.NeedFullpath {
+			bytes, err := readGame(gi.Path)
+			if err != nil {
+				return err
+			}
+
+			if patched, _ := patch.Try(paths[i], bytes); patched != nil {
+				gi.Size = int64(len(*patched))
+				gi.SetData(*patched)
+			} else {
+				gi.SetData(bytes)
+			}
+		}
+		gis[i] = *gi
+	}
+
+	si := state.Core.GetSystemInfo()
+
+	state.Core.HWRenderCallback = nil
+	sandboxFiles(paths...)
+	setOptionsGame(mainPath)
+	ok := state.Core.LoadGameSpecial(subsystem.ID, gis)
+	if !ok {
+		state.CoreRunning = false
+		setOptionsGame("")
+		return fmt.Errorf("failed to load the %s game", subsystem.Desc)
+	}
+
+	state.Subsystem = &subsystem
+	state.SubsystemPaths = paths
+	gameLoaded(mainPath, si)
+
+	return nil
+}
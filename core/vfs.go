@@ -0,0 +1,85 @@
+package core
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/libretro/ludo/discs"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/vfs"
+)
+
+// files are the files cores access through the VFS interface
+var files = vfs.New(vfs.Host())
+
+func environmentGetVFSInterface(data unsafe.Pointer) bool {
+	return state.Core.BindVFSInterface(data, files)
+}
+
+// sandboxFiles restricts the files cores can access in kiosk mode to the
+// system and save directories, and to the directories of the game files
+func sandboxFiles(gamePaths ...string) {
+	if !state.Kiosk {
+		files.Allow()
+		return
+	}
+	dirs := []string{settings.Current.SystemDirectory, settings.Current.SavefilesDirectory}
+	for _, gamePath := range gamePaths {
+		if gamePath != "" {
+			dirs = append(dirs, filepath.Dir(gamePath))
+		}
+	}
+	files.Allow(dirs...)
+}
+
+// archiveEntry picks the file of a zip archive to pass to the core, the
+// first one or a better match like unarchiveGame
+func archiveEntry(filename string) (string, int64, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+
+	entry := ""
+	size := int64(0)
+	extPriority := 0
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		priority := extPrefered[strings.ToLower(filepath.Ext(f.Name))]
+		if entry == "" || priority > extPriority {
+			entry = f.Name
+			size = int64(f.UncompressedSize64)
+			extPriority = priority
+		}
+	}
+	if entry == "" {
+		return "", 0, zip.ErrFormat
+	}
+	return entry, size, nil
+}
+
+// validateArchiveEntry checks that the tracks or discs referenced by a cue
+// sheet or an m3u playlist are in the zip archive too
+func validateArchiveEntry(filename, entry string) error {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return discs.Validate(r, entry)
+}
+
+// readGame reads a game in memory, from inside a zip archive if needed
+func readGame(path string) ([]byte, error) {
+	if _, _, ok := vfs.SplitArchivePath(path); ok {
+		return files.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,184 @@
+// Package coreinfo reads the libretro .info files describing the cores: their
+// name, the extensions they support and the firmware they need. They are used
+// to pick a core for a file and to check the BIOS before starting a core.
+package coreinfo
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+// Firmware is a BIOS or system file a core looks for in the system directory
+type Firmware struct {
+	Path     string // Relative to the system directory
+	Desc     string
+	Optional bool
+	MD5      string // Lower case, empty when the .info file doesn't list it
+}
+
+// Info describes a core, as read from its .info file
+type Info struct {
+	Name                string // File name of the core, without extension
+	DisplayName         string
+	SystemName          string
+	SupportedExtensions []string // Lower case, without the dot
+	Firmware            []Firmware
+	SupportsNoGame      bool
+}
+
+// Infos maps the file name of the cores to their Info
+var Infos = map[string]Info{}
+
+// unquote removes the quotes around a value
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseNotes extracts the checksums listed in the notes, formatted like
+// "(!) scph5501.bin (md5): 490f666e1afb15b7362b406ed1cea246"
+func parseNotes(notes string) map[string]string {
+	sums := map[string]string{}
+	for _, note := range strings.Split(notes, "|") {
+		i := strings.Index(note, "(md5):")
+		if i < 0 {
+			continue
+		}
+		file := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(note[:i]), "(!)"))
+		sums[file] = strings.ToLower(strings.TrimSpace(note[i+len("(md5):"):]))
+	}
+	return sums
+}
+
+// Parse reads a .info file, name is the file name of the core
+func Parse(name string, r io.Reader) (Info, error) {
+	kv := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		kv[strings.TrimSpace(parts[0])] = unquote(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return Info{}, err
+	}
+
+	info := Info{
+		Name:           name,
+		DisplayName:    kv["display_name"],
+		SystemName:     kv["systemname"],
+		SupportsNoGame: kv["supports_no_game"] == "true",
+	}
+	for _, ext := range strings.Split(kv["supported_extensions"], "|") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext != "" {
+			info.SupportedExtensions = append(info.SupportedExtensions, ext)
+		}
+	}
+
+	sums := parseNotes(kv["notes"])
+	count, _ := strconv.Atoi(kv["firmware_count"])
+	for i := 0; i < count; i++ {
+		prefix := "firmware" + strconv.Itoa(i) + "_"
+		fw := Firmware{
+			Path:     kv[prefix+"path"],
+			Desc:     kv[prefix+"desc"],
+			Optional: kv[prefix+"opt"] == "true",
+		}
+		if fw.Path == "" {
+			continue
+		}
+		fw.MD5 = sums[fw.Path]
+		if fw.MD5 == "" {
+			fw.MD5 = sums[filepath.Base(fw.Path)]
+		}
+		info.Firmware = append(info.Firmware, fw)
+	}
+	return info, nil
+}
+
+// Load reads the .info files of the core info directory
+func Load() {
+	Infos = map[string]Info{}
+	paths, err := filepath.Glob(filepath.Join(settings.Current.CoreInfoDirectory, "*.info"))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		info, err := Parse(utils.FileName(path), file)
+		file.Close()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		Infos[info.Name] = info
+	}
+}
+
+// Get returns the Info of the core at the given path
+func Get(corePath string) (Info, bool) {
+	info, ok := Infos[utils.FileName(corePath)]
+	return info, ok
+}
+
+// Supports tells if the core supports the extension of a file
+func (info Info) Supports(path string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	for _, e := range info.SupportedExtensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Compatible returns the paths of the installed cores supporting a file,
+// sorted by display name
+func Compatible(path string) []string {
+	infos := []Info{}
+	for _, info := range Infos {
+		if !info.Supports(path) {
+			continue
+		}
+		if _, err := os.Stat(CorePath(info)); err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].DisplayName < infos[j].DisplayName
+	})
+	cores := []string{}
+	for _, info := range infos {
+		cores = append(cores, CorePath(info))
+	}
+	return cores
+}
+
+// CorePath returns the path of the core in the cores directory
+func CorePath(info Info) string {
+	return filepath.Join(settings.Current.CoresDirectory, info.Name+utils.CoreExt())
+}
@@ -0,0 +1,103 @@
+package coreinfo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/settings"
+)
+
+func TestParse(t *testing.T) {
+	file, err := os.Open("testdata/mednafen_psx_libretro.info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	info, err := Parse("mednafen_psx_libretro", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Reads the names", func(t *testing.T) {
+		if info.DisplayName != "Sony - PlayStation (Beetle PSX)" {
+			t.Errorf("got = %v, want %v", info.DisplayName, "Sony - PlayStation (Beetle PSX)")
+		}
+		if info.SystemName != "PlayStation" {
+			t.Errorf("got = %v, want %v", info.SystemName, "PlayStation")
+		}
+	})
+
+	t.Run("Reads the supported extensions", func(t *testing.T) {
+		want := []string{"cue", "toc", "m3u", "ccd", "exe", "pbp", "chd"}
+		if !reflect.DeepEqual(info.SupportedExtensions, want) {
+			t.Errorf("got = %v, want %v", info.SupportedExtensions, want)
+		}
+		if !info.Supports("/roms/Game (USA).CUE") {
+			t.Errorf("got = %v, want %v", false, true)
+		}
+		if info.Supports("/roms/Game.bin") {
+			t.Errorf("got = %v, want %v", true, false)
+		}
+	})
+
+	t.Run("Reads the firmware with their checksum", func(t *testing.T) {
+		want := []Firmware{
+			{Path: "scph5500.bin", Desc: "scph5500.bin (PS1 JP BIOS)", MD5: "8dd7d5296a650fac7319bce665a6a53c"},
+			{Path: "scph5501.bin", Desc: "scph5501.bin (PS1 US BIOS)", MD5: "490f666e1afb15b7362b406ed1cea246"},
+			{Path: "scph5502.bin", Desc: "scph5502.bin (PS1 EU BIOS)", Optional: true, MD5: "32736f17079d0b2b7024407c39bd3050"},
+		}
+		if !reflect.DeepEqual(info.Firmware, want) {
+			t.Errorf("got = %v, want %v", info.Firmware, want)
+		}
+	})
+
+	t.Run("Reads supports_no_game", func(t *testing.T) {
+		if info.SupportsNoGame {
+			t.Errorf("got = %v, want %v", info.SupportsNoGame, false)
+		}
+	})
+}
+
+func TestCompatible(t *testing.T) {
+	dir := t.TempDir()
+	settings.Current.CoreInfoDirectory = "testdata"
+	settings.Current.CoresDirectory = dir
+	Load()
+
+	t.Run("Loads all the info files", func(t *testing.T) {
+		if len(Infos) != 2 || !Infos["2048_libretro"].SupportsNoGame {
+			t.Errorf("got = %v, want %v", Infos, "the two cores")
+		}
+	})
+
+	t.Run("Skips the cores that are not installed", func(t *testing.T) {
+		if got := Compatible("game.cue"); len(got) != 0 {
+			t.Errorf("got = %v, want %v", got, []string{})
+		}
+	})
+
+	core := CorePath(Infos["mednafen_psx_libretro"])
+	if err := os.WriteFile(core, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Returns the installed cores supporting a file", func(t *testing.T) {
+		want := []string{core}
+		if got := Compatible("game.cue"); !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+		if got := Compatible("game.sfc"); len(got) != 0 {
+			t.Errorf("got = %v, want %v", got, []string{})
+		}
+	})
+
+	t.Run("Finds the info of a core from its path", func(t *testing.T) {
+		info, ok := Get(filepath.Join(dir, "mednafen_psx_libretro.so"))
+		if !ok || info.Name != "mednafen_psx_libretro" {
+			t.Errorf("got = %v, want %v", info.Name, "mednafen_psx_libretro")
+		}
+	})
+}
@@ -0,0 +1,232 @@
+// Package discs understands the files describing CD games: cue sheets listing
+// the tracks of a disc and m3u playlists listing the discs of a game. It
+// checks that the files they reference exist and groups the discs named
+// "Game (Disc N)" into generated m3u playlists.
+package discs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/utils"
+)
+
+// Disc is an entry of an m3u playlist
+type Disc struct {
+	Path  string // Relative to the directory of the playlist, slash separated
+	Label string // Optional, from the "path|label" syntax
+}
+
+// ParseM3U reads the discs listed in an m3u playlist. Comments and blank
+// lines are skipped.
+func ParseM3U(r io.Reader) ([]Disc, error) {
+	discs := []Disc{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		disc := Disc{Path: line}
+		if i := strings.LastIndex(line, "|"); i >= 0 {
+			disc.Path = strings.TrimSpace(line[:i])
+			disc.Label = strings.TrimSpace(line[i+1:])
+		}
+		disc.Path = strings.ReplaceAll(disc.Path, `\`, "/")
+		discs = append(discs, disc)
+	}
+	return discs, scanner.Err()
+}
+
+// ParseCue reads the files referenced by the FILE commands of a cue sheet,
+// relative to the directory of the cue sheet
+func ParseCue(r io.Reader) ([]string, error) {
+	files := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 5 || !strings.EqualFold(line[:5], "FILE ") {
+			continue
+		}
+		name := strings.TrimSpace(line[5:])
+		if strings.HasPrefix(name, `"`) {
+			// FILE "name with spaces.bin" BINARY
+			if end := strings.Index(name[1:], `"`); end >= 0 {
+				name = name[1 : end+1]
+			}
+		} else if i := strings.LastIndex(name, " "); i >= 0 {
+			// FILE name.bin BINARY
+			name = name[:i]
+		}
+		name = strings.ReplaceAll(name, `\`, "/")
+		if name != "" && !utils.StringInSlice(name, files) {
+			files = append(files, name)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// Validate checks that the tracks of a cue sheet, or the discs of an m3u
+// playlist and their tracks, exist in fsys. name is the slash separated path
+// of the cue sheet or playlist in fsys. Other files are always valid.
+func Validate(fsys fs.FS, name string) error {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".cue":
+		return validateCue(fsys, name)
+	case ".m3u":
+		return validateM3U(fsys, name)
+	}
+	return nil
+}
+
+// ValidateFile is Validate for a file of the filesystem
+func ValidateFile(filename string) error {
+	return Validate(os.DirFS(filepath.Dir(filename)), filepath.Base(filename))
+}
+
+func validateCue(fsys fs.FS, name string) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tracks, err := ParseCue(file)
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return fmt.Errorf("%s lists no track", path.Base(name))
+	}
+	for _, track := range tracks {
+		if _, err := fs.Stat(fsys, path.Join(path.Dir(name), track)); err != nil {
+			return fmt.Errorf("%s: missing track %s", path.Base(name), track)
+		}
+	}
+	return nil
+}
+
+func validateM3U(fsys fs.FS, name string) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	discs, err := ParseM3U(file)
+	if err != nil {
+		return err
+	}
+	if len(discs) == 0 {
+		return fmt.Errorf("%s lists no disc", path.Base(name))
+	}
+	for _, disc := range discs {
+		discPath := path.Join(path.Dir(name), disc.Path)
+		if _, err := fs.Stat(fsys, discPath); err != nil {
+			return fmt.Errorf("%s: missing disc %s", path.Base(name), disc.Path)
+		}
+		if err := Validate(fsys, discPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discTag matches the disc number in names like "Game (USA) (Disc 2)" or
+// "Game (Disk 1 of 3)"
+var discTag = regexp.MustCompile(`(?i)\s*\(Dis[ck] (\d+)(?: of \d+)?\)`)
+
+// DiscNumber returns the disc number found in a file name
+func DiscNumber(name string) (int, bool) {
+	m := discTag.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	return n, err == nil
+}
+
+// StripDisc removes the disc number from a file name or a game title
+func StripDisc(name string) string {
+	return discTag.ReplaceAllString(name, "")
+}
+
+// Set is a game made of several discs
+type Set struct {
+	M3U   string   // Path of the playlist grouping the discs
+	Discs []string // Paths of the discs, sorted by disc number
+}
+
+// imageExts are the extensions of the disc and disk images that can be
+// grouped. Track files like .bin are left out, they are listed in cue sheets.
+var imageExts = map[string]bool{
+	".cue": true,
+	".chd": true,
+	".pbp": true,
+	".iso": true,
+	".ccd": true,
+	".gdi": true,
+	".cdi": true,
+	".mds": true,
+	".adf": true,
+	".ipf": true,
+	".d64": true,
+	".dsk": true,
+}
+
+// FindSets groups the images named "Game (Disc N)" of the same directory and
+// extension into sets of discs. Files without a disc number and lone discs
+// are ignored.
+func FindSets(paths []string) []Set {
+	groups := map[string][]string{}
+	numbers := map[string]int{}
+	for _, p := range paths {
+		ext := filepath.Ext(p)
+		if !imageExts[strings.ToLower(ext)] {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(p), ext)
+		n, ok := DiscNumber(name)
+		if !ok {
+			continue
+		}
+		// Discs with different extensions don't belong to the same set
+		key := filepath.Join(filepath.Dir(p), StripDisc(name)) + ".m3u\x00" + strings.ToLower(ext)
+		groups[key] = append(groups[key], p)
+		numbers[p] = n
+	}
+
+	sets := []Set{}
+	for key, discs := range groups {
+		if len(discs) < 2 {
+			continue
+		}
+		sort.Slice(discs, func(i, j int) bool { return numbers[discs[i]] < numbers[discs[j]] })
+		sets = append(sets, Set{M3U: strings.Split(key, "\x00")[0], Discs: discs})
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].M3U < sets[j].M3U })
+	return sets
+}
+
+// WriteM3U writes the playlist of a set of discs, with paths relative to
+// the playlist
+func WriteM3U(set Set) error {
+	var b strings.Builder
+	for _, disc := range set.Discs {
+		rel, err := filepath.Rel(filepath.Dir(set.M3U), disc)
+		if err != nil {
+			return err
+		}
+		b.WriteString(filepath.ToSlash(rel) + "\n")
+	}
+	return os.WriteFile(set.M3U, []byte(b.String()), 0644)
+}
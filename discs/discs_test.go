@@ -0,0 +1,164 @@
+package discs
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseM3U(t *testing.T) {
+	got, err := ParseM3U(strings.NewReader("#EXTM3U\n\nGame (Disc 1).cue\r\nsub\\Game (Disc 2).cue|Second disc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Disc{
+		{Path: "Game (Disc 1).cue"},
+		{Path: "sub/Game (Disc 2).cue", Label: "Second disc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestParseCue(t *testing.T) {
+	cue := `FILE "Game (Track 1).bin" BINARY
+  TRACK 01 MODE2/2352
+    INDEX 01 00:00:00
+FILE track2.bin BINARY
+  TRACK 02 AUDIO
+file "Game (Track 1).bin" BINARY
+`
+	got, err := ParseCue(strings.NewReader(cue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Game (Track 1).bin", "track2.bin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"psx/Game (Disc 1).cue": {Data: []byte(`FILE "Game (Disc 1).bin" BINARY`)},
+		"psx/Game (Disc 1).bin": {Data: []byte("track")},
+		"psx/Game (Disc 2).cue": {Data: []byte(`FILE "Game (Disc 2).bin" BINARY`)},
+		"psx/Game.m3u":          {Data: []byte("Game (Disc 1).cue\nGame (Disc 2).cue\n")},
+		"psx/Other.m3u":         {Data: []byte("Game (Disc 1).cue\nGame (Disc 3).cue\n")},
+		"psx/Empty.m3u":         {Data: []byte("#EXTM3U\n")},
+		"psx/game.iso":          {Data: []byte("iso")},
+	}
+
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{"psx/Game (Disc 1).cue", ""},
+		{"psx/Game (Disc 2).cue", "missing track Game (Disc 2).bin"},
+		{"psx/Game.m3u", "missing track Game (Disc 2).bin"},
+		{"psx/Other.m3u", "missing disc Game (Disc 3).cue"},
+		{"psx/Empty.m3u", "lists no disc"},
+		{"psx/game.iso", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(fsys, tt.name)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("got = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Looks for the tracks inside zip archives", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range map[string]string{"game.cue": `FILE "game.bin" BINARY`, "game.bin": "track"} {
+			f, err := w.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte(content))
+		}
+		w.Close()
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(r, "game.cue"); err != nil {
+			t.Errorf("got = %v, want nil", err)
+		}
+	})
+}
+
+func TestDiscNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   int
+		wantOK bool
+	}{
+		{"Final Fantasy VII (USA) (Disc 2)", 2, true},
+		{"Monkey Island (Disk 1 of 4)", 1, true},
+		{"Tetris (World)", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := DiscNumber(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("DiscNumber(%q) = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got := StripDisc("Final Fantasy VII (USA) (Disc 2)"); got != "Final Fantasy VII (USA)" {
+		t.Errorf("got = %v, want %v", got, "Final Fantasy VII (USA)")
+	}
+}
+
+func TestFindSets(t *testing.T) {
+	got := FindSets([]string{
+		"/roms/psx/FF7 (USA) (Disc 3).cue",
+		"/roms/psx/FF7 (USA) (Disc 1).cue",
+		"/roms/psx/FF7 (USA) (Disc 1).bin",
+		"/roms/psx/FF7 (USA) (Disc 2).cue",
+		"/roms/psx/FF7 (USA) (Disc 2).bin",
+		"/roms/psx/Lone (USA) (Disc 1).cue",
+		"/roms/psx/Tekken (USA).cue",
+		"/roms/psx/Mixed (Disc 1).cue",
+		"/roms/psx/Mixed (Disc 2).chd",
+	})
+	want := []Set{{
+		M3U: "/roms/psx/FF7 (USA).m3u",
+		Discs: []string{
+			"/roms/psx/FF7 (USA) (Disc 1).cue",
+			"/roms/psx/FF7 (USA) (Disc 2).cue",
+			"/roms/psx/FF7 (USA) (Disc 3).cue",
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestWriteM3U(t *testing.T) {
+	dir := t.TempDir()
+	set := Set{
+		M3U:   filepath.Join(dir, "Game.m3u"),
+		Discs: []string{filepath.Join(dir, "Game (Disc 1).chd"), filepath.Join(dir, "Game (Disc 2).chd")},
+	}
+	if err := WriteM3U(set); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(set.M3U)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Game (Disc 1).chd\nGame (Disc 2).chd\n"
+	if string(got) != want {
+		t.Errorf("got = %q, want %q", got, want)
+	}
+}
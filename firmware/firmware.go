@@ -0,0 +1,230 @@
+// Package firmware checks the BIOS and system files needed by the cores. The
+// files expected by a core come from its .info file and from a list of known
+// firmware, and are hashed to tell apart present, missing and wrong versions.
+package firmware
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libretro/ludo/coreinfo"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+// File is a firmware file a core looks for in the system directory
+type File struct {
+	Path     string `json:"path"` // Relative to the system directory
+	Desc     string `json:"desc"`
+	Optional bool   `json:"optional"`
+	Group    string `json:"group,omitempty"` // Any present file of a group is enough
+	MD5      string `json:"md5,omitempty"`   // Lower case, empty when unknown
+	SHA1     string `json:"sha1,omitempty"`  // Lower case, empty when unknown
+}
+
+// Status is the result of checking a firmware file
+type Status string
+
+// The possible statuses of a firmware file
+const (
+	Present      Status = "present"
+	Missing      Status = "missing"
+	WrongVersion Status = "wrong_version" // The checksums don't match
+)
+
+// Report is the status of a firmware file in the system directory
+type Report struct {
+	File
+	Status Status `json:"status"`
+}
+
+// Files returns the firmware expected by a core, from its .info file and the
+// list of known firmware
+func Files(corePath string) []File {
+	name := utils.FileName(corePath)
+	files := []File{}
+	index := map[string]int{}
+	if info, ok := coreinfo.Get(corePath); ok {
+		for _, fw := range info.Firmware {
+			index[fw.Path] = len(files)
+			files = append(files, File{Path: fw.Path, Desc: fw.Desc, Optional: fw.Optional, MD5: fw.MD5})
+		}
+	}
+	for _, k := range Known[name] {
+		i, ok := index[k.Path]
+		if !ok {
+			index[k.Path] = len(files)
+			files = append(files, k)
+			continue
+		}
+		if files[i].MD5 == "" {
+			files[i].MD5 = k.MD5
+		}
+		if files[i].SHA1 == "" {
+			files[i].SHA1 = k.SHA1
+		}
+		if files[i].Desc == "" {
+			files[i].Desc = k.Desc
+		}
+		if files[i].Group == "" {
+			files[i].Group = k.Group
+		}
+	}
+	return files
+}
+
+// Check looks for the firmware of a core in the system directory
+func Check(corePath, systemDir string) []Report {
+	reports := []Report{}
+	for _, f := range Files(corePath) {
+		r := Report{File: f, Status: Present}
+		sums, err := hashFile(filepath.Join(systemDir, f.Path))
+		switch {
+		case err != nil:
+			r.Status = Missing
+		case f.MD5 != "" && sums.md5 != f.MD5, f.SHA1 != "" && sums.sha1 != f.SHA1:
+			r.Status = WrongVersion
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+// Ready tells if none of the required firmware of a core is missing
+func Ready(corePath, systemDir string) bool {
+	return len(missing(Check(corePath, systemDir))) == 0
+}
+
+// missing returns the required files missing from a report. Files of a
+// group are only missing when no file of their group is present, and are
+// then returned together, like "scph5500.bin or scph5501.bin".
+func missing(reports []Report) []string {
+	found := map[string]bool{}
+	for _, r := range reports {
+		if r.Group != "" && r.Status != Missing {
+			found[r.Group] = true
+		}
+	}
+	paths := []string{}
+	groups := map[string][]string{}
+	for _, r := range reports {
+		if r.Status != Missing || r.Optional || found[r.Group] {
+			continue
+		}
+		if r.Group == "" {
+			paths = append(paths, r.Path)
+			continue
+		}
+		if groups[r.Group] == nil {
+			paths = append(paths, r.Group)
+		}
+		groups[r.Group] = append(groups[r.Group], r.Path)
+	}
+	for i, p := range paths {
+		if files, ok := groups[p]; ok {
+			paths[i] = strings.Join(files, " or ")
+		}
+	}
+	return paths
+}
+
+// Cores returns the paths of the installed cores expecting firmware, sorted
+func Cores() []string {
+	names := map[string]bool{}
+	for name := range Known {
+		names[name] = true
+	}
+	for name, info := range coreinfo.Infos {
+		if len(info.Firmware) > 0 {
+			names[name] = true
+		}
+	}
+	cores := []string{}
+	for name := range names {
+		path := filepath.Join(settings.Current.CoresDirectory, name+utils.CoreExt())
+		if _, err := os.Stat(path); err == nil {
+			cores = append(cores, path)
+		}
+	}
+	sort.Strings(cores)
+	return cores
+}
+
+type sums struct {
+	md5, sha1 string
+	size      int64
+	modTime   time.Time
+}
+
+// cache keeps the checksums of the files hashed, until they change on disk
+var cache = struct {
+	sync.Mutex
+	sums map[string]sums
+}{sums: map[string]sums{}}
+
+// hashFile returns the checksums of a file, in lower case hexadecimal
+func hashFile(path string) (sums, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return sums{}, err
+	}
+	if fi.IsDir() {
+		return sums{}, os.ErrNotExist
+	}
+
+	cache.Lock()
+	s, ok := cache.sums[path]
+	cache.Unlock()
+	if ok && s.size == fi.Size() && s.modTime.Equal(fi.ModTime()) {
+		return s, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return sums{}, err
+	}
+	defer f.Close()
+	hm := md5.New()
+	hs := sha1.New()
+	if _, err := io.Copy(io.MultiWriter(hm, hs), f); err != nil {
+		return sums{}, err
+	}
+	s = sums{
+		md5:     hex.EncodeToString(hm.Sum(nil)),
+		sha1:    hex.EncodeToString(hs.Sum(nil)),
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+	}
+
+	cache.Lock()
+	cache.sums[path] = s
+	cache.Unlock()
+	return s, nil
+}
+
+// Summary describes the problems of a report in a few words, like
+// "Missing: scph5501.bin"
+func Summary(reports []Report) string {
+	wrong := []string{}
+	for _, r := range reports {
+		if r.Status == WrongVersion {
+			wrong = append(wrong, r.Path)
+		}
+	}
+	parts := []string{}
+	if missing := missing(reports); len(missing) > 0 {
+		parts = append(parts, "Missing: "+strings.Join(missing, ", "))
+	}
+	if len(wrong) > 0 {
+		parts = append(parts, "Wrong version: "+strings.Join(wrong, ", "))
+	}
+	return strings.Join(parts, ". ")
+}
@@ -0,0 +1,116 @@
+package firmware
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/coreinfo"
+)
+
+func TestFiles(t *testing.T) {
+	coreinfo.Infos = map[string]coreinfo.Info{
+		"mednafen_psx_libretro": {
+			Name: "mednafen_psx_libretro",
+			Firmware: []coreinfo.Firmware{
+				{Path: "scph5501.bin", Desc: "scph5501.bin (PS1 US BIOS)", MD5: "490f666e1afb15b7362b406ed1cea246"},
+			},
+		},
+	}
+	defer func() { coreinfo.Infos = map[string]coreinfo.Info{} }()
+
+	got := Files("cores/mednafen_psx_libretro.so")
+
+	t.Run("Completes the info file with the known checksums", func(t *testing.T) {
+		want := File{
+			Path:  "scph5501.bin",
+			Desc:  "scph5501.bin (PS1 US BIOS)",
+			Group: "PS1 BIOS",
+			MD5:   "490f666e1afb15b7362b406ed1cea246",
+			SHA1:  "0555c6fae8906f3f09baf5988f00e55f88e9f30b",
+		}
+		if !reflect.DeepEqual(got[0], want) {
+			t.Errorf("got = %v, want %v", got[0], want)
+		}
+	})
+
+	t.Run("Adds the known files missing from the info file", func(t *testing.T) {
+		if len(got) != 3 || got[1].Path != "scph5500.bin" || got[2].Path != "scph5502.bin" {
+			t.Errorf("got = %v, want %v", got, "the three BIOS")
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	Known["test_libretro"] = []File{
+		{Path: "good.bin", MD5: "5d41402abc4b2a76b9719d911017c592", SHA1: "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{Path: "bad.bin", MD5: "5d41402abc4b2a76b9719d911017c592"},
+		{Path: "sub/unknown.bin"},
+		{Path: "missing.bin"},
+		{Path: "extra.bin", Optional: true},
+	}
+	defer delete(Known, "test_libretro")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "good.bin"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(dir, "bad.bin"), []byte("hallo"), 0644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "unknown.bin"), []byte("anything"), 0644)
+
+	got := []Status{}
+	reports := Check("test_libretro.so", dir)
+	for _, r := range reports {
+		got = append(got, r.Status)
+	}
+
+	t.Run("Hashes the files of the system directory", func(t *testing.T) {
+		want := []Status{Present, WrongVersion, Present, Missing, Missing}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Summarizes the problems", func(t *testing.T) {
+		want := "Missing: missing.bin. Wrong version: bad.bin"
+		if got := Summary(reports); got != want {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Is not ready while a required file is missing", func(t *testing.T) {
+		if Ready("test_libretro.so", dir) {
+			t.Errorf("got = %v, want %v", true, false)
+		}
+		os.WriteFile(filepath.Join(dir, "missing.bin"), []byte("here"), 0644)
+		if !Ready("test_libretro.so", dir) {
+			t.Errorf("got = %v, want %v", false, true)
+		}
+	})
+}
+
+func TestReady(t *testing.T) {
+	dir := t.TempDir()
+	core := "cores/mednafen_psx_libretro.so"
+
+	t.Run("Needs one of the BIOS of a group", func(t *testing.T) {
+		if Ready(core, dir) {
+			t.Errorf("got = %v, want %v", true, false)
+		}
+		want := "Missing: scph5500.bin or scph5501.bin or scph5502.bin"
+		if got := Summary(Check(core, dir)); got != want {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Is ready with a single BIOS of the group", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "scph5501.bin"), []byte("not a real BIOS"), 0644)
+		if !Ready(core, dir) {
+			t.Errorf("got = %v, want %v", false, true)
+		}
+		want := "Wrong version: scph5501.bin"
+		if got := Summary(Check(core, dir)); got != want {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package firmware
+
+// The BIOS of the PlayStation cores, any region is enough
+var (
+	scph5500 = File{Path: "scph5500.bin", Desc: "PS1 JP BIOS", Group: "PS1 BIOS", MD5: "8dd7d5296a650fac7319bce665a6a53c", SHA1: "b05def971d8ec59f346f2d9ac21fb742e3eb6917"}
+	scph5501 = File{Path: "scph5501.bin", Desc: "PS1 US BIOS", Group: "PS1 BIOS", MD5: "490f666e1afb15b7362b406ed1cea246", SHA1: "0555c6fae8906f3f09baf5988f00e55f88e9f30b"}
+	scph5502 = File{Path: "scph5502.bin", Desc: "PS1 EU BIOS", Group: "PS1 BIOS", MD5: "32736f17079d0b2b7024407c39bd3050", SHA1: "f6bc2d1f5eb6593de7d089c425ac681d6fffd3f0"}
+)
+
+// optional returns a copy of f that the core can run without
+func optional(f File) File {
+	f.Optional = true
+	return f
+}
+
+// Known lists the firmware of the cores offered by default, per core file
+// name. They complete the .info files, which only give MD5 checksums.
+var Known = map[string][]File{
+	"mednafen_psx_libretro":    {scph5500, scph5501, scph5502},
+	"mednafen_psx_hw_libretro": {scph5500, scph5501, scph5502},
+	"swanstation_libretro":     {optional(scph5500), optional(scph5501), optional(scph5502)},
+	"pcsx_rearmed_libretro":    {optional(scph5500), optional(scph5501), optional(scph5502)},
+	"mgba_libretro": {
+		{Path: "gba_bios.bin", Desc: "Game Boy Advance BIOS", Optional: true, MD5: "a860e8c0b6d573d191e4ec7db1b1e4f6", SHA1: "300c20df6731a33952ded8c436f7f186d25d3492"},
+	},
+	"fceumm_libretro": {
+		{Path: "disksys.rom", Desc: "Family Computer Disk System BIOS", Optional: true, MD5: "ca30b50f880eb660a320674ed365ef7a", SHA1: "57fe1bdee955bb48d357e463ccbf129496930b62"},
+	},
+	"mednafen_pce_libretro": {
+		{Path: "syscard3.pce", Desc: "PC Engine CD System Card 3", Optional: true, MD5: "38179df8f4ac870017db21ebcbf53114", SHA1: "79f5ff55dd10187c7fd7b8daab0b3ffbd1f56a2c"},
+	},
+	"genesis_plus_gx_libretro": {
+		{Path: "bios_CD_E.bin", Desc: "Mega-CD EU BIOS", Optional: true, MD5: "e66fa1dc5820d254611fdcdba0662372"},
+		{Path: "bios_CD_U.bin", Desc: "Sega CD US BIOS", Optional: true, MD5: "2efd74e3232ff260e371b99f84024f7f"},
+		{Path: "bios_CD_J.bin", Desc: "Mega-CD JP BIOS", Optional: true, MD5: "278a9397d192149e84e820ac621a8edd"},
+	},
+}
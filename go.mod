@@ -17,11 +17,11 @@ require (
 	golang.org/x/mobile v0.0.0-20250305212854-3a7bc9f8a4de
 )
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
 
 require (
 	fyne.io/fyne v1.4.3
-	fyne.io/fyne/v2 v2.6.1 // indirect
+	fyne.io/fyne/v2 v2.6.1
 	fyne.io/systray v1.11.0 // indirect
 	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/andybalholm/brotli v1.1.1 // indirect
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/adrg/xdg"
 )
@@ -19,6 +20,8 @@ type Game struct {
 	System    string // Name of the game console
 	CorePath  string // Absolute path to the libretro core
 	Savestate string // Absolute path of the last savestate on this game
+	DiskIndex uint   // Index of the last disk inserted in a multi-disk game
+	DiskPath  string // Path of the last disk inserted, checked before restoring it
 }
 
 // History is a list of games
@@ -29,6 +32,12 @@ var List History
 
 // Push pushes a game onto the stack
 func Push(g Game) {
+	// Keep the disk of a game played before
+	if prev, ok := Find(g.Path); ok && g.DiskPath == "" {
+		g.DiskIndex = prev.DiskIndex
+		g.DiskPath = prev.DiskPath
+	}
+
 	List = append([]Game{g}, List...)
 
 	// Deduplicate
@@ -48,6 +57,35 @@ func Push(g Game) {
 	}
 }
 
+// Find returns the entry of a game in the history
+func Find(path string) (Game, bool) {
+	for _, g := range List {
+		if g.Path == path {
+			return g, true
+		}
+	}
+	return Game{}, false
+}
+
+// SetDisk remembers the last disk inserted in a game, to insert it again on
+// the next launch. Games missing from the history are ignored.
+func SetDisk(path string, index uint, diskPath string) {
+	for i := range List {
+		if List[i].Path != path {
+			continue
+		}
+		if List[i].DiskIndex == index && List[i].DiskPath == diskPath {
+			return
+		}
+		List[i].DiskIndex = index
+		List[i].DiskPath = diskPath
+		if err := Save(); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+}
+
 // Load loads history.csv in memory
 func Load() error {
 	file, err := os.Open(filepath.Join(xdg.DataHome, "ludo", "history.csv"))
@@ -57,6 +95,8 @@ func Load() error {
 	defer file.Close()
 
 	wr := csv.NewReader(bufio.NewReader(file))
+	// Files written before the disk columns have fewer fields
+	wr.FieldsPerRecord = -1
 
 	List = History{}
 	for {
@@ -67,12 +107,21 @@ func Load() error {
 		if err != nil {
 			return err
 		}
-		List = append(List, Game{
+		if len(record) < 4 {
+			continue
+		}
+		game := Game{
 			Path:     record[0],
 			Name:     record[1],
 			System:   record[2],
 			CorePath: record[3],
-		})
+		}
+		if len(record) >= 6 {
+			index, _ := strconv.ParseUint(record[4], 10, 32)
+			game.DiskIndex = uint(index)
+			game.DiskPath = record[5]
+		}
+		List = append(List, game)
 	}
 
 	return nil
@@ -95,6 +144,8 @@ func Save() error {
 			game.Name,
 			game.System,
 			game.CorePath,
+			strconv.FormatUint(uint64(game.DiskIndex), 10),
+			game.DiskPath,
 		})
 	}
 
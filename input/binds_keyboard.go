@@ -22,4 +22,5 @@ var keyBinds = map[glfw.Key]uint32{
 	glfw.KeyP:          ActionMenuToggle, // Change P to menu toggle instead of payment
 	glfw.KeyF:          ActionFullscreenToggle,
 	glfw.KeyEscape:     ActionShouldClose,
+	glfw.KeyR:          ActionRewind,
 }
@@ -0,0 +1,252 @@
+package input
+
+import (
+	"sync"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	lr "github.com/libretro/ludo/libretro"
+)
+
+// Kinds of DeviceEvent
+const (
+	DeviceRumble = "rumble"
+	DeviceLED    = "led"
+	DeviceSensor = "sensor"
+)
+
+// DeviceEvent reports a change of the rumble motors, LEDs or sensors driven
+// by the core. For rumble, ID is the motor and Value the strength between 0
+// and 1. For LEDs, Port is unused and Value is the LED state. For sensors, ID
+// is the sensor and Value its reading, or -1 when the core disabled it.
+type DeviceEvent struct {
+	Kind  string  `json:"kind"`
+	Port  uint    `json:"port"`
+	ID    uint    `json:"id"`
+	Value float64 `json:"value"`
+}
+
+// rumbler drives the force feedback motors of a gamepad
+type rumbler interface {
+	Rumble(strong, weak uint16) error
+	Close() error
+}
+
+// DefaultIlluminance is the emulated ambient light, in lux, until the
+// players change it
+const DefaultIlluminance = 400
+
+var (
+	devicesMu      sync.Mutex
+	deviceListener func(DeviceEvent)
+
+	rumbleState [MaxPlayers][2]uint16
+	rumblers    [MaxPlayers]rumbler
+	rumblerName [MaxPlayers]string
+
+	ledState = map[int]int{}
+
+	illuminanceOn [MaxPlayers]bool
+	illuminance   float32 = DefaultIlluminance
+)
+
+// gamepadNames are the names of the gamepads plugged in each port, refreshed
+// by Poll since GLFW can only be queried from the main thread
+var gamepadNames [MaxPlayers]string
+
+// gamepadName returns the name of the gamepad plugged in port, or an empty
+// string when there is none. Must be called with devicesMu held.
+var gamepadName = func(port uint) string {
+	return gamepadNames[port]
+}
+
+// pollGamepadNames caches the names of the gamepads. Ports are assigned like
+// in pollJoypads.
+func pollGamepadNames() {
+	var names [MaxPlayers]string
+	if vid != nil && !vid.Headless() {
+		p := 0
+		for joy := glfw.Joystick(0); joy < glfw.JoystickLast && p < MaxPlayers; joy++ {
+			if !joy.IsGamepad() {
+				continue
+			}
+			names[p] = joy.GetName()
+			p++
+		}
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	gamepadNames = names
+}
+
+// SetDeviceListener registers f to be notified of the device events. f is
+// called with the device state locked and must not block.
+func SetDeviceListener(f func(DeviceEvent)) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	deviceListener = f
+}
+
+// emit must be called with devicesMu held
+func emit(e DeviceEvent) {
+	if deviceListener != nil {
+		deviceListener(e)
+	}
+}
+
+// SetRumbleState is the rumble callback given to the core. It forwards the
+// strength of the motor to the gamepad of the port if it supports force
+// feedback.
+func SetRumbleState(port uint, effect uint32, strength uint16) bool {
+	if port >= MaxPlayers || effect > lr.RumbleWeak {
+		return false
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if rumbleState[port][effect] == strength {
+		return true
+	}
+	rumbleState[port][effect] = strength
+	emit(DeviceEvent{Kind: DeviceRumble, Port: port, ID: uint(effect), Value: float64(strength) / 0xffff})
+
+	r := rumblerFor(port)
+	if r == nil {
+		return false
+	}
+	state := rumbleState[port]
+	if err := r.Rumble(state[lr.RumbleStrong], state[lr.RumbleWeak]); err != nil {
+		r.Close()
+		rumblers[port] = nil
+		return false
+	}
+	return true
+}
+
+// rumblerFor opens the force feedback device of the gamepad plugged in port,
+// or returns the one already open. Must be called with devicesMu held.
+func rumblerFor(port uint) rumbler {
+	name := gamepadName(port)
+	if rumblers[port] != nil && rumblerName[port] == name {
+		return rumblers[port]
+	}
+	if rumblers[port] != nil {
+		rumblers[port].Close()
+		rumblers[port] = nil
+	}
+	rumblerName[port] = name
+	if name == "" {
+		return nil
+	}
+	rumblers[port] = openRumbler(name)
+	return rumblers[port]
+}
+
+// StopRumble stops all the motors and resets the LEDs and sensors. It is
+// called when the game is unloaded.
+func StopRumble() {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	for port := range rumblers {
+		if rumblers[port] != nil {
+			rumblers[port].Rumble(0, 0)
+			rumblers[port].Close()
+			rumblers[port] = nil
+		}
+		rumblerName[port] = ""
+		for effect, strength := range rumbleState[port] {
+			if strength != 0 {
+				emit(DeviceEvent{Kind: DeviceRumble, Port: uint(port), ID: uint(effect)})
+			}
+		}
+		rumbleState[port] = [2]uint16{}
+		if illuminanceOn[port] {
+			emit(DeviceEvent{Kind: DeviceSensor, Port: uint(port), ID: lr.SensorIlluminance, Value: -1})
+		}
+		illuminanceOn[port] = false
+	}
+	for led, state := range ledState {
+		if state != 0 {
+			emit(DeviceEvent{Kind: DeviceLED, ID: uint(led)})
+		}
+	}
+	ledState = map[int]int{}
+}
+
+// SetLEDState is the LED callback given to the core
+func SetLEDState(led, state int) {
+	if led < 0 {
+		return
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if ledState[led] == state {
+		return
+	}
+	ledState[led] = state
+	emit(DeviceEvent{Kind: DeviceLED, ID: uint(led), Value: float64(state)})
+}
+
+// SetSensorState is the sensor callback given to the core. Gamepads don't
+// report motion through GLFW, so only the illuminance sensor is supported. It
+// is emulated, for example for the solar sensor of Boktai cartridges.
+func SetSensorState(port uint, action uint32, rate uint) bool {
+	if port >= MaxPlayers {
+		return false
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	switch action {
+	case lr.SensorIlluminanceEnable:
+		if !illuminanceOn[port] {
+			illuminanceOn[port] = true
+			emit(DeviceEvent{Kind: DeviceSensor, Port: port, ID: lr.SensorIlluminance, Value: float64(illuminance)})
+		}
+		return true
+	case lr.SensorIlluminanceDisable:
+		if illuminanceOn[port] {
+			illuminanceOn[port] = false
+			emit(DeviceEvent{Kind: DeviceSensor, Port: port, ID: lr.SensorIlluminance, Value: -1})
+		}
+		return true
+	}
+	return false
+}
+
+// SensorInput is the sensor reading callback given to the core
+func SensorInput(port uint, id uint) float32 {
+	if port >= MaxPlayers || id != lr.SensorIlluminance {
+		return 0
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if !illuminanceOn[port] {
+		return 0
+	}
+	return illuminance
+}
+
+// SetIlluminance changes the emulated ambient light, in lux
+func SetIlluminance(lux float32) {
+	if lux < 0 {
+		lux = 0
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	illuminance = lux
+	for port, on := range illuminanceOn {
+		if on {
+			emit(DeviceEvent{Kind: DeviceSensor, Port: uint(port), ID: lr.SensorIlluminance, Value: float64(lux)})
+		}
+	}
+}
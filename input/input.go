@@ -30,6 +30,9 @@ var (
 	Pressed  States // keys just pressed during this frame
 
 	NewAnalogState AnalogStates // analog input state for the current frame
+
+	// Unplugged ports are reported as idle to the core, whatever is pressed
+	Unplugged [MaxPlayers]bool
 )
 
 var oldMouseX float64
@@ -45,8 +48,10 @@ const (
 	ActionShouldClose uint32 = lr.DeviceIDJoypadR3 + 3
 	// ActionFastForwardToggle will run the core as fast as possible
 	ActionFastForwardToggle uint32 = lr.DeviceIDJoypadR3 + 4
+	// ActionRewind steps the game backwards while held
+	ActionRewind uint32 = lr.DeviceIDJoypadR3 + 5
 	// ActionLast is used for iterating
-	ActionLast uint32 = lr.DeviceIDJoypadR3 + 5
+	ActionLast uint32 = lr.DeviceIDJoypadR3 + 6
 )
 
 // joystickCallback is triggered when a joypad is plugged.
@@ -70,6 +75,10 @@ var vid *video.Video
 // Init initializes the input package
 func Init(v *video.Video) {
 	vid = v
+	// Headless runs have no window nor joypads, inputs come from scripts
+	if v.Headless() {
+		return
+	}
 	if !glfw.UpdateGamepadMappings(mappings) {
 		log.Println("Failed to update mappings")
 	}
@@ -174,6 +183,7 @@ func getPressedReleased(new States, old States) (States, States) {
 // Poll calculates the input state. It is meant to be called for each frame.
 func Poll() {
 	NewState = States{}
+	pollGamepadNames()
 	NewState, NewAnalogState = pollJoypads(NewState, NewAnalogState)
 	NewState = pollKeyboard(NewState)
 	Pressed, Released = getPressedReleased(NewState, OldState)
@@ -185,10 +195,13 @@ func Poll() {
 // State is a callback passed to core.SetInputState
 // It returns 1 if the button corresponding to the parameters is pressed
 func State(port uint, device uint32, index uint, id uint) int16 {
-	if port >= MaxPlayers {
+	if port >= MaxPlayers || Unplugged[port] {
 		return 0
 	}
 
+	// Cores may ask for their own subclasses of the base devices
+	device &= lr.DeviceMask
+
 	if device == lr.DeviceJoypad {
 		if id >= uint(ActionLast) || index > 0 {
 			return 0
@@ -203,7 +216,7 @@ func State(port uint, device uint32, index uint, id uint) int16 {
 		return NewAnalogState[port][index][id]
 	}
 
-	if device == lr.DeviceMouse {
+	if device == lr.DeviceMouse && vid.Window != nil {
 		x, y := vid.Window.GetCursorPos()
 		if id == uint(lr.DeviceIDMouseX) {
 			d := x - oldMouseX
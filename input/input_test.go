@@ -1,7 +1,10 @@
 package input
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/libretro/ludo/libretro"
 )
 
 func Test_getPressedReleased(t *testing.T) {
@@ -23,3 +26,72 @@ func Test_getPressedReleased(t *testing.T) {
 		}
 	})
 }
+
+func TestButtonName(t *testing.T) {
+	tests := []struct {
+		name   string
+		device uint32
+		index  uint
+		id     uint
+		want   string
+	}{
+		{"Joypad buttons are named like on the RetroPad", libretro.DeviceJoypad, 0, 8, "A"},
+		{"Subclasses of the joypad have the same names", libretro.DeviceSubclass(libretro.DeviceJoypad, 1), 0, 3, "Start"},
+		{"Analog axes name their stick", libretro.DeviceAnalog, uint(libretro.DeviceIndexAnalogRight), uint(libretro.DeviceIDAnalogY), "Right Stick Y"},
+		{"Analog buttons are named like joypad buttons", libretro.DeviceAnalog, uint(libretro.DeviceIndexAnalogButton), 12, "L2"},
+		{"Unknown inputs fall back to their ID", libretro.DeviceJoypad, 0, 42, "Input 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ButtonName(tt.device, tt.index, tt.id); got != tt.want {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	t.Run("Holds the buttons of each port until its next step", func(t *testing.T) {
+		script, err := ParseScript(strings.NewReader(`# frame port buttons
+10 1 start
+12 1 -
+11 2 a,Right # both at once
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			frame  int
+			port   int
+			button uint32
+			want   int16
+		}{
+			{9, 0, libretro.DeviceIDJoypadStart, 0},
+			{10, 0, libretro.DeviceIDJoypadStart, 1},
+			{11, 0, libretro.DeviceIDJoypadStart, 1},
+			{12, 0, libretro.DeviceIDJoypadStart, 0},
+			{11, 1, libretro.DeviceIDJoypadA, 1},
+			{500, 1, libretro.DeviceIDJoypadRight, 1},
+		}
+		for _, tt := range tests {
+			got := script.State(tt.frame)[tt.port][tt.button]
+			if got != tt.want {
+				t.Errorf("frame %d: got = %v, want %v", tt.frame, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Rejects unknown buttons", func(t *testing.T) {
+		_, err := ParseScript(strings.NewReader("0 1 jump\n"))
+		if err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+	})
+
+	t.Run("Rejects ports out of range", func(t *testing.T) {
+		_, err := ParseScript(strings.NewReader("0 9 a\n"))
+		if err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+	})
+}
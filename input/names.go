@@ -0,0 +1,39 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/libretro/ludo/libretro"
+)
+
+// joypadNames are the RetroPad buttons, indexed by libretro joypad ID
+var joypadNames = []string{
+	"B", "Y", "Select", "Start", "Up", "Down", "Left", "Right",
+	"A", "X", "L", "R", "L2", "R2", "L3", "R3",
+}
+
+// ButtonName returns the name of the RetroPad button or stick axis an input
+// descriptor refers to
+func ButtonName(device uint32, index, id uint) string {
+	switch device & libretro.DeviceMask {
+	case libretro.DeviceJoypad:
+		if id < uint(len(joypadNames)) {
+			return joypadNames[id]
+		}
+	case libretro.DeviceAnalog:
+		stick := "Left Stick"
+		switch uint32(index) {
+		case libretro.DeviceIndexAnalogRight:
+			stick = "Right Stick"
+		case libretro.DeviceIndexAnalogButton:
+			if id < uint(len(joypadNames)) {
+				return joypadNames[id]
+			}
+		}
+		if uint32(id) == libretro.DeviceIDAnalogY {
+			return stick + " Y"
+		}
+		return stick + " X"
+	}
+	return fmt.Sprintf("Input %d", id)
+}
@@ -0,0 +1,163 @@
+//go:build linux
+
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Force feedback constants from linux/input.h and linux/input-event-codes.h
+const (
+	evFF     = 0x15
+	ffRumble = 0x50
+
+	eviocrmff = 0x40044581 // _IOW('E', 0x81, int)
+)
+
+// ffEffect mirrors struct ff_effect with the ff_rumble_effect member of the
+// union. The union holds a pointer in ff_periodic_effect, so its size depends
+// on the architecture.
+type ffEffect struct {
+	Type      uint16
+	ID        int16
+	Direction uint16
+	Trigger   [2]uint16
+	Replay    [2]uint16 // length, delay
+	_         uint16
+	Strong    uint16
+	Weak      uint16
+	_         [20 + unsafe.Sizeof(uintptr(0))]byte
+}
+
+// eviocsff is _IOW('E', 0x80, struct ff_effect)
+const eviocsff = 1<<30 | unsafe.Sizeof(ffEffect{})<<16 | 'E'<<8 | 0x80
+
+// inputEvent mirrors struct input_event
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// evdevDevice is an opened /dev/input/event* file
+type evdevDevice interface {
+	Write(b []byte) (int, error)
+	UploadEffect(effect *ffEffect) error
+	RemoveEffect(id int16) error
+	Close() error
+}
+
+// evdevRumbler drives a gamepad through an evdev FF_RUMBLE effect. The
+// effect is uploaded again each time the strength changes, and plays until
+// it is stopped.
+type evdevRumbler struct {
+	dev     evdevDevice
+	id      int16
+	playing bool
+}
+
+func newEvdevRumbler(dev evdevDevice) *evdevRumbler {
+	return &evdevRumbler{dev: dev, id: -1}
+}
+
+// Rumble sets the strength of the motors, zero for both stops the effect
+func (r *evdevRumbler) Rumble(strong, weak uint16) error {
+	if strong == 0 && weak == 0 {
+		if !r.playing {
+			return nil
+		}
+		r.playing = false
+		return r.play(0)
+	}
+
+	effect := ffEffect{Type: ffRumble, ID: r.id, Strong: strong, Weak: weak}
+	if err := r.dev.UploadEffect(&effect); err != nil {
+		return err
+	}
+	r.id = effect.ID
+	if r.playing {
+		return nil
+	}
+	r.playing = true
+	return r.play(1)
+}
+
+func (r *evdevRumbler) play(value int32) error {
+	ev := inputEvent{Type: evFF, Code: uint16(r.id), Value: value}
+	_, err := r.dev.Write((*[unsafe.Sizeof(inputEvent{})]byte)(unsafe.Pointer(&ev))[:])
+	return err
+}
+
+// Close removes the effect from the device and closes it
+func (r *evdevRumbler) Close() error {
+	if r.id >= 0 {
+		r.dev.RemoveEffect(r.id)
+	}
+	return r.dev.Close()
+}
+
+// evdevFile is an evdevDevice backed by a device node
+type evdevFile struct {
+	*os.File
+}
+
+// UploadEffect sends effect to the device, which assigns its ID when it is -1
+func (f evdevFile) UploadEffect(effect *ffEffect) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), eviocsff, uintptr(unsafe.Pointer(effect)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// RemoveEffect frees the effect id on the device
+func (f evdevFile) RemoveEffect(id int16) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), eviocrmff, uintptr(id))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// sysInput is where the kernel describes the input devices
+var sysInput = "/sys/class/input"
+
+// openEvdev opens the evdev node of the device called name that supports
+// force feedback. GLFW names joysticks after their evdev name.
+var openEvdev = func(name string) (evdevDevice, error) {
+	nodes, err := filepath.Glob(filepath.Join(sysInput, "event*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		n, err := os.ReadFile(filepath.Join(node, "device", "name"))
+		if err != nil || strings.TrimSpace(string(n)) != name {
+			continue
+		}
+		ff, err := os.ReadFile(filepath.Join(node, "device", "capabilities", "ff"))
+		if err != nil || strings.TrimSpace(string(ff)) == "0" {
+			continue
+		}
+		f, err := os.OpenFile(filepath.Join("/dev/input", filepath.Base(node)), os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		return evdevFile{f}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// openRumbler returns a rumbler for the gamepad called name, or nil if it
+// has no force feedback or can't be opened
+func openRumbler(name string) rumbler {
+	dev, err := openEvdev(name)
+	if err != nil {
+		return nil
+	}
+	return newEvdevRumbler(dev)
+}
@@ -0,0 +1,199 @@
+//go:build linux
+
+package input
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/libretro/ludo/libretro"
+)
+
+// fakeEvdev records what is sent to a force feedback device
+type fakeEvdev struct {
+	effects map[int16]ffEffect
+	events  []inputEvent
+	nextID  int16
+	closed  bool
+}
+
+func newFakeEvdev() *fakeEvdev {
+	return &fakeEvdev{effects: map[int16]ffEffect{}}
+}
+
+func (d *fakeEvdev) Write(b []byte) (int, error) {
+	d.events = append(d.events, *(*inputEvent)(unsafe.Pointer(&b[0])))
+	return len(b), nil
+}
+
+func (d *fakeEvdev) UploadEffect(effect *ffEffect) error {
+	if effect.ID == -1 {
+		effect.ID = d.nextID
+		d.nextID++
+	}
+	d.effects[effect.ID] = *effect
+	return nil
+}
+
+func (d *fakeEvdev) RemoveEffect(id int16) error {
+	delete(d.effects, id)
+	return nil
+}
+
+func (d *fakeEvdev) Close() error {
+	d.closed = true
+	return nil
+}
+
+// withFakeGamepad plugs a fake force feedback gamepad in the first port
+func withFakeGamepad(t *testing.T) *fakeEvdev {
+	dev := newFakeEvdev()
+	oldName, oldOpen := gamepadName, openEvdev
+	gamepadName = func(port uint) string {
+		if port == 0 {
+			return "Fake Pad"
+		}
+		return ""
+	}
+	openEvdev = func(name string) (evdevDevice, error) {
+		return dev, nil
+	}
+	t.Cleanup(func() {
+		StopRumble()
+		gamepadName, openEvdev = oldName, oldOpen
+	})
+	return dev
+}
+
+func Test_ffEffect(t *testing.T) {
+	t.Run("Has the size of struct ff_effect", func(t *testing.T) {
+		got := unsafe.Sizeof(ffEffect{})
+		want := 16 + 24 + unsafe.Sizeof(uintptr(0))
+		if got != want {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestSetRumbleState(t *testing.T) {
+	t.Run("Uploads a rumble effect and plays it", func(t *testing.T) {
+		dev := withFakeGamepad(t)
+		if !SetRumbleState(0, libretro.RumbleStrong, 0x8000) {
+			t.Fatal("rumble refused")
+		}
+		got := dev.effects[0]
+		if got.Type != ffRumble || got.Strong != 0x8000 || got.Weak != 0 {
+			t.Errorf("got = %+v, want a strong rumble", got)
+		}
+		wantEvents := []inputEvent{{Type: evFF, Code: 0, Value: 1}}
+		if !reflect.DeepEqual(dev.events, wantEvents) {
+			t.Errorf("got = %v, want %v", dev.events, wantEvents)
+		}
+	})
+
+	t.Run("Updates the playing effect when the strength changes", func(t *testing.T) {
+		dev := withFakeGamepad(t)
+		SetRumbleState(0, libretro.RumbleStrong, 0x8000)
+		SetRumbleState(0, libretro.RumbleWeak, 0x1000)
+		if len(dev.effects) != 1 {
+			t.Fatalf("got = %v effects, want 1", len(dev.effects))
+		}
+		got := dev.effects[0]
+		if got.Strong != 0x8000 || got.Weak != 0x1000 {
+			t.Errorf("got = %+v, want both motors", got)
+		}
+		if len(dev.events) != 1 {
+			t.Errorf("got = %v, want a single play event", dev.events)
+		}
+	})
+
+	t.Run("Stops the effect when both motors are off", func(t *testing.T) {
+		dev := withFakeGamepad(t)
+		SetRumbleState(0, libretro.RumbleWeak, 0xffff)
+		SetRumbleState(0, libretro.RumbleWeak, 0)
+		want := inputEvent{Type: evFF, Code: 0, Value: 0}
+		if got := dev.events[len(dev.events)-1]; got != want {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Ports without a gamepad report the motors as missing", func(t *testing.T) {
+		withFakeGamepad(t)
+		if SetRumbleState(1, libretro.RumbleStrong, 0xffff) {
+			t.Errorf("got = true, want false")
+		}
+	})
+
+	t.Run("Notifies the listener once per change", func(t *testing.T) {
+		withFakeGamepad(t)
+		var got []DeviceEvent
+		SetDeviceListener(func(e DeviceEvent) { got = append(got, e) })
+		defer SetDeviceListener(nil)
+		SetRumbleState(0, libretro.RumbleStrong, 0xffff)
+		SetRumbleState(0, libretro.RumbleStrong, 0xffff)
+		want := []DeviceEvent{{Kind: DeviceRumble, Port: 0, ID: uint(libretro.RumbleStrong), Value: 1}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestStopRumble(t *testing.T) {
+	t.Run("Stops the motors and closes the device", func(t *testing.T) {
+		dev := withFakeGamepad(t)
+		SetRumbleState(0, libretro.RumbleStrong, 0xffff)
+		StopRumble()
+		if !dev.closed {
+			t.Errorf("got = %v, want %v", dev.closed, true)
+		}
+		if len(dev.effects) != 0 {
+			t.Errorf("got = %v, want no effects left", dev.effects)
+		}
+		if rumbleState[0] != [2]uint16{} {
+			t.Errorf("got = %v, want %v", rumbleState[0], [2]uint16{})
+		}
+	})
+}
+
+func TestSensorInput(t *testing.T) {
+	t.Run("Reads the emulated illuminance once enabled", func(t *testing.T) {
+		withFakeGamepad(t)
+		if got := SensorInput(0, libretro.SensorIlluminance); got != 0 {
+			t.Errorf("got = %v, want %v", got, 0)
+		}
+		if !SetSensorState(0, libretro.SensorIlluminanceEnable, 0) {
+			t.Fatal("illuminance refused")
+		}
+		SetIlluminance(1200)
+		defer SetIlluminance(DefaultIlluminance)
+		if got := SensorInput(0, libretro.SensorIlluminance); got != 1200 {
+			t.Errorf("got = %v, want %v", got, 1200)
+		}
+	})
+
+	t.Run("Motion sensors are not supported", func(t *testing.T) {
+		if SetSensorState(0, libretro.SensorAccelerometerEnable, 60) {
+			t.Errorf("got = true, want false")
+		}
+	})
+}
+
+func TestSetLEDState(t *testing.T) {
+	t.Run("Notifies the listener of changes", func(t *testing.T) {
+		withFakeGamepad(t)
+		var got []DeviceEvent
+		SetDeviceListener(func(e DeviceEvent) { got = append(got, e) })
+		defer SetDeviceListener(nil)
+		SetLEDState(0, 1)
+		SetLEDState(0, 1)
+		SetLEDState(0, 0)
+		want := []DeviceEvent{
+			{Kind: DeviceLED, ID: 0, Value: 1},
+			{Kind: DeviceLED, ID: 0, Value: 0},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package input
+
+// openRumbler returns nil, force feedback is only implemented with evdev
+func openRumbler(name string) rumbler {
+	return nil
+}
@@ -0,0 +1,104 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScriptStep holds the buttons of a port from a frame on
+type ScriptStep struct {
+	Frame   int
+	Port    uint
+	Buttons []uint32
+}
+
+// Script plays inputs without players, frame by frame. It is used by the
+// headless runner to drive games in automated tests.
+type Script []ScriptStep
+
+// ParseScript reads a script made of one step per line:
+//
+//	# frame port buttons
+//	60      1    start
+//	62      1    -
+//	120     1    a,right
+//
+// Ports start at 1 and buttons are RetroPad button names. A step holds the
+// buttons until the next step of the same port, "-" releases them all.
+func ParseScript(r io.Reader) (Script, error) {
+	script := Script{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected frame, port and buttons", n)
+		}
+
+		frame, err := strconv.Atoi(fields[0])
+		if err != nil || frame < 0 {
+			return nil, fmt.Errorf("line %d: invalid frame %q", n, fields[0])
+		}
+		port, err := strconv.Atoi(fields[1])
+		if err != nil || port < 1 || port > MaxPlayers {
+			return nil, fmt.Errorf("line %d: invalid port %q", n, fields[1])
+		}
+
+		step := ScriptStep{Frame: frame, Port: uint(port - 1)}
+		if fields[2] != "-" {
+			for _, name := range strings.Split(fields[2], ",") {
+				id, ok := buttonID(name)
+				if !ok {
+					return nil, fmt.Errorf("line %d: unknown button %q", n, name)
+				}
+				step.Buttons = append(step.Buttons, id)
+			}
+		}
+		script = append(script, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(script, func(i, j int) bool { return script[i].Frame < script[j].Frame })
+	return script, nil
+}
+
+// buttonID returns the joypad ID of a RetroPad button name
+func buttonID(name string) (uint32, bool) {
+	for id, n := range joypadNames {
+		if strings.EqualFold(n, name) {
+			return uint32(id), true
+		}
+	}
+	return 0, false
+}
+
+// State returns the joypad state of all the ports at the given frame
+func (s Script) State(frame int) States {
+	var held [MaxPlayers][]uint32
+	for _, step := range s {
+		if step.Frame > frame {
+			break
+		}
+		held[step.Port] = step.Buttons
+	}
+
+	var st States
+	for port, buttons := range held {
+		for _, id := range buttons {
+			st[port][id] = 1
+		}
+	}
+	return st
+}
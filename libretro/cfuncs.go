@@ -2,6 +2,7 @@ package libretro
 
 /*
 #include "libretro.h"
+#include "instances.h"
 #include <stdbool.h>
 #include <stdarg.h>
 #include <stdio.h>
@@ -48,7 +49,8 @@ struct thread_cmd_t s_job;
 static pthread_t s_thread;
 static SEM_T s_sem_do;
 static SEM_T s_sem_done;
-static bool s_use_thread = false;
+static bool s_thread_started = false;
+static pthread_mutex_t s_job_lock = PTHREAD_MUTEX_INITIALIZER;
 
 void* emu_thread_loop(void *a0) {
 	print_sema("begin thread\n");
@@ -80,6 +82,8 @@ void* emu_thread_loop(void *a0) {
 	}
 }
 
+// thread_sync must be called with s_job_lock held, the emulation thread is
+// shared by all the loaded cores
 void thread_sync() {
 	// Fire the job
 	print_sema("signal do\n");
@@ -92,18 +96,24 @@ void thread_sync() {
 	print_sema("done\n");
 }
 
-void run_wrapper(void *f) {
-	if (s_use_thread) {
+// run_wrapper runs f on the emulation thread when use_thread is set, the
+// choice is made by each core instance
+void run_wrapper(bool use_thread, void *f) {
+	if (use_thread) {
+		pthread_mutex_lock(&s_job_lock);
 		s_job.cmd = CMD_F;
 		s_job.f = f;
 		thread_sync();
+		pthread_mutex_unlock(&s_job_lock);
 	} else {
 		((void (*)(void))f)();
 	}
 }
 
 void cothread_init() {
-	s_use_thread = true;
+	if (s_thread_started)
+		return;
+	s_thread_started = true;
 
 	SEM_INIT(s_sem_do);
 	SEM_INIT(s_sem_done);
@@ -115,12 +125,12 @@ void cothread_init() {
 	SEM_WAIT(s_sem_done);
 }
 
-void bridge_retro_init(void *f) {
-	run_wrapper(f);
+void bridge_retro_init(bool use_thread, void *f) {
+	run_wrapper(use_thread, f);
 }
 
-void bridge_retro_deinit(void *f) {
-	run_wrapper(f);
+void bridge_retro_deinit(bool use_thread, void *f) {
+	run_wrapper(use_thread, f);
 }
 
 unsigned bridge_retro_api_version(void *f) {
@@ -179,13 +189,18 @@ bool bridge_retro_load_game(void *f, struct retro_game_info *gi) {
   return ((bool (*)(struct retro_game_info *))f)(gi);
 }
 
+bool bridge_retro_load_game_special(void *f, unsigned game_type, struct retro_game_info *gi, size_t num_info) {
+  return ((bool (*)(unsigned, struct retro_game_info *, size_t))f)(game_type, gi, num_info);
+}
+
 size_t bridge_retro_serialize_size(void *f) {
   return ((size_t (*)(void))f)();
 }
 
-bool bridge_retro_serialize(void *f, void *data, size_t size) {
-	if (s_use_thread) {
+bool bridge_retro_serialize(bool use_thread, void *f, void *data, size_t size) {
+	if (use_thread) {
 		bool res;
+		pthread_mutex_lock(&s_job_lock);
 		s_job.cmd = CMD_SERIALIZE;
 		s_job.f = f;
 		s_job.arg1 = data;
@@ -193,16 +208,18 @@ bool bridge_retro_serialize(void *f, void *data, size_t size) {
 		s_job.res  = &res;
 
 		thread_sync();
+		pthread_mutex_unlock(&s_job_lock);
 
-		return s_job.res;
+		return res;
 	} else {
 		return ((bool (*)(void*, size_t))f)(data, size);
 	}
 }
 
-bool bridge_retro_unserialize(void *f, void *data, size_t size) {
-	if (s_use_thread) {
+bool bridge_retro_unserialize(bool use_thread, void *f, void *data, size_t size) {
+	if (use_thread) {
 		bool res;
+		pthread_mutex_lock(&s_job_lock);
 		s_job.cmd = CMD_SERIALIZE; // Same command format for both serialize & unserialize
 		s_job.f = f;
 		s_job.arg1 = data;
@@ -210,23 +227,24 @@ bool bridge_retro_unserialize(void *f, void *data, size_t size) {
 		s_job.res  = &res;
 
 		thread_sync();
+		pthread_mutex_unlock(&s_job_lock);
 
-		return s_job.res;
+		return res;
 	} else {
 		return ((bool (*)(void*, size_t))f)(data, size);
 	}
 }
 
-void bridge_retro_unload_game(void *f) {
-	run_wrapper(f);
+void bridge_retro_unload_game(bool use_thread, void *f) {
+	run_wrapper(use_thread, f);
 }
 
-void bridge_retro_run(void *f) {
-	run_wrapper(f);
+void bridge_retro_run(bool use_thread, void *f) {
+	run_wrapper(use_thread, f);
 }
 
-void bridge_retro_reset(void *f) {
-	run_wrapper(f);
+void bridge_retro_reset(bool use_thread, void *f) {
+	run_wrapper(use_thread, f);
 }
 
 size_t bridge_retro_get_memory_size(void *f, unsigned id) {
@@ -237,6 +255,14 @@ void* bridge_retro_get_memory_data(void *f, unsigned id) {
 	return ((void* (*)(unsigned))f)(id);
 }
 
+void bridge_retro_cheat_reset(bool use_thread, void *f) {
+	run_wrapper(use_thread, f);
+}
+
+void bridge_retro_cheat_set(void *f, unsigned index, bool enabled, const char *code) {
+	((void (*)(unsigned, bool, const char *))f)(index, enabled, code);
+}
+
 void bridge_retro_set_eject_state(retro_set_eject_state_t f, bool state) {
 	f(state);
 }
@@ -257,50 +283,261 @@ unsigned bridge_retro_get_num_images(retro_get_num_images_t f) {
 	return ((unsigned (*)())f)();
 }
 
-bool coreEnvironment_cgo(unsigned cmd, void *data) {
-	bool coreEnvironment(unsigned, void*);
-	return coreEnvironment(cmd, data);
+bool bridge_retro_replace_image_index(retro_replace_image_index_t f, unsigned index, const struct retro_game_info *info) {
+	return f(index, info);
+}
+
+bool bridge_retro_add_image_index(retro_add_image_index_t f) {
+	return f();
+}
+
+bool bridge_retro_set_initial_image(retro_set_initial_image_t f, unsigned index, const char *path) {
+	return f(index, path);
+}
+
+bool bridge_retro_get_image_path(retro_get_image_path_t f, unsigned index, char *path, size_t len) {
+	return f(index, path, len);
+}
+
+bool bridge_retro_get_image_label(retro_get_image_label_t f, unsigned index, char *label, size_t len) {
+	return f(index, label, len);
+}
+
+void bridge_retro_hw_context_reset(retro_hw_context_reset_t f) {
+	if (f)
+		f();
+}
+
+bool bridge_is_hw_frame(const void *data) {
+	return data == RETRO_HW_FRAME_BUFFER_VALID;
+}
+
+bool bridge_retro_core_options_update_display(retro_core_options_update_display_callback_t f) {
+	return f();
+}
+
+bool coreEnvironment(int, unsigned, void*);
+void coreVideoRefresh(int, void*, unsigned, unsigned, size_t);
+void coreInputPoll(int);
+int16_t coreInputState(int, unsigned, unsigned, unsigned, unsigned);
+void coreAudioSample(int, int16_t, int16_t);
+size_t coreAudioSampleBatch(int, const int16_t*, size_t);
+void coreLog(int, enum retro_log_level, const char*);
+uint64_t coreGetTimeUsec(int);
+uintptr_t coreGetCurrentFramebuffer(int);
+void* coreGetProcAddress(int, char*);
+
+// CORE_CALLBACKS defines the callbacks given to the core loaded in slot n,
+// they forward the calls to Go along with the slot number.
+#define CORE_CALLBACKS(n) \
+static bool coreEnvironment_##n(unsigned cmd, void *data) { \
+	return coreEnvironment(n, cmd, data); \
+} \
+static void coreVideoRefresh_##n(const void *data, unsigned width, unsigned height, size_t pitch) { \
+	coreVideoRefresh(n, (void*)data, width, height, pitch); \
+} \
+static void coreInputPoll_##n(void) { \
+	coreInputPoll(n); \
+} \
+static int16_t coreInputState_##n(unsigned port, unsigned device, unsigned index, unsigned id) { \
+	return coreInputState(n, port, device, index, id); \
+} \
+static void coreAudioSample_##n(int16_t left, int16_t right) { \
+	coreAudioSample(n, left, right); \
+} \
+static size_t coreAudioSampleBatch_##n(const int16_t *data, size_t frames) { \
+	return coreAudioSampleBatch(n, data, frames); \
+} \
+static void coreLog_##n(enum retro_log_level level, const char *fmt, ...) { \
+	char msg[4096] = {0}; \
+	va_list va; \
+	va_start(va, fmt); \
+	vsnprintf(msg, sizeof(msg), fmt, va); \
+	va_end(va); \
+	coreLog(n, level, msg); \
+} \
+static retro_time_t coreGetTimeUsec_##n(void) { \
+	return coreGetTimeUsec(n); \
+} \
+static uintptr_t coreGetCurrentFramebuffer_##n(void) { \
+	return coreGetCurrentFramebuffer(n); \
+} \
+static retro_proc_address_t coreGetProcAddress_##n(const char *sym) { \
+	return (retro_proc_address_t)coreGetProcAddress(n, (char*)sym); \
+}
+
+#define CORE_CALLBACKS_ENTRY(n) { \
+	coreEnvironment_##n, \
+	coreVideoRefresh_##n, \
+	coreInputPoll_##n, \
+	coreInputState_##n, \
+	coreAudioSample_##n, \
+	coreAudioSampleBatch_##n, \
+	coreLog_##n, \
+	coreGetTimeUsec_##n, \
+	coreGetCurrentFramebuffer_##n, \
+	coreGetProcAddress_##n, \
+}
+
+CORE_CALLBACKS(0)
+CORE_CALLBACKS(1)
+CORE_CALLBACKS(2)
+CORE_CALLBACKS(3)
+
+static const struct core_callbacks callbacks[CORE_MAX_INSTANCES] = {
+	CORE_CALLBACKS_ENTRY(0),
+	CORE_CALLBACKS_ENTRY(1),
+	CORE_CALLBACKS_ENTRY(2),
+	CORE_CALLBACKS_ENTRY(3),
+};
+
+const struct core_callbacks *core_callbacks(int slot) {
+	return &callbacks[slot];
+}
+
+bool coreSetRumbleState_cgo(unsigned port, enum retro_rumble_effect effect, uint16_t strength) {
+	bool coreSetRumbleState(unsigned, enum retro_rumble_effect, uint16_t);
+	return coreSetRumbleState(port, effect, strength);
+}
+
+void coreSetLEDState_cgo(int led, int state) {
+	void coreSetLEDState(int, int);
+	coreSetLEDState(led, state);
+}
+
+bool coreSetSensorState_cgo(unsigned port, enum retro_sensor_action action, unsigned rate) {
+	bool coreSetSensorState(unsigned, enum retro_sensor_action, unsigned);
+	return coreSetSensorState(port, action, rate);
 }
 
-void coreVideoRefresh_cgo(void *data, unsigned width, unsigned height, size_t pitch) {
-	void coreVideoRefresh(void*, unsigned, unsigned, size_t);
-	return coreVideoRefresh(data, width, height, pitch);
+float coreGetSensorInput_cgo(unsigned port, unsigned id) {
+	float coreGetSensorInput(unsigned, unsigned);
+	return coreGetSensorInput(port, id);
 }
 
-void coreInputPoll_cgo() {
-	void coreInputPoll();
-	return coreInputPoll();
+const char *coreVFSGetPath_cgo(struct retro_vfs_file_handle *stream) {
+	const char *coreVFSGetPath(uintptr_t);
+	return coreVFSGetPath((uintptr_t)stream);
 }
 
-int16_t coreInputState_cgo(unsigned port, unsigned device, unsigned index, unsigned id) {
-	int16_t coreInputState(unsigned, unsigned, unsigned, unsigned);
-	return coreInputState(port, device, index, id);
+struct retro_vfs_file_handle *coreVFSOpen_cgo(const char *path, unsigned mode, unsigned hints) {
+	uintptr_t coreVFSOpen(char*, unsigned, unsigned);
+	return (struct retro_vfs_file_handle*)coreVFSOpen((char*)path, mode, hints);
 }
 
-void coreAudioSample_cgo(int16_t left, int16_t right) {
-	void coreAudioSample(int16_t, int16_t);
-	coreAudioSample(left, right);
+int coreVFSClose_cgo(struct retro_vfs_file_handle *stream) {
+	int coreVFSClose(uintptr_t);
+	return coreVFSClose((uintptr_t)stream);
 }
 
-size_t coreAudioSampleBatch_cgo(const int16_t *data, size_t frames) {
-	size_t coreAudioSampleBatch(const int16_t*, size_t);
-	return coreAudioSampleBatch(data, frames);
+int64_t coreVFSSize_cgo(struct retro_vfs_file_handle *stream) {
+	int64_t coreVFSSize(uintptr_t);
+	return coreVFSSize((uintptr_t)stream);
 }
 
-void coreLog_cgo(enum retro_log_level level, const char *fmt, ...) {
-	char msg[4096] = {0};
-	va_list va;
-	va_start(va, fmt);
-	vsnprintf(msg, sizeof(msg), fmt, va);
-	va_end(va);
+int64_t coreVFSTruncate_cgo(struct retro_vfs_file_handle *stream, int64_t length) {
+	int64_t coreVFSTruncate(uintptr_t, int64_t);
+	return coreVFSTruncate((uintptr_t)stream, length);
+}
+
+int64_t coreVFSTell_cgo(struct retro_vfs_file_handle *stream) {
+	int64_t coreVFSTell(uintptr_t);
+	return coreVFSTell((uintptr_t)stream);
+}
 
-	void coreLog(enum retro_log_level level, const char *msg);
-	coreLog(level, msg);
+int64_t coreVFSSeek_cgo(struct retro_vfs_file_handle *stream, int64_t offset, int seek_position) {
+	int64_t coreVFSSeek(uintptr_t, int64_t, int);
+	return coreVFSSeek((uintptr_t)stream, offset, seek_position);
 }
 
-int64_t coreGetTimeUsec_cgo() {
-	uint64_t coreGetTimeUsec();
-	return coreGetTimeUsec();
+int64_t coreVFSRead_cgo(struct retro_vfs_file_handle *stream, void *s, uint64_t len) {
+	int64_t coreVFSRead(uintptr_t, void*, uint64_t);
+	return coreVFSRead((uintptr_t)stream, s, len);
+}
+
+int64_t coreVFSWrite_cgo(struct retro_vfs_file_handle *stream, const void *s, uint64_t len) {
+	int64_t coreVFSWrite(uintptr_t, void*, uint64_t);
+	return coreVFSWrite((uintptr_t)stream, (void*)s, len);
+}
+
+int coreVFSFlush_cgo(struct retro_vfs_file_handle *stream) {
+	int coreVFSFlush(uintptr_t);
+	return coreVFSFlush((uintptr_t)stream);
+}
+
+int coreVFSRemove_cgo(const char *path) {
+	int coreVFSRemove(char*);
+	return coreVFSRemove((char*)path);
+}
+
+int coreVFSRename_cgo(const char *old_path, const char *new_path) {
+	int coreVFSRename(char*, char*);
+	return coreVFSRename((char*)old_path, (char*)new_path);
+}
+
+int coreVFSStat_cgo(const char *path, int32_t *size) {
+	int coreVFSStat(char*, int32_t*);
+	return coreVFSStat((char*)path, size);
+}
+
+int coreVFSMkdir_cgo(const char *dir) {
+	int coreVFSMkdir(char*);
+	return coreVFSMkdir((char*)dir);
+}
+
+struct retro_vfs_dir_handle *coreVFSOpenDir_cgo(const char *dir, bool include_hidden) {
+	uintptr_t coreVFSOpenDir(char*, bool);
+	return (struct retro_vfs_dir_handle*)coreVFSOpenDir((char*)dir, include_hidden);
+}
+
+bool coreVFSReadDir_cgo(struct retro_vfs_dir_handle *dirstream) {
+	bool coreVFSReadDir(uintptr_t);
+	return coreVFSReadDir((uintptr_t)dirstream);
+}
+
+const char *coreVFSDirentGetName_cgo(struct retro_vfs_dir_handle *dirstream) {
+	const char *coreVFSDirentGetName(uintptr_t);
+	return coreVFSDirentGetName((uintptr_t)dirstream);
+}
+
+bool coreVFSDirentIsDir_cgo(struct retro_vfs_dir_handle *dirstream) {
+	bool coreVFSDirentIsDir(uintptr_t);
+	return coreVFSDirentIsDir((uintptr_t)dirstream);
+}
+
+int coreVFSCloseDir_cgo(struct retro_vfs_dir_handle *dirstream) {
+	int coreVFSCloseDir(uintptr_t);
+	return coreVFSCloseDir((uintptr_t)dirstream);
+}
+
+static struct retro_vfs_interface vfs_interface = {
+	coreVFSGetPath_cgo,
+	coreVFSOpen_cgo,
+	coreVFSClose_cgo,
+	coreVFSSize_cgo,
+	coreVFSTell_cgo,
+	coreVFSSeek_cgo,
+	coreVFSRead_cgo,
+	coreVFSWrite_cgo,
+	coreVFSFlush_cgo,
+	coreVFSRemove_cgo,
+	coreVFSRename_cgo,
+	coreVFSTruncate_cgo,
+	coreVFSStat_cgo,
+	coreVFSMkdir_cgo,
+	coreVFSOpenDir_cgo,
+	coreVFSReadDir_cgo,
+	coreVFSDirentGetName_cgo,
+	coreVFSDirentIsDir_cgo,
+	coreVFSCloseDir_cgo,
+};
+
+bool bridge_set_vfs_interface(struct retro_vfs_interface_info *info) {
+	if (info->required_interface_version > 3)
+		return false;
+	info->required_interface_version = 3;
+	info->iface = &vfs_interface;
+	return true;
 }
 
 */
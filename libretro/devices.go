@@ -0,0 +1,116 @@
+package libretro
+
+/*
+#include "libretro.h"
+
+bool coreSetRumbleState_cgo(unsigned port, enum retro_rumble_effect effect, uint16_t strength);
+void coreSetLEDState_cgo(int led, int state);
+bool coreSetSensorState_cgo(unsigned port, enum retro_sensor_action action, unsigned rate);
+float coreGetSensorInput_cgo(unsigned port, unsigned id);
+*/
+import "C"
+import "unsafe"
+
+// Rumble motors
+const (
+	RumbleStrong = uint32(C.RETRO_RUMBLE_STRONG)
+	RumbleWeak   = uint32(C.RETRO_RUMBLE_WEAK)
+)
+
+// Sensor actions
+const (
+	SensorAccelerometerEnable  = uint32(C.RETRO_SENSOR_ACCELEROMETER_ENABLE)
+	SensorAccelerometerDisable = uint32(C.RETRO_SENSOR_ACCELEROMETER_DISABLE)
+	SensorGyroscopeEnable      = uint32(C.RETRO_SENSOR_GYROSCOPE_ENABLE)
+	SensorGyroscopeDisable     = uint32(C.RETRO_SENSOR_GYROSCOPE_DISABLE)
+	SensorIlluminanceEnable    = uint32(C.RETRO_SENSOR_ILLUMINANCE_ENABLE)
+	SensorIlluminanceDisable   = uint32(C.RETRO_SENSOR_ILLUMINANCE_DISABLE)
+)
+
+// Sensor IDs
+const (
+	SensorAccelerometerX = uint(C.RETRO_SENSOR_ACCELEROMETER_X)
+	SensorAccelerometerY = uint(C.RETRO_SENSOR_ACCELEROMETER_Y)
+	SensorAccelerometerZ = uint(C.RETRO_SENSOR_ACCELEROMETER_Z)
+	SensorGyroscopeX     = uint(C.RETRO_SENSOR_GYROSCOPE_X)
+	SensorGyroscopeY     = uint(C.RETRO_SENSOR_GYROSCOPE_Y)
+	SensorGyroscopeZ     = uint(C.RETRO_SENSOR_GYROSCOPE_Z)
+	SensorIlluminance    = uint(C.RETRO_SENSOR_ILLUMINANCE)
+)
+
+type (
+	rumbleFunc      func(port uint, effect uint32, strength uint16) bool
+	ledFunc         func(led, state int)
+	sensorStateFunc func(port uint, action uint32, rate uint) bool
+	sensorInputFunc func(port uint, id uint) float32
+)
+
+var (
+	setRumbleState rumbleFunc
+	setLEDState    ledFunc
+	setSensorState sensorStateFunc
+	getSensorInput sensorInputFunc
+)
+
+// BindRumbleInterface binds f to the rumble interface
+func (core *Core) BindRumbleInterface(data unsafe.Pointer, f rumbleFunc) {
+	setRumbleState = f
+	ri := (*C.struct_retro_rumble_interface)(data)
+	ri.set_rumble_state = (C.retro_set_rumble_state_t)(C.coreSetRumbleState_cgo)
+}
+
+// BindLEDInterface binds f to the LED interface
+func (core *Core) BindLEDInterface(data unsafe.Pointer, f ledFunc) {
+	setLEDState = f
+	li := (*C.struct_retro_led_interface)(data)
+	li.set_led_state = (C.retro_set_led_state_t)(C.coreSetLEDState_cgo)
+}
+
+// BindSensorInterface binds set and get to the sensor interface
+func (core *Core) BindSensorInterface(data unsafe.Pointer, set sensorStateFunc, get sensorInputFunc) {
+	setSensorState = set
+	getSensorInput = get
+	si := (*C.struct_retro_sensor_interface)(data)
+	si.set_sensor_state = (C.retro_set_sensor_state_t)(C.coreSetSensorState_cgo)
+	si.get_sensor_input = (C.retro_sensor_get_input_t)(C.coreGetSensorInput_cgo)
+}
+
+// unbindDevices forgets the device callbacks when the core is unloaded
+func unbindDevices() {
+	setRumbleState = nil
+	setLEDState = nil
+	setSensorState = nil
+	getSensorInput = nil
+}
+
+//export coreSetRumbleState
+func coreSetRumbleState(port C.unsigned, effect C.enum_retro_rumble_effect, strength C.uint16_t) C.bool {
+	if setRumbleState == nil {
+		return false
+	}
+	return C.bool(setRumbleState(uint(port), uint32(effect), uint16(strength)))
+}
+
+//export coreSetLEDState
+func coreSetLEDState(led C.int, state C.int) {
+	if setLEDState == nil {
+		return
+	}
+	setLEDState(int(led), int(state))
+}
+
+//export coreSetSensorState
+func coreSetSensorState(port C.unsigned, action C.enum_retro_sensor_action, rate C.unsigned) C.bool {
+	if setSensorState == nil {
+		return false
+	}
+	return C.bool(setSensorState(uint(port), uint32(action), uint(rate)))
+}
+
+//export coreGetSensorInput
+func coreGetSensorInput(port C.unsigned, id C.unsigned) C.float {
+	if getSensorInput == nil {
+		return 0
+	}
+	return C.float(getSensorInput(uint(port), uint(id)))
+}
@@ -0,0 +1,101 @@
+package libretro
+
+/*
+#include "instances.h"
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// MaxInstances is the number of cores that can be loaded at the same time
+const MaxInstances = int(C.CORE_MAX_INSTANCES)
+
+// ErrTooManyInstances is returned by Load when all the slots are taken
+var ErrTooManyInstances = errors.New("too many cores loaded")
+
+// instances maps the slots of the C callbacks to the loaded cores. Callbacks
+// are called from the emulation thread, so slots are swapped atomically.
+var instances [MaxInstances]atomic.Pointer[Core]
+
+// register reserves a slot for the core
+func register(core *Core) error {
+	for i := range instances {
+		if instances[i].CompareAndSwap(nil, core) {
+			core.slot = i
+			return nil
+		}
+	}
+	return ErrTooManyInstances
+}
+
+// unregister frees the slot of the core
+func unregister(core *Core) {
+	instances[core.slot].CompareAndSwap(core, nil)
+}
+
+// instance returns the core loaded in a slot
+func instance(slot C.int) *Core {
+	if slot < 0 || int(slot) >= MaxInstances {
+		return nil
+	}
+	return instances[slot].Load()
+}
+
+// Instances returns the number of cores currently loaded
+func Instances() int {
+	n := 0
+	for i := range instances {
+		if instances[i].Load() != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// callbacks returns the C callbacks bound to the slot of the core
+func (core *Core) callbacks() *C.struct_core_callbacks {
+	return C.core_callbacks(C.int(core.slot))
+}
+
+// loadedElsewhere tells if another instance already opened the same library.
+// The dynamic loader would hand out the same handle, and the two instances
+// would share the globals of the core.
+func loadedElsewhere(core *Core) bool {
+	for i := range instances {
+		other := instances[i].Load()
+		if other != nil && other != core && other.path == core.path {
+			return true
+		}
+	}
+	return false
+}
+
+// copyLibrary copies a core library to a temporary file, so it can be opened
+// a second time with its own globals
+func copyLibrary(sofile string) (string, error) {
+	src, err := os.Open(sofile)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "ludo-*-"+filepath.Base(sofile))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
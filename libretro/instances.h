@@ -0,0 +1,25 @@
+#ifndef LUDO_INSTANCES_H
+#define LUDO_INSTANCES_H
+
+#include "libretro.h"
+
+// Number of cores that can be loaded at the same time. Each slot has its own
+// set of C callbacks so the Go side knows which instance a call comes from.
+#define CORE_MAX_INSTANCES 4
+
+struct core_callbacks {
+	retro_environment_t environment;
+	retro_video_refresh_t video_refresh;
+	retro_input_poll_t input_poll;
+	retro_input_state_t input_state;
+	retro_audio_sample_t audio_sample;
+	retro_audio_sample_batch_t audio_sample_batch;
+	retro_log_printf_t log;
+	retro_perf_get_time_usec_t get_time_usec;
+	retro_hw_get_current_framebuffer_t get_current_framebuffer;
+	retro_hw_get_proc_address_t get_proc_address;
+};
+
+const struct core_callbacks *core_callbacks(int slot);
+
+#endif
@@ -0,0 +1,105 @@
+package libretro
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/libretro/ludo/utils"
+)
+
+func loadVecx(t *testing.T, frames *int) *Core {
+	t.Helper()
+	core, err := Load("../core/testdata/vecx_libretro" + utils.CoreExt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	core.SetEnvironment(func(cmd uint32, data unsafe.Pointer) bool { return false })
+	core.Init()
+	core.SetVideoRefresh(func(data unsafe.Pointer, width, height, pitch int32) { *frames++ })
+	core.SetAudioSample(func(left, right int16) {})
+	core.SetAudioSampleBatch(func(buf []byte, size int32) int32 { return size })
+	core.SetInputPoll(func() {})
+	core.SetInputState(func(port uint, device uint32, index, id uint) int16 { return 0 })
+
+	rom, err := os.ReadFile("../core/testdata/Polar Rescue (USA).vec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi := GameInfo{Path: "Polar Rescue (USA).vec", Size: int64(len(rom))}
+	gi.SetData(rom)
+	if !core.LoadGame(gi) {
+		t.Fatal("retro_load_game failed")
+	}
+	return core
+}
+
+func TestLoad(t *testing.T) {
+	var framesA, framesB int
+	a := loadVecx(t, &framesA)
+	b := loadVecx(t, &framesB)
+
+	t.Run("Gives each instance its own slot", func(t *testing.T) {
+		if a.slot == b.slot {
+			t.Errorf("got = %v, want %v", b.slot, "a different slot")
+		}
+		if got := Instances(); got != 2 {
+			t.Errorf("got = %v, want %v", got, 2)
+		}
+	})
+
+	t.Run("Opens a private copy of a library already loaded", func(t *testing.T) {
+		if a.copy != "" {
+			t.Errorf("got = %v, want %v", a.copy, "")
+		}
+		if b.copy == "" {
+			t.Errorf("got = %v, want a copy", b.copy)
+		}
+	})
+
+	t.Run("Routes the callbacks to the right instance", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			a.Run()
+		}
+		if framesA != 10 || framesB != 0 {
+			t.Errorf("got = %v %v, want %v %v", framesA, framesB, 10, 0)
+		}
+	})
+
+	t.Run("Keeps the state of the instances apart", func(t *testing.T) {
+		sa, err := a.Serialize(a.SerializeSize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb, err := b.Serialize(b.SerializeSize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(sa, sb) {
+			t.Errorf("got = %v, want %v", "equal states", "different states")
+		}
+		for i := 0; i < 10; i++ {
+			b.Run()
+		}
+		sb, _ = b.Serialize(b.SerializeSize())
+		if !bytes.Equal(sa, sb) {
+			t.Errorf("got = %v, want %v", "different states", "equal states")
+		}
+	})
+
+	copy := b.copy
+	b.UnloadGame()
+	b.Deinit()
+	a.UnloadGame()
+	a.Deinit()
+
+	t.Run("Frees the slots and the copy on Deinit", func(t *testing.T) {
+		if got := Instances(); got != 0 {
+			t.Errorf("got = %v, want %v", got, 0)
+		}
+		if _, err := os.Stat(copy); !os.IsNotExist(err) {
+			t.Errorf("got = %v, want %v", err, "not exist")
+		}
+	})
+}
@@ -10,14 +10,15 @@ package libretro
 
 /*
 #include "libretro.h"
+#include "instances.h"
 #include <stdlib.h>
 #include <stdio.h>
 #include <string.h>
 
 void cothread_init();
 
-void bridge_retro_init(void *f);
-void bridge_retro_deinit(void *f);
+void bridge_retro_init(bool use_thread, void *f);
+void bridge_retro_deinit(bool use_thread, void *f);
 unsigned bridge_retro_api_version(void *f);
 void bridge_retro_get_system_info(void *f, struct retro_system_info *si);
 void bridge_retro_get_system_av_info(void *f, struct retro_system_av_info *si);
@@ -29,12 +30,15 @@ void bridge_retro_set_input_state(void *f, void *callback);
 void bridge_retro_set_audio_sample(void *f, void *callback);
 void bridge_retro_set_audio_sample_batch(void *f, void *callback);
 bool bridge_retro_load_game(void *f, struct retro_game_info *gi);
-bool bridge_retro_serialize(void *f, void *data, size_t size);
-bool bridge_retro_unserialize(void *f, void *data, size_t size);
+bool bridge_retro_load_game_special(void *f, unsigned game_type, struct retro_game_info *gi, size_t num_info);
+bool bridge_retro_serialize(bool use_thread, void *f, void *data, size_t size);
+bool bridge_retro_unserialize(bool use_thread, void *f, void *data, size_t size);
 size_t bridge_retro_serialize_size(void *f);
-void bridge_retro_unload_game(void *f);
-void bridge_retro_run(void *f);
-void bridge_retro_reset(void *f);
+void bridge_retro_unload_game(bool use_thread, void *f);
+void bridge_retro_run(bool use_thread, void *f);
+void bridge_retro_reset(bool use_thread, void *f);
+void bridge_retro_cheat_reset(bool use_thread, void *f);
+void bridge_retro_cheat_set(void *f, unsigned index, bool enabled, const char *code);
 void bridge_retro_frame_time_callback(retro_frame_time_callback_t f, retro_usec_t usec);
 void bridge_retro_audio_callback(retro_audio_callback_t f);
 void bridge_retro_audio_set_state(retro_audio_set_state_callback_t f, bool state);
@@ -45,19 +49,19 @@ bool bridge_retro_get_eject_state(retro_get_eject_state_t f);
 unsigned bridge_retro_get_image_index(retro_get_image_index_t f);
 void bridge_retro_set_image_index(retro_set_image_index_t f, unsigned index);
 unsigned bridge_retro_get_num_images(retro_get_num_images_t f);
-
-bool coreEnvironment_cgo(unsigned cmd, void *data);
-void coreVideoRefresh_cgo(void *data, unsigned width, unsigned height, size_t pitch);
-void coreInputPoll_cgo();
-void coreAudioSample_cgo(int16_t left, int16_t right);
-size_t coreAudioSampleBatch_cgo(const int16_t *data, size_t frames);
-int16_t coreInputState_cgo(unsigned port, unsigned device, unsigned index, unsigned id);
-void coreLog_cgo(enum retro_log_level level, const char *msg);
-int64_t coreGetTimeUsec_cgo();
+bool bridge_retro_replace_image_index(retro_replace_image_index_t f, unsigned index, const struct retro_game_info *info);
+bool bridge_retro_add_image_index(retro_add_image_index_t f);
+bool bridge_retro_set_initial_image(retro_set_initial_image_t f, unsigned index, const char *path);
+bool bridge_retro_get_image_path(retro_get_image_path_t f, unsigned index, char *path, size_t len);
+bool bridge_retro_get_image_label(retro_get_image_label_t f, unsigned index, char *label, size_t len);
+void bridge_retro_hw_context_reset(retro_hw_context_reset_t f);
+bool bridge_is_hw_frame(const void *data);
+bool bridge_retro_core_options_update_display(retro_core_options_update_display_callback_t f);
 */
 import "C"
 import (
 	"errors"
+	"os"
 	"strings"
 	"unsafe"
 )
@@ -213,6 +217,65 @@ func (cod *CoreOptionDefinition) DefaultValue() string {
 	return C.GoString(cod.default_value)
 }
 
+// CoreOptionCategory groups core options in the version 2 of the core options API
+type CoreOptionCategory struct {
+	Key  string
+	Desc string
+	Info string
+}
+
+// CoreOptionV2Definition represents a core option in the version 2 of the core options API
+type CoreOptionV2Definition C.struct_retro_core_option_v2_definition
+
+// Key returns the key of a CoreOptionV2Definition as a string
+func (cod *CoreOptionV2Definition) Key() string {
+	return C.GoString(cod.key)
+}
+
+// Desc returns the name of a CoreOptionV2Definition, as displayed inside its
+// category if it has one
+func (cod *CoreOptionV2Definition) Desc() string {
+	if cod.category_key != nil && cod.desc_categorized != nil {
+		return C.GoString(cod.desc_categorized)
+	}
+	return C.GoString(cod.desc)
+}
+
+// Info returns the detailed description of a CoreOptionV2Definition, as
+// displayed inside its category if it has one
+func (cod *CoreOptionV2Definition) Info() string {
+	if cod.category_key != nil && cod.info_categorized != nil {
+		return C.GoString(cod.info_categorized)
+	}
+	return C.GoString(cod.info)
+}
+
+// Category returns the key of the category of a CoreOptionV2Definition, or
+// an empty string
+func (cod *CoreOptionV2Definition) Category() string {
+	return C.GoString(cod.category_key)
+}
+
+// Choices returns the CoreOptionV2Definition values as a string slice
+func (cod *CoreOptionV2Definition) Choices() []string {
+	choices := []string{}
+
+	for i := 0; i < C.RETRO_NUM_CORE_OPTION_VALUES_MAX; i++ {
+		v := (C.struct_retro_core_option_value)(cod.values[i])
+		if v.value == nil {
+			break
+		}
+		choices = append(choices, C.GoString(v.value))
+	}
+
+	return choices
+}
+
+// DefaultValue returns the default value of a CoreOptionV2Definition as a string
+func (cod *CoreOptionV2Definition) DefaultValue() string {
+	return C.GoString(cod.default_value)
+}
+
 // FrameTimeCallback stores the frame time callback itself and the reference time
 type FrameTimeCallback struct {
 	Callback  func(int64)
@@ -280,8 +343,19 @@ const (
 	// Positive Y axis is down.
 	// Only use ANALOG type when polling for analog values of the axes.
 	DeviceAnalog = uint32(C.RETRO_DEVICE_ANALOG)
+
+	// DeviceTypeShift is the shift of the core specific part of a device ID
+	DeviceTypeShift = uint32(C.RETRO_DEVICE_TYPE_SHIFT)
+	// DeviceMask extracts the base device from a core specific device ID
+	DeviceMask = uint32(C.RETRO_DEVICE_MASK)
 )
 
+// DeviceSubclass returns the ID of the core specific device number id,
+// which is a specialization of base
+func DeviceSubclass(base, id uint32) uint32 {
+	return ((id + 1) << DeviceTypeShift) | base
+}
+
 // Buttons for the RetroPad (JOYPAD).
 // The placement of these is equivalent to placements on the
 // Super Nintendo controller.
@@ -389,9 +463,12 @@ const (
 	EnvironmentSetCoreOptions                   = uint32(C.RETRO_ENVIRONMENT_SET_CORE_OPTIONS)
 	EnvironmentSetCoreOptionsIntl               = uint32(C.RETRO_ENVIRONMENT_SET_CORE_OPTIONS_INTL)
 	EnvironmentSetCoreOptionsDisplay            = uint32(C.RETRO_ENVIRONMENT_SET_CORE_OPTIONS_DISPLAY)
+	EnvironmentSetCoreOptionsV2                 = uint32(C.RETRO_ENVIRONMENT_SET_CORE_OPTIONS_V2)
+	EnvironmentSetCoreOptionsV2Intl             = uint32(C.RETRO_ENVIRONMENT_SET_CORE_OPTIONS_V2_INTL)
+	EnvironmentSetCoreOptionsUpdateDisplay      = uint32(C.RETRO_ENVIRONMENT_SET_CORE_OPTIONS_UPDATE_DISPLAY_CALLBACK)
 	EnvironmentGetPrefferedHWRender             = uint32(C.RETRO_ENVIRONMENT_GET_PREFERRED_HW_RENDER)
 	EnvironmentGetDiskControlInterfaceVersion   = uint32(C.RETRO_ENVIRONMENT_GET_DISK_CONTROL_INTERFACE_VERSION)
-	EnvironmentGetDiskControlExtInterface       = uint32(C.RETRO_ENVIRONMENT_SET_DISK_CONTROL_EXT_INTERFACE)
+	EnvironmentSetDiskControlExtInterface       = uint32(C.RETRO_ENVIRONMENT_SET_DISK_CONTROL_EXT_INTERFACE)
 )
 
 // Debug levels
@@ -403,6 +480,17 @@ const (
 	LogLevelDummy = uint32(C.RETRO_LOG_DUMMY)
 )
 
+// Hardware contexts a core can request with EnvironmentSetHWRender
+const (
+	HWContextNone            = uint32(C.RETRO_HW_CONTEXT_NONE)
+	HWContextOpenGL          = uint32(C.RETRO_HW_CONTEXT_OPENGL)
+	HWContextOpenGLES2       = uint32(C.RETRO_HW_CONTEXT_OPENGLES2)
+	HWContextOpenGLCore      = uint32(C.RETRO_HW_CONTEXT_OPENGL_CORE)
+	HWContextOpenGLES3       = uint32(C.RETRO_HW_CONTEXT_OPENGLES3)
+	HWContextOpenGLESVersion = uint32(C.RETRO_HW_CONTEXT_OPENGLES_VERSION)
+	HWContextVulkan          = uint32(C.RETRO_HW_CONTEXT_VULKAN)
+)
+
 // Memory constants
 const (
 	MemoryMask      = uint32(C.RETRO_MEMORY_MASK)
@@ -421,30 +509,43 @@ type (
 	inputStateFunc       func(uint, uint32, uint, uint) int16
 	logFunc              func(uint32, string)
 	getTimeUsecFunc      func() int64
+	getFramebufferFunc   func() uintptr
+	getProcAddressFunc   func(string) unsafe.Pointer
 )
 
-var (
-	environment      environmentFunc
-	videoRefresh     videoRefreshFunc
-	audioSample      audioSampleFunc
-	audioSampleBatch audioSampleBatchFunc
-	inputPoll        inputPollFunc
-	inputState       inputStateFunc
-	log              logFunc
-	getTimeUsec      getTimeUsecFunc
-)
-
-// Load dynamically loads a libretro core at the given path and returns a Core instance
+// Load dynamically loads a libretro core at the given path and returns a Core
+// instance. Several instances can be loaded at the same time, even of the same
+// core, up to MaxInstances.
 func Load(sofile string) (*Core, error) {
-	core := Core{}
+	core := &Core{path: sofile}
+
+	if err := register(core); err != nil {
+		return nil, err
+	}
+
+	lib := sofile
+	if loadedElsewhere(core) {
+		var err error
+		core.copy, err = copyLibrary(sofile)
+		if err != nil {
+			unregister(core)
+			return nil, err
+		}
+		lib = core.copy
+	}
 
 	var err error
-	core.handle, err = DlOpen(sofile)
+	core.handle, err = DlOpen(lib)
 	if err != nil {
+		unregister(core)
+		if core.copy != "" {
+			os.Remove(core.copy)
+		}
 		return nil, err
 	}
 
 	C.cothread_init()
+	core.thread = true
 
 	core.symRetroInit = DlSym(core.handle, "retro_init")
 	core.symRetroDeinit = DlSym(core.handle, "retro_deinit")
@@ -461,19 +562,22 @@ func Load(sofile string) (*Core, error) {
 	core.symRetroRun = DlSym(core.handle, "retro_run")
 	core.symRetroReset = DlSym(core.handle, "retro_reset")
 	core.symRetroLoadGame = DlSym(core.handle, "retro_load_game")
+	core.symRetroLoadGameSpecial = DlSym(core.handle, "retro_load_game_special")
 	core.symRetroUnloadGame = DlSym(core.handle, "retro_unload_game")
 	core.symRetroSerializeSize = DlSym(core.handle, "retro_serialize_size")
 	core.symRetroSerialize = DlSym(core.handle, "retro_serialize")
 	core.symRetroUnserialize = DlSym(core.handle, "retro_unserialize")
 	core.symRetroGetMemorySize = DlSym(core.handle, "retro_get_memory_size")
 	core.symRetroGetMemoryData = DlSym(core.handle, "retro_get_memory_data")
+	core.symRetroCheatReset = DlSym(core.handle, "retro_cheat_reset")
+	core.symRetroCheatSet = DlSym(core.handle, "retro_cheat_set")
 
-	return &core, nil
+	return core, nil
 }
 
 // Init takes care of the library global initialization
 func (core *Core) Init() {
-	C.bridge_retro_init(core.symRetroInit)
+	C.bridge_retro_init(C.bool(core.thread), core.symRetroInit)
 }
 
 // APIVersion returns the RETRO_API_VERSION.
@@ -484,17 +588,33 @@ func (core *Core) APIVersion() uint {
 
 // Deinit takes care of the library global deinitialization
 func (core *Core) Deinit() {
-	C.bridge_retro_deinit(core.symRetroDeinit)
+	C.bridge_retro_deinit(C.bool(core.thread), core.symRetroDeinit)
 	DlClose(core.handle)
 	core.MemoryMap = nil
-	environment = nil
-	videoRefresh = nil
-	audioSample = nil
-	audioSampleBatch = nil
-	inputPoll = nil
-	inputState = nil
-	log = nil
-	getTimeUsec = nil
+	core.InputDescriptors = nil
+	core.ControllerInfo = nil
+	core.Subsystems = nil
+	core.SupportsNoGame = false
+	core.environment = nil
+	core.videoRefresh = nil
+	core.audioSample = nil
+	core.audioSampleBatch = nil
+	core.inputPoll = nil
+	core.inputState = nil
+	core.log = nil
+	core.getTimeUsec = nil
+	core.getFramebuffer = nil
+	core.getProcAddress = nil
+	unregister(core)
+	if core.copy != "" {
+		os.Remove(core.copy)
+		core.copy = ""
+	}
+	// The VFS and the input devices are shared by all the instances
+	if Instances() == 0 {
+		vfs = nil
+		unbindDevices()
+	}
 }
 
 // Run runs the game for one video frame.
@@ -504,12 +624,25 @@ func (core *Core) Deinit() {
 // a frame if GET_CAN_DUPE returns true.
 // In this case, the video callback can take a NULL argument for data.
 func (core *Core) Run() {
-	C.bridge_retro_run(core.symRetroRun)
+	C.bridge_retro_run(C.bool(core.thread), core.symRetroRun)
 }
 
 // Reset resets the current game.
 func (core *Core) Reset() {
-	C.bridge_retro_reset(core.symRetroReset)
+	C.bridge_retro_reset(C.bool(core.thread), core.symRetroReset)
+}
+
+// CheatReset disables all the cheats of the current game.
+func (core *Core) CheatReset() {
+	C.bridge_retro_cheat_reset(C.bool(core.thread), core.symRetroCheatReset)
+}
+
+// CheatSet enables or disables a cheat code. The format of the code, like
+// Game Genie or Action Replay, depends on the core.
+func (core *Core) CheatSet(index uint, enabled bool, code string) {
+	ccode := C.CString(code)
+	defer C.free(unsafe.Pointer(ccode))
+	C.bridge_retro_cheat_set(core.symRetroCheatSet, C.unsigned(index), C.bool(enabled), ccode)
 }
 
 // GetSystemInfo returns statically known system info. Pointers provided in *info
@@ -560,6 +693,32 @@ func (core *Core) LoadGame(gi GameInfo) bool {
 	return bool(C.bridge_retro_load_game(core.symRetroLoadGame, &rgi))
 }
 
+// LoadNoGame starts a core that supports running without a game
+func (core *Core) LoadNoGame() bool {
+	return bool(C.bridge_retro_load_game(core.symRetroLoadGame, nil))
+}
+
+// LoadGameSpecial loads the content of a subsystem. gis follows the order of
+// the ROMs of the subsystem, with a zero GameInfo for skipped optional ROMs.
+// The core copies what it needs during the load, the paths are freed after.
+func (core *Core) LoadGameSpecial(gameType uint32, gis []GameInfo) bool {
+	if len(gis) == 0 {
+		return false
+	}
+	array := C.calloc(C.size_t(len(gis)), C.size_t(unsafe.Sizeof(C.struct_retro_game_info{})))
+	defer C.free(array)
+	rgis := unsafe.Slice((*C.struct_retro_game_info)(array), len(gis))
+	for i, gi := range gis {
+		if gi.Path != "" {
+			rgis[i].path = C.CString(gi.Path)
+			defer C.free(unsafe.Pointer(rgis[i].path))
+		}
+		rgis[i].size = C.size_t(gi.Size)
+		rgis[i].data = gi.Data
+	}
+	return bool(C.bridge_retro_load_game_special(core.symRetroLoadGameSpecial, C.unsigned(gameType), &rgis[0], C.size_t(len(gis))))
+}
+
 // SerializeSize returns the amount of data the implementation requires to serialize
 // internal state (save states).
 // Between calls to retro_load_game() and retro_unload_game(), the
@@ -572,7 +731,7 @@ func (core *Core) SerializeSize() uint {
 // Serialize serializes internal state and returns the state as a byte slice.
 func (core *Core) Serialize(size uint) ([]byte, error) {
 	data := C.malloc(C.size_t(size))
-	ok := bool(C.bridge_retro_serialize(core.symRetroSerialize, data, C.size_t(size)))
+	ok := bool(C.bridge_retro_serialize(C.bool(core.thread), core.symRetroSerialize, data, C.size_t(size)))
 	if !ok {
 		return nil, errors.New("retro_serialize failed")
 	}
@@ -589,7 +748,7 @@ func (core *Core) Unserialize(bytes []byte, size uint) error {
 	if uint(len(bytes)) > size {
 		size = uint(len(bytes))
 	}
-	ok := bool(C.bridge_retro_unserialize(core.symRetroUnserialize, unsafe.Pointer(&bytes[0]), C.size_t(size)))
+	ok := bool(C.bridge_retro_unserialize(C.bool(core.thread), core.symRetroUnserialize, unsafe.Pointer(&bytes[0]), C.size_t(size)))
 	if !ok {
 		return errors.New("retro_unserialize failed")
 	}
@@ -598,63 +757,63 @@ func (core *Core) Unserialize(bytes []byte, size uint) error {
 
 // UnloadGame unloads a currently loaded game
 func (core *Core) UnloadGame() {
-	C.bridge_retro_unload_game(core.symRetroUnloadGame)
+	C.bridge_retro_unload_game(C.bool(core.thread), core.symRetroUnloadGame)
 }
 
 // SetEnvironment sets the environment callback.
 // Must be called before Init
 func (core *Core) SetEnvironment(f environmentFunc) {
-	environment = f
-	C.bridge_retro_set_environment(core.symRetroSetEnvironment, C.coreEnvironment_cgo)
+	core.environment = f
+	C.bridge_retro_set_environment(core.symRetroSetEnvironment, unsafe.Pointer(core.callbacks().environment))
 }
 
 // SetVideoRefresh sets the video refresh callback.
 // Must be set before the first Run call
 func (core *Core) SetVideoRefresh(f videoRefreshFunc) {
-	videoRefresh = f
-	C.bridge_retro_set_video_refresh(core.symRetroSetVideoRefresh, C.coreVideoRefresh_cgo)
+	core.videoRefresh = f
+	C.bridge_retro_set_video_refresh(core.symRetroSetVideoRefresh, unsafe.Pointer(core.callbacks().video_refresh))
 }
 
 // SetAudioSample sets the audio sample callback.
 // Must be set before the first Run call
 func (core *Core) SetAudioSample(f audioSampleFunc) {
-	audioSample = f
-	C.bridge_retro_set_audio_sample(core.symRetroSetAudioSample, C.coreAudioSample_cgo)
+	core.audioSample = f
+	C.bridge_retro_set_audio_sample(core.symRetroSetAudioSample, unsafe.Pointer(core.callbacks().audio_sample))
 }
 
 // SetAudioSampleBatch sets the audio sample batch callback.
 // Must be set before the first Run call
 func (core *Core) SetAudioSampleBatch(f audioSampleBatchFunc) {
-	audioSampleBatch = f
-	C.bridge_retro_set_audio_sample_batch(core.symRetroSetAudioSampleBatch, C.coreAudioSampleBatch_cgo)
+	core.audioSampleBatch = f
+	C.bridge_retro_set_audio_sample_batch(core.symRetroSetAudioSampleBatch, unsafe.Pointer(core.callbacks().audio_sample_batch))
 }
 
 // SetInputPoll sets the input poll callback.
 // Must be set before the first Run call
 func (core *Core) SetInputPoll(f inputPollFunc) {
-	inputPoll = f
-	C.bridge_retro_set_input_poll(core.symRetroSetInputPoll, C.coreInputPoll_cgo)
+	core.inputPoll = f
+	C.bridge_retro_set_input_poll(core.symRetroSetInputPoll, unsafe.Pointer(core.callbacks().input_poll))
 }
 
 // SetInputState sets the input state callback.
 // Must be set before the first Run call
 func (core *Core) SetInputState(f inputStateFunc) {
-	inputState = f
-	C.bridge_retro_set_input_state(core.symRetroSetInputState, C.coreInputState_cgo)
+	core.inputState = f
+	C.bridge_retro_set_input_state(core.symRetroSetInputState, unsafe.Pointer(core.callbacks().input_state))
 }
 
 // BindLogCallback binds f to the log callback
 func (core *Core) BindLogCallback(data unsafe.Pointer, f logFunc) {
-	log = f
+	core.log = f
 	cb := (*C.struct_retro_log_callback)(data)
-	cb.log = (C.retro_log_printf_t)(C.coreLog_cgo)
+	cb.log = core.callbacks().log
 }
 
 // BindPerfCallback binds f to the perf callback get_time_usec
 func (core *Core) BindPerfCallback(data unsafe.Pointer, f getTimeUsecFunc) {
-	getTimeUsec = f
+	core.getTimeUsec = f
 	cb := (*C.struct_retro_perf_callback)(data)
-	cb.get_time_usec = (C.retro_perf_get_time_usec_t)(C.coreGetTimeUsec_cgo)
+	cb.get_time_usec = core.callbacks().get_time_usec
 }
 
 // SetControllerPortDevice sets the device type attached to a controller port
@@ -663,67 +822,96 @@ func (core *Core) SetControllerPortDevice(port uint, device uint32) {
 }
 
 //export coreEnvironment
-func coreEnvironment(cmd C.unsigned, data unsafe.Pointer) bool {
-	if environment == nil {
+func coreEnvironment(slot C.int, cmd C.unsigned, data unsafe.Pointer) bool {
+	core := instance(slot)
+	if core == nil || core.environment == nil {
 		return false
 	}
-	return environment(uint32(cmd), data)
+	return core.environment(uint32(cmd), data)
 }
 
 //export coreVideoRefresh
-func coreVideoRefresh(data unsafe.Pointer, width C.unsigned, height C.unsigned, pitch C.size_t) {
-	if videoRefresh == nil {
+func coreVideoRefresh(slot C.int, data unsafe.Pointer, width C.unsigned, height C.unsigned, pitch C.size_t) {
+	core := instance(slot)
+	if core == nil || core.videoRefresh == nil {
 		return
 	}
-	videoRefresh(data, int32(width), int32(height), int32(pitch))
+	if C.bridge_is_hw_frame(data) {
+		data = HWFrameBufferValid
+	}
+	core.videoRefresh(data, int32(width), int32(height), int32(pitch))
 }
 
 //export coreInputPoll
-func coreInputPoll() {
-	if inputPoll == nil {
+func coreInputPoll(slot C.int) {
+	core := instance(slot)
+	if core == nil || core.inputPoll == nil {
 		return
 	}
-	inputPoll()
+	core.inputPoll()
 }
 
 //export coreInputState
-func coreInputState(port C.unsigned, device C.unsigned, index C.unsigned, id C.unsigned) C.int16_t {
-	if inputState == nil {
+func coreInputState(slot C.int, port C.unsigned, device C.unsigned, index C.unsigned, id C.unsigned) C.int16_t {
+	core := instance(slot)
+	if core == nil || core.inputState == nil {
 		return 0
 	}
-	return C.int16_t(inputState(uint(port), uint32(device), uint(index), uint(id)))
+	return C.int16_t(core.inputState(uint(port), uint32(device), uint(index), uint(id)))
 }
 
 //export coreAudioSample
-func coreAudioSample(left C.int16_t, right C.int16_t) {
-	if audioSample == nil {
+func coreAudioSample(slot C.int, left C.int16_t, right C.int16_t) {
+	core := instance(slot)
+	if core == nil || core.audioSample == nil {
 		return
 	}
-	audioSample(int16(left), int16(right))
+	core.audioSample(int16(left), int16(right))
 }
 
 //export coreAudioSampleBatch
-func coreAudioSampleBatch(buf unsafe.Pointer, frames C.size_t) C.size_t {
-	if audioSampleBatch == nil {
+func coreAudioSampleBatch(slot C.int, buf unsafe.Pointer, frames C.size_t) C.size_t {
+	core := instance(slot)
+	if core == nil || core.audioSampleBatch == nil {
 		return 0
 	}
-	return C.size_t(audioSampleBatch(C.GoBytes(buf, C.int(4*int(frames))), int32(frames))) / 4
+	return C.size_t(core.audioSampleBatch(C.GoBytes(buf, C.int(4*int(frames))), int32(frames))) / 4
 }
 
 //export coreLog
-func coreLog(level C.enum_retro_log_level, msg *C.char) {
-	if log == nil {
+func coreLog(slot C.int, level C.enum_retro_log_level, msg *C.char) {
+	core := instance(slot)
+	if core == nil || core.log == nil {
 		return
 	}
-	log(level, C.GoString(msg))
+	core.log(uint32(level), C.GoString(msg))
 }
 
 //export coreGetTimeUsec
-func coreGetTimeUsec() C.uint64_t {
-	if getTimeUsec == nil {
+func coreGetTimeUsec(slot C.int) C.uint64_t {
+	core := instance(slot)
+	if core == nil || core.getTimeUsec == nil {
+		return 0
+	}
+	return C.uint64_t(core.getTimeUsec())
+}
+
+//export coreGetCurrentFramebuffer
+func coreGetCurrentFramebuffer(slot C.int) C.uintptr_t {
+	core := instance(slot)
+	if core == nil || core.getFramebuffer == nil {
 		return 0
 	}
-	return C.uint64_t(getTimeUsec())
+	return C.uintptr_t(core.getFramebuffer())
+}
+
+//export coreGetProcAddress
+func coreGetProcAddress(slot C.int, sym *C.char) unsafe.Pointer {
+	core := instance(slot)
+	if core == nil || core.getProcAddress == nil {
+		return nil
+	}
+	return core.getProcAddress(C.GoString(sym))
 }
 
 // SetData is a setter for the data of a GameInfo type
@@ -782,6 +970,51 @@ func GetCoreOptionDefinitions(data unsafe.Pointer) []CoreOptionDefinition {
 	return definitions
 }
 
+// GetCoreOptionsV2 is an environment callback helper that returns the option
+// categories and the options needed by a core
+func GetCoreOptionsV2(data unsafe.Pointer) ([]CoreOptionCategory, []CoreOptionV2Definition) {
+	var categories []CoreOptionCategory
+	var definitions []CoreOptionV2Definition
+
+	opts := (*C.struct_retro_core_options_v2)(data)
+	for i := 0; opts.categories != nil; i++ {
+		c := (*C.struct_retro_core_option_v2_category)(unsafe.Pointer(uintptr(unsafe.Pointer(opts.categories)) +
+			uintptr(i)*unsafe.Sizeof(C.struct_retro_core_option_v2_category{})))
+		if c.key == nil {
+			break
+		}
+		categories = append(categories, CoreOptionCategory{
+			Key:  C.GoString(c.key),
+			Desc: C.GoString(c.desc),
+			Info: C.GoString(c.info),
+		})
+	}
+	for i := 0; opts.definitions != nil; i++ {
+		v := (*C.struct_retro_core_option_v2_definition)(unsafe.Pointer(uintptr(unsafe.Pointer(opts.definitions)) +
+			uintptr(i)*unsafe.Sizeof(C.struct_retro_core_option_v2_definition{})))
+		if v.key == nil {
+			break
+		}
+		definitions = append(definitions, *(*CoreOptionV2Definition)(v))
+	}
+
+	return categories, definitions
+}
+
+// GetCoreOptionsV2Intl is an environment callback helper that returns the
+// option categories and the options needed by a core, in US English
+func GetCoreOptionsV2Intl(data unsafe.Pointer) ([]CoreOptionCategory, []CoreOptionV2Definition) {
+	intl := (*C.struct_retro_core_options_v2_intl)(data)
+	return GetCoreOptionsV2(unsafe.Pointer(intl.us))
+}
+
+// GetCoreOptionDisplay is an environment callback helper that returns the key
+// of a core option and whether it should be displayed
+func GetCoreOptionDisplay(data unsafe.Pointer) (string, bool) {
+	d := (*C.struct_retro_core_option_display)(data)
+	return C.GoString(d.key), bool(d.visible)
+}
+
 // GetCoreOptionsIntl is an environment callback helper that returns the list of CoreOptionsIntl needed by a core
 func GetCoreOptionsIntl(data unsafe.Pointer) []CoreOptionDefinition {
 	var definitions []CoreOptionDefinition
@@ -817,7 +1050,8 @@ func GetMemoryMap(data unsafe.Pointer) []MemoryDescriptor {
 		descriptors[i] = MemoryDescriptor{
 			Flags:      uint64(d.flags),
 			Ptr:        d.ptr,
-			Offset:     uintptr(d.start),
+			Offset:     uintptr(d.offset),
+			Start:      uintptr(d.start),
 			Select:     uintptr(d._select),
 			Disconnect: uintptr(d.disconnect),
 			Len:        uintptr(d.len),
@@ -827,6 +1061,123 @@ func GetMemoryMap(data unsafe.Pointer) []MemoryDescriptor {
 	return descriptors
 }
 
+// InputDescriptor tells what an input does in the game
+type InputDescriptor struct {
+	Port        uint
+	Device      uint32
+	Index       uint
+	ID          uint
+	Description string
+}
+
+// GetInputDescriptors is an environment callback helper that returns the
+// input descriptors set in EnvironmentSetInputDescriptors
+func GetInputDescriptors(data unsafe.Pointer) []InputDescriptor {
+	descriptors := []InputDescriptor{}
+	for i := 0; ; i++ {
+		cDescriptor := unsafe.Pointer(uintptr(data) + uintptr(i)*unsafe.Sizeof(C.struct_retro_input_descriptor{}))
+		d := *(*C.struct_retro_input_descriptor)(cDescriptor)
+		if d.description == nil {
+			break
+		}
+		descriptors = append(descriptors, InputDescriptor{
+			Port:        uint(d.port),
+			Device:      uint32(d.device),
+			Index:       uint(d.index),
+			ID:          uint(d.id),
+			Description: C.GoString(d.description),
+		})
+	}
+	return descriptors
+}
+
+// ControllerDescription is a device a core supports on a port
+type ControllerDescription struct {
+	Desc string
+	ID   uint32
+}
+
+// GetControllerInfo is an environment callback helper that returns the
+// devices supported on each port, set in EnvironmentSetControllerInfo
+func GetControllerInfo(data unsafe.Pointer) [][]ControllerDescription {
+	ports := [][]ControllerDescription{}
+	for i := 0; ; i++ {
+		cInfo := unsafe.Pointer(uintptr(data) + uintptr(i)*unsafe.Sizeof(C.struct_retro_controller_info{}))
+		info := *(*C.struct_retro_controller_info)(cInfo)
+		if info.types == nil {
+			break
+		}
+		types := unsafe.Slice(info.types, int(info.num_types))
+		port := make([]ControllerDescription, len(types))
+		for i, t := range types {
+			port[i] = ControllerDescription{Desc: C.GoString(t.desc), ID: uint32(t.id)}
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// SubsystemMemoryInfo is a persistent memory of a subsystem ROM
+type SubsystemMemoryInfo struct {
+	Extension string
+	Type      uint32
+}
+
+// SubsystemROMInfo describes one of the ROMs needed by a subsystem
+type SubsystemROMInfo struct {
+	Desc            string
+	ValidExtensions string
+	NeedFullpath    bool
+	BlockExtract    bool
+	Required        bool
+	Memory          []SubsystemMemoryInfo
+}
+
+// SubsystemInfo is a way to load a game made of several ROMs, like a Game Boy
+// game in a Super Game Boy. The first ROM is the most significant one.
+type SubsystemInfo struct {
+	Desc  string
+	Ident string
+	ROMs  []SubsystemROMInfo
+	ID    uint32
+}
+
+// GetSubsystemInfo is an environment callback helper that returns the
+// subsystems set in EnvironmentSetSubsystemInfo
+func GetSubsystemInfo(data unsafe.Pointer) []SubsystemInfo {
+	subsystems := []SubsystemInfo{}
+	for i := 0; ; i++ {
+		cInfo := unsafe.Pointer(uintptr(data) + uintptr(i)*unsafe.Sizeof(C.struct_retro_subsystem_info{}))
+		info := *(*C.struct_retro_subsystem_info)(cInfo)
+		if info.desc == nil {
+			break
+		}
+		subsystem := SubsystemInfo{
+			Desc:  C.GoString(info.desc),
+			Ident: C.GoString(info.ident),
+			ID:    uint32(info.id),
+		}
+		for _, rom := range unsafe.Slice(info.roms, int(info.num_roms)) {
+			r := SubsystemROMInfo{
+				Desc:            C.GoString(rom.desc),
+				ValidExtensions: C.GoString(rom.valid_extensions),
+				NeedFullpath:    bool(rom.need_fullpath),
+				BlockExtract:    bool(rom.block_extract),
+				Required:        bool(rom.required),
+			}
+			for _, m := range unsafe.Slice(rom.memory, int(rom.num_memory)) {
+				r.Memory = append(r.Memory, SubsystemMemoryInfo{
+					Extension: C.GoString(m.extension),
+					Type:      uint32(m._type),
+				})
+			}
+			subsystem.ROMs = append(subsystem.ROMs, r)
+		}
+		subsystems = append(subsystems, subsystem)
+	}
+	return subsystems
+}
+
 // GetGeometry is an environment callback helper that returns the game geometry
 // in EnvironmentSetGeometry.
 func GetGeometry(data unsafe.Pointer) GameGeometry {
@@ -913,21 +1264,35 @@ func (core *Core) GetMemoryData(id uint32) unsafe.Pointer {
 	return C.bridge_retro_get_memory_data(core.symRetroGetMemoryData, C.unsigned(id))
 }
 
-// DiskControlCallback is an interface which frontend can use to eject and insert disk images
+// DiskControlCallback is an interface which frontend can use to eject and insert disk images.
+// The functions the core doesn't implement are nil.
 type DiskControlCallback struct {
 	SetEjectState func(bool)
 	GetEjectState func() bool
 	GetImageIndex func() uint
 	SetImageIndex func(uint)
 	GetNumImages  func() uint
+
+	// ReplaceImageIndex replaces the image at index, a nil GameInfo removes it
+	ReplaceImageIndex func(uint, *GameInfo) bool
+	// AddImageIndex appends an empty image, to be filled with ReplaceImageIndex
+	AddImageIndex func() bool
+
+	// The functions below come from the extended interface
+
+	// SetInitialImage selects the image inserted when the game is loaded. It
+	// must be called before LoadGame.
+	SetInitialImage func(uint, string) bool
+	GetImagePath    func(uint) string
+	GetImageLabel   func(uint) string
 }
 
-// SetDiskControlCallback sets an interface which frontend can use to eject and insert disk images
-func (core *Core) SetDiskControlCallback(data unsafe.Pointer) {
-	if data == nil {
-		return
-	}
-	c := *(*C.struct_retro_disk_control_callback)(data)
+// diskStringLen is the size of the buffers receiving image paths and labels
+const diskStringLen = 4096
+
+// newDiskControlCallback binds the functions shared by the basic and the
+// extended disk control interfaces
+func newDiskControlCallback(c C.struct_retro_disk_control_callback) *DiskControlCallback {
 	dcc := &DiskControlCallback{}
 	dcc.SetEjectState = func(state bool) {
 		C.bridge_retro_set_eject_state(c.set_eject_state, C.bool(state))
@@ -944,5 +1309,140 @@ func (core *Core) SetDiskControlCallback(data unsafe.Pointer) {
 	dcc.GetNumImages = func() uint {
 		return uint(C.bridge_retro_get_num_images(c.get_num_images))
 	}
+	if c.replace_image_index != nil {
+		dcc.ReplaceImageIndex = func(index uint, gi *GameInfo) bool {
+			if gi == nil {
+				return bool(C.bridge_retro_replace_image_index(c.replace_image_index, C.unsigned(index), nil))
+			}
+			rgi := C.struct_retro_game_info{}
+			rgi.path = C.CString(gi.Path)
+			defer C.free(unsafe.Pointer(rgi.path))
+			rgi.size = C.size_t(gi.Size)
+			rgi.data = gi.Data
+			return bool(C.bridge_retro_replace_image_index(c.replace_image_index, C.unsigned(index), &rgi))
+		}
+	}
+	if c.add_image_index != nil {
+		dcc.AddImageIndex = func() bool {
+			return bool(C.bridge_retro_add_image_index(c.add_image_index))
+		}
+	}
+	return dcc
+}
+
+// SetDiskControlCallback sets an interface which frontend can use to eject and insert disk images
+func (core *Core) SetDiskControlCallback(data unsafe.Pointer) {
+	if data == nil {
+		return
+	}
+	core.DiskControlCallback = newDiskControlCallback(*(*C.struct_retro_disk_control_callback)(data))
+}
+
+// SetDiskControlExtCallback sets the extended disk control interface, which
+// also names the disk images and lets the frontend pick the first one
+func (core *Core) SetDiskControlExtCallback(data unsafe.Pointer) {
+	if data == nil {
+		return
+	}
+	// The extended interface starts with the fields of the basic one
+	dcc := newDiskControlCallback(*(*C.struct_retro_disk_control_callback)(data))
+	c := *(*C.struct_retro_disk_control_ext_callback)(data)
+	if c.set_initial_image != nil {
+		dcc.SetInitialImage = func(index uint, path string) bool {
+			cpath := C.CString(path)
+			defer C.free(unsafe.Pointer(cpath))
+			return bool(C.bridge_retro_set_initial_image(c.set_initial_image, C.unsigned(index), cpath))
+		}
+	}
+	if c.get_image_path != nil {
+		dcc.GetImagePath = func(index uint) string {
+			buf := (*C.char)(C.calloc(diskStringLen, 1))
+			defer C.free(unsafe.Pointer(buf))
+			if !C.bridge_retro_get_image_path(c.get_image_path, C.unsigned(index), buf, diskStringLen) {
+				return ""
+			}
+			return C.GoString(buf)
+		}
+	}
+	if c.get_image_label != nil {
+		dcc.GetImageLabel = func(index uint) string {
+			buf := (*C.char)(C.calloc(diskStringLen, 1))
+			defer C.free(unsafe.Pointer(buf))
+			if !C.bridge_retro_get_image_label(c.get_image_label, C.unsigned(index), buf, diskStringLen) {
+				return ""
+			}
+			return C.GoString(buf)
+		}
+	}
 	core.DiskControlCallback = dcc
 }
+
+// SetCoreOptionsUpdateDisplayCallback sets the function the frontend calls
+// after changing core options, to let the core hide or show other options
+func (core *Core) SetCoreOptionsUpdateDisplayCallback(data unsafe.Pointer) {
+	core.UpdateOptionsDisplay = nil
+	if data == nil {
+		return
+	}
+	c := *(*C.struct_retro_core_options_update_display_callback)(data)
+	if c.callback == nil {
+		return
+	}
+	core.UpdateOptionsDisplay = func() bool {
+		return bool(C.bridge_retro_core_options_update_display(c.callback))
+	}
+}
+
+// hwFrameMarker only exists to give HWFrameBufferValid a unique address
+var hwFrameMarker byte
+
+// HWFrameBufferValid is passed to the video refresh callback instead of a
+// pixel buffer when the core rendered the frame in the framebuffer returned
+// by get_current_framebuffer
+var HWFrameBufferValid = unsafe.Pointer(&hwFrameMarker)
+
+// HWRenderCallback describes the hardware context requested by a core
+type HWRenderCallback struct {
+	ContextType      uint32
+	VersionMajor     uint
+	VersionMinor     uint
+	Depth            bool
+	Stencil          bool
+	BottomLeftOrigin bool
+	ContextReset     func()
+	ContextDestroy   func()
+}
+
+// GetHWRenderCallback reads the hardware context requested by the core
+func GetHWRenderCallback(data unsafe.Pointer) *HWRenderCallback {
+	c := *(*C.struct_retro_hw_render_callback)(data)
+	return &HWRenderCallback{
+		ContextType:      uint32(c.context_type),
+		VersionMajor:     uint(c.version_major),
+		VersionMinor:     uint(c.version_minor),
+		Depth:            bool(c.depth),
+		Stencil:          bool(c.stencil),
+		BottomLeftOrigin: bool(c.bottom_left_origin),
+		ContextReset: func() {
+			C.bridge_retro_hw_context_reset(c.context_reset)
+		},
+		ContextDestroy: func() {
+			C.bridge_retro_hw_context_reset(c.context_destroy)
+		},
+	}
+}
+
+// BindHWRenderCallback accepts the hardware context requested by the core.
+// fb returns the framebuffer object the core renders to and proc resolves
+// OpenGL symbols. The core issues GL calls from retro_run, so the emulation
+// thread is disabled for this instance to keep them on the thread owning
+// the context.
+func (core *Core) BindHWRenderCallback(data unsafe.Pointer, fb getFramebufferFunc, proc getProcAddressFunc) {
+	core.getFramebuffer = fb
+	core.getProcAddress = proc
+	cb := (*C.struct_retro_hw_render_callback)(data)
+	cb.get_current_framebuffer = core.callbacks().get_current_framebuffer
+	cb.get_proc_address = core.callbacks().get_proc_address
+	core.HWRenderCallback = GetHWRenderCallback(data)
+	core.thread = false
+}
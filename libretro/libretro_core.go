@@ -5,6 +5,21 @@ import "unsafe"
 // Core is an instance of a dynamically loaded libretro core
 type Core struct {
 	handle DlHandle
+	slot   int    // index of the C callbacks handed to the core
+	path   string // path of the library, as given to Load
+	copy   string // private copy of the library, when it was already loaded
+	thread bool   // runs on the emulation thread, unless hardware rendered
+
+	environment      environmentFunc
+	videoRefresh     videoRefreshFunc
+	audioSample      audioSampleFunc
+	audioSampleBatch audioSampleBatchFunc
+	inputPoll        inputPollFunc
+	inputState       inputStateFunc
+	log              logFunc
+	getTimeUsec      getTimeUsecFunc
+	getFramebuffer   getFramebufferFunc
+	getProcAddress   getProcAddressFunc
 
 	symRetroInit                    unsafe.Pointer
 	symRetroDeinit                  unsafe.Pointer
@@ -21,16 +36,29 @@ type Core struct {
 	symRetroRun                     unsafe.Pointer
 	symRetroReset                   unsafe.Pointer
 	symRetroLoadGame                unsafe.Pointer
+	symRetroLoadGameSpecial         unsafe.Pointer
 	symRetroUnloadGame              unsafe.Pointer
 	symRetroSerializeSize           unsafe.Pointer
 	symRetroSerialize               unsafe.Pointer
 	symRetroUnserialize             unsafe.Pointer
 	symRetroGetMemorySize           unsafe.Pointer
 	symRetroGetMemoryData           unsafe.Pointer
+	symRetroCheatReset              unsafe.Pointer
+	symRetroCheatSet                unsafe.Pointer
 
 	AudioCallback       *AudioCallback
 	FrameTimeCallback   *FrameTimeCallback
 	DiskControlCallback *DiskControlCallback
+	HWRenderCallback    *HWRenderCallback
+	VFS                 bool // the core accesses files through the VFS interface
+	SupportsNoGame      bool // the core can run without a game
+
+	// UpdateOptionsDisplay lets the core hide or show options after a change.
+	// It returns true if the visibility of an option changed.
+	UpdateOptionsDisplay func() bool
 
-	MemoryMap []MemoryDescriptor
+	MemoryMap        []MemoryDescriptor
+	InputDescriptors []InputDescriptor
+	ControllerInfo   [][]ControllerDescription
+	Subsystems       []SubsystemInfo
 }
@@ -0,0 +1,317 @@
+package libretro
+
+/*
+#include "libretro.h"
+#include <stdlib.h>
+
+bool bridge_set_vfs_interface(struct retro_vfs_interface_info *info);
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"unsafe"
+)
+
+// VFS file access modes
+const (
+	VFSFileAccessRead           = uint32(C.RETRO_VFS_FILE_ACCESS_READ)
+	VFSFileAccessWrite          = uint32(C.RETRO_VFS_FILE_ACCESS_WRITE)
+	VFSFileAccessReadWrite      = uint32(C.RETRO_VFS_FILE_ACCESS_READ_WRITE)
+	VFSFileAccessUpdateExisting = uint32(C.RETRO_VFS_FILE_ACCESS_UPDATE_EXISTING)
+)
+
+// VFS is the file system cores access through the VFS interface. Files and
+// directories are referred to by non zero handles.
+type VFS interface {
+	Open(path string, mode uint32) (uintptr, error)
+	Close(h uintptr) error
+	Size(h uintptr) (int64, error)
+	Truncate(h uintptr, size int64) error
+	Seek(h uintptr, offset int64, whence int) (int64, error)
+	Read(h uintptr, p []byte) (int, error)
+	Write(h uintptr, p []byte) (int, error)
+	Flush(h uintptr) error
+	Remove(path string) error
+	Rename(oldpath, newpath string) error
+	Stat(path string) (fs.FileInfo, error)
+	Mkdir(path string) error
+	OpenDir(path string, hidden bool) (uintptr, error)
+	// ReadDir returns the next entry of a directory, or io.EOF
+	ReadDir(h uintptr) (fs.DirEntry, error)
+	CloseDir(h uintptr) error
+}
+
+var (
+	vfs VFS
+
+	// The C strings handed to the core, freed when closing the handles
+	vfsMu      sync.Mutex
+	vfsPaths   = map[uintptr]*C.char{}
+	vfsDirents = map[uintptr]*vfsDirent{}
+)
+
+type vfsDirent struct {
+	name *C.char
+	dir  bool
+}
+
+// BindVFSInterface gives the core access to the files of v. It returns false
+// if the core needs a newer version of the interface.
+func (core *Core) BindVFSInterface(data unsafe.Pointer, v VFS) bool {
+	if !C.bridge_set_vfs_interface((*C.struct_retro_vfs_interface_info)(data)) {
+		return false
+	}
+	vfs = v
+	core.VFS = true
+	return true
+}
+
+// vfsCode turns an error into the return codes of the VFS interface
+func vfsCode(err error) C.int {
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+//export coreVFSGetPath
+func coreVFSGetPath(h C.uintptr_t) *C.char {
+	vfsMu.Lock()
+	defer vfsMu.Unlock()
+	return vfsPaths[uintptr(h)]
+}
+
+//export coreVFSOpen
+func coreVFSOpen(path *C.char, mode C.unsigned, hints C.unsigned) C.uintptr_t {
+	if vfs == nil {
+		return 0
+	}
+	h, err := vfs.Open(C.GoString(path), uint32(mode))
+	if err != nil {
+		return 0
+	}
+	vfsMu.Lock()
+	vfsPaths[h] = C.CString(C.GoString(path))
+	vfsMu.Unlock()
+	return C.uintptr_t(h)
+}
+
+//export coreVFSClose
+func coreVFSClose(h C.uintptr_t) C.int {
+	vfsMu.Lock()
+	if p, ok := vfsPaths[uintptr(h)]; ok {
+		C.free(unsafe.Pointer(p))
+		delete(vfsPaths, uintptr(h))
+	}
+	vfsMu.Unlock()
+	if vfs == nil {
+		return -1
+	}
+	return vfsCode(vfs.Close(uintptr(h)))
+}
+
+//export coreVFSSize
+func coreVFSSize(h C.uintptr_t) C.int64_t {
+	if vfs == nil {
+		return -1
+	}
+	size, err := vfs.Size(uintptr(h))
+	if err != nil {
+		return -1
+	}
+	return C.int64_t(size)
+}
+
+//export coreVFSTruncate
+func coreVFSTruncate(h C.uintptr_t, length C.int64_t) C.int64_t {
+	if vfs == nil {
+		return -1
+	}
+	return C.int64_t(vfsCode(vfs.Truncate(uintptr(h), int64(length))))
+}
+
+//export coreVFSTell
+func coreVFSTell(h C.uintptr_t) C.int64_t {
+	return coreVFSSeek(h, 0, io.SeekCurrent)
+}
+
+//export coreVFSSeek
+func coreVFSSeek(h C.uintptr_t, offset C.int64_t, whence C.int) C.int64_t {
+	if vfs == nil {
+		return -1
+	}
+	pos, err := vfs.Seek(uintptr(h), int64(offset), int(whence))
+	if err != nil {
+		return -1
+	}
+	return C.int64_t(pos)
+}
+
+//export coreVFSRead
+func coreVFSRead(h C.uintptr_t, s unsafe.Pointer, length C.uint64_t) C.int64_t {
+	if vfs == nil {
+		return -1
+	}
+	if length == 0 {
+		return 0
+	}
+	n, err := io.ReadFull(vfsReader{uintptr(h)}, unsafe.Slice((*byte)(s), int(length)))
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return -1
+	}
+	return C.int64_t(n)
+}
+
+//export coreVFSWrite
+func coreVFSWrite(h C.uintptr_t, s unsafe.Pointer, length C.uint64_t) C.int64_t {
+	if vfs == nil {
+		return -1
+	}
+	if length == 0 {
+		return 0
+	}
+	n, err := vfs.Write(uintptr(h), unsafe.Slice((*byte)(s), int(length)))
+	if err != nil {
+		return -1
+	}
+	return C.int64_t(n)
+}
+
+//export coreVFSFlush
+func coreVFSFlush(h C.uintptr_t) C.int {
+	if vfs == nil {
+		return -1
+	}
+	return vfsCode(vfs.Flush(uintptr(h)))
+}
+
+//export coreVFSRemove
+func coreVFSRemove(path *C.char) C.int {
+	if vfs == nil {
+		return -1
+	}
+	return vfsCode(vfs.Remove(C.GoString(path)))
+}
+
+//export coreVFSRename
+func coreVFSRename(oldpath, newpath *C.char) C.int {
+	if vfs == nil {
+		return -1
+	}
+	return vfsCode(vfs.Rename(C.GoString(oldpath), C.GoString(newpath)))
+}
+
+//export coreVFSStat
+func coreVFSStat(path *C.char, size *C.int32_t) C.int {
+	if vfs == nil {
+		return 0
+	}
+	fi, err := vfs.Stat(C.GoString(path))
+	if err != nil {
+		return 0
+	}
+	if size != nil {
+		*size = C.int32_t(fi.Size())
+	}
+	flags := C.int(C.RETRO_VFS_STAT_IS_VALID)
+	if fi.IsDir() {
+		flags |= C.RETRO_VFS_STAT_IS_DIRECTORY
+	}
+	if fi.Mode()&fs.ModeCharDevice != 0 {
+		flags |= C.RETRO_VFS_STAT_IS_CHARACTER_SPECIAL
+	}
+	return flags
+}
+
+//export coreVFSMkdir
+func coreVFSMkdir(dir *C.char) C.int {
+	if vfs == nil {
+		return -1
+	}
+	err := vfs.Mkdir(C.GoString(dir))
+	if errors.Is(err, fs.ErrExist) {
+		return -2
+	}
+	return vfsCode(err)
+}
+
+//export coreVFSOpenDir
+func coreVFSOpenDir(dir *C.char, hidden C.bool) C.uintptr_t {
+	if vfs == nil {
+		return 0
+	}
+	h, err := vfs.OpenDir(C.GoString(dir), bool(hidden))
+	if err != nil {
+		return 0
+	}
+	return C.uintptr_t(h)
+}
+
+//export coreVFSReadDir
+func coreVFSReadDir(h C.uintptr_t) C.bool {
+	if vfs == nil {
+		return false
+	}
+	entry, err := vfs.ReadDir(uintptr(h))
+	vfsMu.Lock()
+	defer vfsMu.Unlock()
+	freeDirent(uintptr(h))
+	if err != nil {
+		return false
+	}
+	vfsDirents[uintptr(h)] = &vfsDirent{name: C.CString(entry.Name()), dir: entry.IsDir()}
+	return true
+}
+
+//export coreVFSDirentGetName
+func coreVFSDirentGetName(h C.uintptr_t) *C.char {
+	vfsMu.Lock()
+	defer vfsMu.Unlock()
+	if d, ok := vfsDirents[uintptr(h)]; ok {
+		return d.name
+	}
+	return nil
+}
+
+//export coreVFSDirentIsDir
+func coreVFSDirentIsDir(h C.uintptr_t) C.bool {
+	vfsMu.Lock()
+	defer vfsMu.Unlock()
+	if d, ok := vfsDirents[uintptr(h)]; ok {
+		return C.bool(d.dir)
+	}
+	return false
+}
+
+//export coreVFSCloseDir
+func coreVFSCloseDir(h C.uintptr_t) C.int {
+	vfsMu.Lock()
+	freeDirent(uintptr(h))
+	vfsMu.Unlock()
+	if vfs == nil {
+		return -1
+	}
+	return vfsCode(vfs.CloseDir(uintptr(h)))
+}
+
+// freeDirent releases the name of the last entry read from a directory.
+// vfsMu must be held.
+func freeDirent(h uintptr) {
+	if d, ok := vfsDirents[h]; ok {
+		C.free(unsafe.Pointer(d.name))
+		delete(vfsDirents, h)
+	}
+}
+
+// vfsReader reads a file of the VFS, so reads can be retried until the
+// buffer of the core is full
+type vfsReader struct {
+	h uintptr
+}
+
+func (r vfsReader) Read(p []byte) (int, error) {
+	return vfs.Read(r.h, p)
+}
@@ -0,0 +1,93 @@
+package ludo
+
+import (
+	"image"
+	"os"
+
+	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/video"
+)
+
+// HeadlessConfig describes a game run without window, audio device nor
+// players
+type HeadlessConfig struct {
+	CorePath string
+	GamePath string       // empty to start the core without a game
+	Frames   int          // number of frames to run
+	Script   input.Script // inputs of the players, can be nil
+	Movie    string       // movie file played back over the script, optional
+	Record   string       // file to record the inputs to, optional
+}
+
+// HeadlessResult is what the game showed and played during a headless run
+type HeadlessResult struct {
+	Frame      *image.RGBA // last frame, nil if the core didn't render any
+	Audio      []byte      // interleaved signed 16-bit stereo PCM
+	SampleRate int32
+}
+
+// RunHeadless runs a game for a number of frames and returns its last frame
+// and its audio. It needs neither a GPU nor an audio device, so every game
+// and core can be regression-tested on CI machines. Games start without SRAM
+// and nothing is saved, to keep runs reproducible. A movie going out of sync
+// during the run is reported as an error.
+func RunHeadless(cfg HeadlessConfig) (*HeadlessResult, error) {
+	saves, err := os.MkdirTemp("", "ludo-headless")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(saves)
+
+	savefiles, savestates := settings.Current.SavefilesDirectory, settings.Current.SavestatesDirectory
+	settings.Current.SavefilesDirectory, settings.Current.SavestatesDirectory = saves, saves
+	defer func() {
+		settings.Current.SavefilesDirectory, settings.Current.SavestatesDirectory = savefiles, savestates
+	}()
+
+	vid := video.NewHeadless()
+	audio.Capture()
+	core.Init(vid)
+	input.Init(vid)
+
+	if err := core.Load(cfg.CorePath); err != nil {
+		return nil, err
+	}
+	defer core.Unload()
+
+	if err := loadContent(cfg.GamePath); err != nil {
+		return nil, err
+	}
+	defer core.UnloadGame()
+
+	if cfg.Movie != "" {
+		if err := core.PlayMovie(cfg.Movie); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Record != "" {
+		if err := core.RecordMovie(cfg.Record); err != nil {
+			return nil, err
+		}
+	}
+
+	for f := 0; f < cfg.Frames; f++ {
+		input.NewState = cfg.Script.State(f)
+		core.RunFrame()
+		if state.Core.FrameTimeCallback != nil {
+			state.Core.FrameTimeCallback.Callback(state.Core.FrameTimeCallback.Reference)
+		}
+		if state.Core.AudioCallback != nil {
+			state.Core.AudioCallback.Callback()
+		}
+	}
+	if err := core.StopMovie(); err != nil {
+		return nil, err
+	}
+
+	pcm, rate := audio.Captured()
+	return &HeadlessResult{Frame: vid.Frame(), Audio: pcm, SampleRate: rate}, nil
+}
@@ -0,0 +1,62 @@
+package ludo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/utils"
+)
+
+func TestRunHeadless(t *testing.T) {
+	script, err := input.ParseScript(strings.NewReader("30 1 start\n32 1 -\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := RunHeadless(HeadlessConfig{
+		CorePath: "../core/testdata/vecx_libretro" + utils.CoreExt(),
+		GamePath: "../core/testdata/Polar Rescue (USA).vec",
+		Frames:   120,
+		Script:   script,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Captures the last frame", func(t *testing.T) {
+		if res.Frame == nil {
+			t.Fatalf("got = %v, want a frame", res.Frame)
+		}
+		b := res.Frame.Bounds()
+		if b.Dx() == 0 || b.Dy() == 0 {
+			t.Errorf("got = %v, want a non empty frame", b)
+		}
+		lit := false
+		for i := 0; i < len(res.Frame.Pix); i += 4 {
+			if res.Frame.Pix[i] != 0 || res.Frame.Pix[i+1] != 0 || res.Frame.Pix[i+2] != 0 {
+				lit = true
+				break
+			}
+		}
+		if !lit {
+			t.Errorf("got = a black frame, want the game")
+		}
+	})
+
+	t.Run("Captures the audio", func(t *testing.T) {
+		if len(res.Audio) == 0 || res.SampleRate == 0 {
+			t.Errorf("got = %v bytes at %v Hz, want some audio", len(res.Audio), res.SampleRate)
+		}
+	})
+}
+
+func TestRunHeadlessNoGame(t *testing.T) {
+	_, err := RunHeadless(HeadlessConfig{
+		CorePath: "../core/testdata/vecx_libretro" + utils.CoreExt(),
+		Frames:   10,
+	})
+	if err == nil || err.Error() != "the core needs a game" {
+		t.Errorf("got = %v, want %v", err, "the core needs a game")
+	}
+}
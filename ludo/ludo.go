@@ -10,6 +10,7 @@ import (
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/libretro/ludo/audio"
 	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/coreinfo"
 	"github.com/libretro/ludo/history"
 	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/menu"
@@ -29,11 +30,14 @@ func init() {
 
 var frame = 0
 
-// timerOverlay holds the remaining time and a mutex for thread safety.
+// SecondsPerMinute converts the minutes bought by a player into seconds of
+// play
+const SecondsPerMinute = 60
+
+// timerOverlay tells if the seat timers are drawn, with a mutex for thread safety.
 type timerOverlay struct {
-	remaining int
-	mu        sync.RWMutex
-	visible   bool
+	mu      sync.RWMutex
+	visible bool
 }
 
 var globalTimerOverlay = &timerOverlay{}
@@ -56,14 +60,15 @@ func isGLFWInitialized() bool {
 	return glfwInitialized
 }
 
-// drawTimerOverlay draws the timer in the top-right corner, small, always visible, transparent background.
+// drawTimerOverlay draws the timers in the top-right corner, small, always visible, transparent background.
+// Multiplayer sessions get one timer per seat, stacked and prefixed with the player number.
 func drawTimerOverlay(vid *video.Video) {
 	globalTimerOverlay.mu.RLock()
-	remaining := globalTimerOverlay.remaining
 	visible := globalTimerOverlay.visible
 	globalTimerOverlay.mu.RUnlock()
 
-	if !visible || remaining < 0 {
+	times := seats.billed()
+	if !visible || len(times) == 0 {
 		return
 	}
 
@@ -72,18 +77,35 @@ func drawTimerOverlay(vid *video.Video) {
 	padding := 32 * ratio
 	bgW := 120 * ratio // much smaller width
 	bgH := 36 * ratio  // much smaller height
+	if len(times) > 1 {
+		bgW = 170 * ratio
+	}
 	x := float32(w) - bgW - padding
 	y := padding
 
-	// Transparent background
-	vid.DrawRect(x, y, bgW, bgH, 6*ratio, video.Color{R: 0, G: 0, B: 0, A: 0.45})
+	for i := 0; i < input.MaxPlayers; i++ {
+		remaining, ok := times[i]
+		if !ok {
+			continue
+		}
+
+		// Transparent background
+		vid.DrawRect(x, y, bgW, bgH, 6*ratio, video.Color{R: 0, G: 0, B: 0, A: 0.45})
 
-	// Timer text (red, much smaller)
-	mins := remaining / 60
-	secs := remaining % 60
-	timerStr := fmt.Sprintf("%02d:%02d", mins, secs)
-	vid.Font.SetColor(video.Color{R: 1, G: 0, B: 0, A: 1})
-	vid.Font.Printf(x+18*ratio, y+7*ratio, 0.32*ratio, timerStr)
+		// Timer text (red, much smaller), greyed out once the seat is unplugged
+		timerStr := fmt.Sprintf("%02d:%02d", remaining/60, remaining%60)
+		if len(times) > 1 {
+			timerStr = fmt.Sprintf("P%d %s", i+1, timerStr)
+		}
+		c := video.Color{R: 1, G: 0, B: 0, A: 1}
+		if remaining <= 0 {
+			c = video.Color{R: 0.5, G: 0.5, B: 0.5, A: 1}
+		}
+		vid.Font.SetColor(c)
+		vid.Font.Printf(x+18*ratio, y+7*ratio, 0.32*ratio, timerStr)
+
+		y += bgH + 8*ratio
+	}
 }
 
 func runLoop(vid *video.Video, m *menu.Menu) {
@@ -104,7 +126,8 @@ func runLoop(vid *video.Video, m *menu.Menu) {
 
 		if !state.MenuActive {
 			if state.CoreRunning {
-				state.Core.Run()
+				applySeatDevices()
+				core.RunFrame()
 				if state.Core.FrameTimeCallback != nil {
 					state.Core.FrameTimeCallback.Callback(state.Core.FrameTimeCallback.Reference)
 				}
@@ -140,9 +163,25 @@ func runLoop(vid *video.Video, m *menu.Menu) {
 	}
 }
 
+// loadContent loads a game on the current core, or starts the core without a
+// game when gamePath is empty
+func loadContent(gamePath string) error {
+	if gamePath == "" {
+		return core.LoadNoGame()
+	}
+	return core.LoadGame(gamePath)
+}
+
 // RunGame launches the given core+game and shows a "TIME LEFT: mm:ss" overlay
-// in the top-right corner of the Ludo window. When countdown hits zero, window is paused automatically.
-func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int, resumeChan chan bool) error {
+// in the top-right corner of the Ludo window. Each seat, starting from port 0,
+// is given the number of seconds its player paid for. A seat running out of
+// time is unplugged while the others keep playing, and the game is paused
+// automatically once every seat is out of time. The time bought to resume it
+// is shared between the seats paid for, which are all plugged back. The
+// cheats named in cheats, like an easy mode chosen by the operator, are
+// enabled, and achievements are unlocked for profile. The signals of the session are sent to signalChan, and the seconds
+// bought are received from timerChan.
+func RunGame(corePath, gamePath string, cheats []string, profile string, seatSeconds []int, signalChan, timerChan chan int, resumeChan chan bool) error {
 	// Ensure we're running on a locked OS thread
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
@@ -172,6 +211,7 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 
 	playlists.Load()
 	history.Load()
+	coreinfo.Load()
 
 	// Force window to be visible and focused
 	vid := video.Init(true) // Force fullscreen to ensure visibility
@@ -188,23 +228,30 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 	core.Init(vid)
 	input.Init(vid)
 
+	// Rewind and other player conveniences depend on the kiosk mode
+	state.Kiosk = true
+	state.Profile = profile
+
 	// Load core and game with improved error handling
 	if err := core.Load(corePath); err != nil {
 		return fmt.Errorf("failed to load core: %w", err)
 	}
 
-	if err := core.LoadGame(gamePath); err != nil {
+	if err := loadContent(gamePath); err != nil {
 		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
 		return fmt.Errorf("failed to load game: %w", err)
 	}
+	if len(cheats) > 0 {
+		core.EnableCheats(cheats)
+	}
 
 	// Start the game immediately - don't go to quick menu
 	state.MenuActive = false
 	state.CoreRunning = true
 
-	// Initialize timer overlay
+	// Initialize seat timers and overlay
+	seats.reset(seatSeconds, settings.Current.KioskSeats)
 	globalTimerOverlay.mu.Lock()
-	globalTimerOverlay.remaining = durationSeconds
 	globalTimerOverlay.visible = true
 	globalTimerOverlay.mu.Unlock()
 
@@ -219,15 +266,21 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 
 	// Signal that the game is loaded and ready
 	log.Println("Game fully loaded, sending confirmation signal")
-	timerChan <- -999 // Special signal for game loaded
+	signalChan <- SignalGameLoaded
 
 	// Create channels for timer management
 	doneChan := make(chan struct{})
 	gameExitedChan := make(chan struct{})
 
+	// Free minutes from vouchers entered in the menu
+	bonusChan := make(chan int, 10)
+	menu.AddPlayTime = func(minutes int) {
+		bonusChan <- minutes * SecondsPerMinute
+	}
+	defer func() { menu.AddPlayTime = nil }()
+
 	// Timer management goroutine with cancellation support
 	go func() {
-		remaining := durationSeconds
 		ticker := time.NewTicker(1 * time.Second)
 		defer ticker.Stop()
 		prepareTimeoutSent := false
@@ -250,18 +303,32 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 					continue
 				}
 
-				remaining--
-				globalTimerOverlay.mu.Lock()
-				globalTimerOverlay.remaining = remaining
-				globalTimerOverlay.mu.Unlock()
+				expired, remaining := seats.tick()
 
 				// Send prepare timeout signal 10 seconds before actual timeout
+				if remaining > 10 {
+					prepareTimeoutSent = false
+				}
 				if remaining == 10 && !prepareTimeoutSent {
 					log.Println("Sending prepare timeout signal (10 seconds remaining)")
-					timerChan <- -2 // -2 is the prepare timeout signal
+					signalChan <- SignalPrepareTimeout
 					prepareTimeoutSent = true
 				}
 
+				// Seats running out of time are unplugged while the others keep
+				// playing. When nobody has time left, the first seat stays plugged
+				// and goes through the timeout flow below.
+				for _, seat := range expired {
+					if remaining <= 0 && seat == 0 {
+						continue
+					}
+					log.Printf("Seat %d is out of time, unplugging it", seat)
+					seats.unplug(seat)
+					if remaining > 0 {
+						signalChan <- SeatExpiredSignal(seat)
+					}
+				}
+
 				if remaining <= 0 {
 					// Check if GLFW is still initialized before accessing window
 					if !isGLFWInitialized() {
@@ -279,13 +346,13 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 					log.Printf("Game timeout! Pausing game at window position: %d,%d %dx%d", xpos, ypos, width, height)
 
 					// Signal timeout to UI with window information
-					timerChan <- -1 // -1 is the timeout signal
+					signalChan <- SignalTimeout
 
 					// Send window information through the same channel
-					timerChan <- xpos
-					timerChan <- ypos
-					timerChan <- width
-					timerChan <- height
+					signalChan <- xpos
+					signalChan <- ypos
+					signalChan <- width
+					signalChan <- height
 
 					// Pause the game but keep window visible
 					state.MenuActive = true
@@ -307,14 +374,9 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 						select {
 						case newDuration := <-timerChan:
 							log.Printf("New timer duration received: %d seconds", newDuration)
-							remaining = newDuration
+							seats.extend(newDuration)
 							prepareTimeoutSent = false // Reset for next cycle
 
-							// Update overlay
-							globalTimerOverlay.mu.Lock()
-							globalTimerOverlay.remaining = remaining
-							globalTimerOverlay.mu.Unlock()
-
 							// Resume game and ensure focus (safely)
 							log.Println("Resuming game...")
 							state.MenuActive = false
@@ -329,11 +391,11 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 								log.Println("Cannot focus window: GLFW not initialized")
 							}
 
-							log.Printf("Game resumed with %d seconds remaining", remaining)
+							log.Printf("Game resumed with %d seconds remaining", newDuration)
 
 						case <-time.After(5 * time.Second):
 							log.Println("Timeout waiting for new duration, using default value")
-							remaining = 60 // Default to 60 seconds if no value received
+							seats.extend(60) // Default to 60 seconds if no value received
 							gamePaused = false
 						}
 
@@ -346,14 +408,15 @@ func RunGame(corePath, gamePath string, durationSeconds int, timerChan chan int,
 					}
 				}
 
+			case bonus := <-bonusChan:
+				log.Printf("Voucher redeemed: adding %d seconds", bonus)
+				seats.add(0, bonus)
+
 			case newDuration := <-timerChan:
 				// Handle additional time being added (not during timeout)
 				if newDuration > 0 {
 					log.Printf("Timer updated: adding %d seconds", newDuration)
-					remaining += newDuration // Add to existing time instead of replacing
-					globalTimerOverlay.mu.Lock()
-					globalTimerOverlay.remaining = remaining
-					globalTimerOverlay.mu.Unlock()
+					seats.extend(newDuration) // Add to existing time instead of replacing
 				}
 			}
 		}
@@ -0,0 +1,186 @@
+package ludo
+
+import (
+	"log"
+	"sync"
+
+	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/state"
+)
+
+// Signals sent to the frontend through the signal channel
+const (
+	SignalTimeout        = -1   // Every seat is out of time, the game is paused
+	SignalPrepareTimeout = -2   // The game will pause in 10 seconds
+	SignalGameLoaded     = -999 // The game is running
+	signalSeatExpired    = -100 // Minus the seat index, see SeatExpiredSignal
+)
+
+// SeatExpiredSignal is sent when a seat runs out of time while other players
+// keep playing. The seat index is folded into the value so that it can't be
+// separated from the signal by another reader of the channel.
+func SeatExpiredSignal(seat int) int {
+	return signalSeatExpired - seat
+}
+
+// ExpiredSeat decodes a signal built by SeatExpiredSignal
+func ExpiredSeat(signal int) (int, bool) {
+	seat := signalSeatExpired - signal
+	if seat < 0 || seat >= input.MaxPlayers {
+		return 0, false
+	}
+	return seat, true
+}
+
+// seat is the billing state of a controller port. Each player of a
+// multiplayer cabinet pays for their own time.
+type seat struct {
+	remaining int  // Seconds of play left
+	billed    bool // The seat has been paid for during this session
+	unplugged bool // The port has been disconnected from the core
+}
+
+// seatTable holds the timers of all the seats. Port changes are queued here
+// and applied by the main thread, between two frames.
+type seatTable struct {
+	sync.Mutex
+	seats   [input.MaxPlayers]seat
+	pending map[int]bool // port -> plugged
+}
+
+var seats = &seatTable{}
+
+// reset starts a session where each seat has paid for the given seconds. On
+// a cabinet with more seats than that, the seats nobody paid for stay
+// unplugged until a player buys time for them.
+func (t *seatTable) reset(durations []int, cabinetSeats int) {
+	t.Lock()
+	defer t.Unlock()
+	t.seats = [input.MaxPlayers]seat{}
+	t.pending = map[int]bool{}
+	for i := range t.seats {
+		switch {
+		case i < len(durations):
+			t.seats[i] = seat{remaining: durations[i], billed: true}
+			t.pending[i] = true // may have been unplugged in a previous session
+		case i < cabinetSeats:
+			t.seats[i] = seat{unplugged: true}
+			t.pending[i] = false
+		}
+	}
+}
+
+// tick counts down one second on every seat still playing. It returns the
+// seats that just ran out of time, and the longest time left on a seat.
+func (t *seatTable) tick() (expired []int, left int) {
+	t.Lock()
+	defer t.Unlock()
+	for i := range t.seats {
+		s := &t.seats[i]
+		if !s.billed || s.remaining <= 0 {
+			continue
+		}
+		s.remaining--
+		if s.remaining == 0 {
+			expired = append(expired, i)
+		}
+		if s.remaining > left {
+			left = s.remaining
+		}
+	}
+	return
+}
+
+// add credits time to a seat. A seat that was unplugged for lack of time is
+// plugged back.
+func (t *seatTable) add(i, secs int) {
+	if i < 0 || i >= input.MaxPlayers || secs <= 0 {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	s := &t.seats[i]
+	s.billed = true
+	s.remaining += secs
+	if s.unplugged {
+		s.unplugged = false
+		t.pending[i] = true
+	}
+}
+
+// extend shares time bought for the whole group, after a timeout, between
+// the seats paid for during the session. They are all plugged back.
+func (t *seatTable) extend(secs int) {
+	t.Lock()
+	players := []int{}
+	for i, s := range t.seats {
+		if s.billed {
+			players = append(players, i)
+		}
+	}
+	t.Unlock()
+	if len(players) == 0 {
+		players = []int{0}
+	}
+	for n, i := range players {
+		share := secs / len(players)
+		if n < secs%len(players) {
+			share++
+		}
+		t.add(i, share)
+	}
+}
+
+// unplug disconnects a seat that ran out of time while others keep playing
+func (t *seatTable) unplug(i int) {
+	t.Lock()
+	defer t.Unlock()
+	if t.seats[i].unplugged {
+		return
+	}
+	t.seats[i].unplugged = true
+	t.pending[i] = false
+}
+
+// billed returns the time left on every seat paid for during the session
+func (t *seatTable) billed() map[int]int {
+	t.Lock()
+	defer t.Unlock()
+	times := map[int]int{}
+	for i, s := range t.seats {
+		if s.billed {
+			times[i] = s.remaining
+		}
+	}
+	return times
+}
+
+// applySeatDevices tells the core about the ports plugged or unplugged since
+// the last frame. It must run on the main thread.
+func applySeatDevices() {
+	seats.Lock()
+	pending := seats.pending
+	seats.pending = map[int]bool{}
+	seats.Unlock()
+
+	for port, plugged := range pending {
+		device := libretro.DeviceNone
+		if plugged {
+			device = core.PortDevice(port)
+		}
+		log.Printf("[Seats]: Port %d plugged: %t", port, plugged)
+		input.Unplugged[port] = !plugged
+		if state.Core != nil {
+			state.Core.SetControllerPortDevice(uint(port), device)
+		}
+	}
+}
+
+// AddSeatTime credits seconds of play to a seat of the running session, for
+// example when the second player of a cabinet inserts a coin.
+func AddSeatTime(seat, secs int) {
+	log.Printf("[Seats]: Adding %d seconds to seat %d", secs, seat)
+	seats.add(seat, secs)
+}
@@ -0,0 +1,85 @@
+package ludo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_seatTable(t *testing.T) {
+	t.Run("Unplugs the seats nobody paid for", func(t *testing.T) {
+		s := &seatTable{}
+		s.reset([]int{10}, 2)
+		want := map[int]bool{0: true, 1: false}
+		if !reflect.DeepEqual(s.pending, want) {
+			t.Errorf("got = %v, want %v", s.pending, want)
+		}
+	})
+
+	t.Run("Reports seats running out of time", func(t *testing.T) {
+		s := &seatTable{}
+		s.reset([]int{1, 3}, 2)
+		expired, left := s.tick()
+		if !reflect.DeepEqual(expired, []int{0}) || left != 2 {
+			t.Errorf("got = %v %v, want %v %v", expired, left, []int{0}, 2)
+		}
+		expired, left = s.tick()
+		if expired != nil || left != 1 {
+			t.Errorf("got = %v %v, want %v %v", expired, left, nil, 1)
+		}
+	})
+
+	t.Run("Plugs a seat back when time is added", func(t *testing.T) {
+		s := &seatTable{}
+		s.reset([]int{1, 5}, 2)
+		s.tick()
+		s.unplug(0)
+		s.pending = map[int]bool{}
+		s.add(0, 4)
+		want := map[int]bool{0: true}
+		if !reflect.DeepEqual(s.pending, want) {
+			t.Errorf("got = %v, want %v", s.pending, want)
+		}
+		got := s.billed()
+		wantTimes := map[int]int{0: 4, 1: 4}
+		if !reflect.DeepEqual(got, wantTimes) {
+			t.Errorf("got = %v, want %v", got, wantTimes)
+		}
+	})
+
+	t.Run("Shares an extension between the seats that were playing", func(t *testing.T) {
+		s := &seatTable{}
+		s.reset([]int{1, 2, 1}, 4)
+		s.tick()
+		s.unplug(2)
+		s.tick()
+		s.unplug(1)
+		s.pending = map[int]bool{}
+		s.extend(7)
+		want := map[int]bool{1: true, 2: true}
+		if !reflect.DeepEqual(s.pending, want) {
+			t.Errorf("got = %v, want %v", s.pending, want)
+		}
+		got := s.billed()
+		wantTimes := map[int]int{0: 3, 1: 2, 2: 2}
+		if !reflect.DeepEqual(got, wantTimes) {
+			t.Errorf("got = %v, want %v", got, wantTimes)
+		}
+	})
+}
+
+func TestExpiredSeat(t *testing.T) {
+	t.Run("Decodes seat signals", func(t *testing.T) {
+		got, ok := ExpiredSeat(SeatExpiredSignal(1))
+		if !ok || got != 1 {
+			t.Errorf("got = %v %v, want %v %v", got, ok, 1, true)
+		}
+	})
+
+	t.Run("Ignores other signals and durations", func(t *testing.T) {
+		for _, signal := range []int{SignalTimeout, SignalPrepareTimeout, SignalGameLoaded, 0, 120} {
+			if _, ok := ExpiredSeat(signal); ok {
+				t.Errorf("signal %d decoded as a seat", signal)
+			}
+		}
+	})
+}
@@ -1,15 +1,36 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
-	"time"
 
+	"github.com/libretro/ludo/catalog"
+	"github.com/libretro/ludo/coreinfo"
+	"github.com/libretro/ludo/session"
+	"github.com/libretro/ludo/settings"
+	ui "github.com/libretro/ludo/ui-wrapper"
 	"github.com/libretro/ludo/webui"
 )
 
 func main() {
+	// ludo run plays a single game, without the kiosk
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(run(os.Args[2:]))
+	}
+
+	frontend := flag.String("frontend", "web", "Kiosk frontend: web (browser) or fyne (native window)")
+	flag.Parse()
+
+	// The web UI needs the settings (e.g. the voucher secret) before any game runs
+	if err := settings.Load(); err != nil {
+		fmt.Println("Failed to load settings, using defaults:", err)
+	}
+	// The catalog hides the games of cores missing their firmware
+	coreinfo.Load()
+
 	// Determine the appropriate cores directory based on architecture
 	var coresDir string
 	switch runtime.GOARCH {
@@ -26,72 +47,43 @@ func main() {
 	}
 
 	// ==============================
-	// 1) Prepare your game‐core map
+	// 1) Prepare the kiosk catalog
 	// ==============================
-	games := map[string]string{
-		"Nova":            "games/nova.nes",
-		"Super Adventure": "games/nova.nes",
-		"Pixel Quest":     "games/nova.nes",
-		"Retro Hero":      "games/nova.nes",
-		"Classic Journey": "games/nova.nes",
+	nestopia := filepath.Join(coresDir, "nestopia_libretro.so")
+	games := catalog.Catalog{
+		{Name: "Nova", GamePath: "games/nova.nes", CorePath: nestopia, ImagePath: "assets/spets/games/nova.png", Rating: catalog.AllAges},
+		{Name: "Super Adventure", GamePath: "games/nova.nes", CorePath: nestopia, ImagePath: "assets/spets/games/mario.png", Rating: catalog.AllAges},
+		{Name: "Pixel Quest", GamePath: "games/nova.nes", CorePath: nestopia, ImagePath: "assets/spets/games/nova.png", Rating: catalog.Age7},
+		{Name: "Retro Hero", GamePath: "games/nova.nes", CorePath: nestopia, ImagePath: "assets/spets/games/nova.png", Rating: catalog.Age12, Tags: []string{"violence"}},
+		{Name: "Classic Journey", GamePath: "games/nova.nes", CorePath: nestopia, ImagePath: "assets/spets/games/nova.png", Rating: catalog.AllAges},
 		// Add more games here
 	}
 
-	// Build core paths using the determined cores directory
-	cores := map[string]string{
-		"Nova":            filepath.Join(coresDir, "nestopia_libretro.so"),
-		"Super Adventure": filepath.Join(coresDir, "nestopia_libretro.so"),
-		"Pixel Quest":     filepath.Join(coresDir, "nestopia_libretro.so"),
-		"Retro Hero":      filepath.Join(coresDir, "nestopia_libretro.so"),
-		"Classic Journey": filepath.Join(coresDir, "nestopia_libretro.so"),
-		// Add corresponding cores here
-	}
-
-	// Add paths to game pictures
-	gamePictures := map[string]string{
-		"Nova":            "assets/spets/games/nova.png",
-		"Super Adventure": "assets/spets/games/mario.png",
-		"Pixel Quest":     "assets/spets/games/nova.png",
-		"Retro Hero":      "assets/spets/games/nova.png",
-		"Classic Journey": "assets/spets/games/nova.png",
-		// Add corresponding picture paths here
-	}
-
-	// Channels for communication between Web UI and game logic
+	// Channels for communication between the frontend and game logic
+	signalChan := make(chan int, 10)
 	timerChan := make(chan int, 10)
 	resumeChan := make(chan bool, 10)
 
-	// Create the web server
-	server := webui.NewServer(games, cores, gamePictures, timerChan, resumeChan)
+	// The session drives the kiosk flow, the frontend only renders it
+	controller := session.NewController(games, signalChan, timerChan, resumeChan)
 
-	// Monitor timer events and game loading using a ticker
-	ticker := time.NewTicker(100 * time.Millisecond)
-	go func() {
-		defer ticker.Stop()
-		for range ticker.C {
-			select {
-			case signal := <-timerChan:
-				if signal == -1 {
-					// Timer expired, tell the server to handle it
-					fmt.Println("Main: Received timer expired signal (-1)")
-					server.HandleTimeout()
-				} else if signal == -999 {
-					// Special signal indicating game is loaded
-					fmt.Println("Main: Received game loaded signal")
-					server.OnGameLoaded()
-				} else if signal == -2 {
-					// Signal to prepare for timeout (10 seconds remaining)
-					fmt.Println("Main: Received prepare timeout signal (-2)")
-					server.PrepareTimeout()
-				}
-			default:
-				// No signal, continue
-			}
-		}
-	}()
+	switch *frontend {
+	case "fyne":
+		w := ui.NewUI(controller)
+		controller.SetFrontend(w)
+		go controller.Watch()
+		w.Run()
+	case "web":
+		server := webui.NewServer(controller)
+		controller.SetFrontend(server)
+		go controller.Watch()
 
-	// Start the web server on port 8080 - this will also launch the browser
-	if err := server.Start(":8080"); err != nil {
-		fmt.Printf("Failed to start web server: %v\n", err)
+		// Start the web server on port 8080 - this will also launch the browser
+		if err := server.Start(":8080"); err != nil {
+			fmt.Printf("Failed to start web server: %v\n", err)
+		}
+	default:
+		fmt.Printf("Unknown frontend %q, expected web or fyne\n", *frontend)
+		os.Exit(1)
 	}
 }
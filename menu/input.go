@@ -2,6 +2,7 @@ package menu
 
 import (
 	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/core"
 	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/libretro"
 	ntf "github.com/libretro/ludo/notifications"
@@ -204,6 +205,11 @@ func (m *Menu) ProcessHotkeys() {
 		}
 	}
 
+	state.Rewind = input.NewState[0][input.ActionRewind] == 1 && !state.MenuActive
+	if input.Pressed[0][input.ActionRewind] == 1 && !state.MenuActive && !core.RewindEnabled() {
+		ntf.DisplayAndLog(ntf.Warning, "Menu", "Rewind is disabled")
+	}
+
 	if input.Pressed[0][input.ActionFastForwardToggle] == 1 && !state.MenuActive {
 		state.FastForward = !state.FastForward
 		if state.FastForward {
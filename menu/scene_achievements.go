@@ -0,0 +1,73 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libretro/ludo/achievements"
+	"github.com/libretro/ludo/core"
+)
+
+type sceneAchievements struct {
+	entry
+}
+
+func buildAchievements() Scene {
+	var list sceneAchievements
+	list.label = "Achievements"
+
+	for _, a := range core.Achievements() {
+		id, points := a.ID, a.Points
+		list.children = append(list.children, entry{
+			label: strings.Replace(a.Title, "%", "%%", -1),
+			icon:  "subsetting",
+			stringValue: func() string {
+				for _, s := range core.Achievements() {
+					if s.ID == id && s.State == achievements.Unlocked {
+						return "Unlocked"
+					}
+				}
+				return fmt.Sprintf("%d points", points)
+			},
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "No achievements",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneAchievements) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneAchievements) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneAchievements) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneAchievements) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneAchievements) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneAchievements) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneAchievements) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -0,0 +1,85 @@
+package menu
+
+import (
+	"strings"
+
+	"github.com/libretro/ludo/core"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneCheats struct {
+	entry
+}
+
+func buildCheats() Scene {
+	var list sceneCheats
+	list.label = "Cheats"
+
+	for i, c := range core.Cheats {
+		// RAM cheats Ludo can't apply are only kept in the file
+		if c.Unsupported {
+			continue
+		}
+		index := i
+		list.children = append(list.children, entry{
+			label: strings.Replace(c.Desc, "%", "%%", -1),
+			icon:  "subsetting",
+			value: func() interface{} {
+				return core.Cheats[index].Enabled
+			},
+			widget: widgets["switch"],
+			callbackOK: func() {
+				if err := core.ToggleCheat(index); err != nil {
+					ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+				}
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneCheats) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneCheats) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneCheats) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneCheats) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneCheats) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneCheats) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneCheats) drawHintBar() {
+	w, h := menu.GetFramebufferSize()
+
+	menu.DrawRect(0, float32(h)-88*menu.ratio, float32(w), 88*menu.ratio, 0, hintBgColor)
+	menu.DrawRect(0, float32(h)-88*menu.ratio, float32(w), 2*menu.ratio, 0, sepColor)
+
+	_, upDown, _, a, b, _, _, _, _, guide := hintIcons()
+
+	lstack := float32(75) * menu.ratio
+	rstack := float32(w) - 96*menu.ratio
+	stackHintLeft(&lstack, upDown, "Navigate", h)
+	stackHintRight(&rstack, a, "Toggle", h)
+	stackHintRight(&rstack, b, "Back", h)
+	if state.CoreRunning {
+		stackHintRight(&rstack, guide, "Resume", h)
+	}
+}
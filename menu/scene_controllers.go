@@ -0,0 +1,85 @@
+package menu
+
+import (
+	"fmt"
+
+	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneControllers struct {
+	entry
+}
+
+func buildControllers() Scene {
+	var list sceneControllers
+	list.label = "Controllers"
+
+	for port := 0; port < input.MaxPlayers; port++ {
+		port := port
+		list.children = append(list.children, entry{
+			label: fmt.Sprintf("Port %d", port+1),
+			icon:  "subsetting",
+			stringValue: func() string {
+				return core.PortDeviceName(port)
+			},
+			incr: func(direction int) {
+				devices := core.PortDevices(port)
+				i := 0
+				for j, d := range devices {
+					if d.ID == core.PortDevice(port) {
+						i = j
+					}
+				}
+				i = (i + direction + len(devices)) % len(devices)
+				core.SetPortDevice(port, devices[i].ID)
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneControllers) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneControllers) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneControllers) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneControllers) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneControllers) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneControllers) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneControllers) drawHintBar() {
+	w, h := menu.GetFramebufferSize()
+	menu.DrawRect(0, float32(h)-88*menu.ratio, float32(w), 88*menu.ratio, 0, hintBgColor)
+	menu.DrawRect(0, float32(h)-88*menu.ratio, float32(w), 2*menu.ratio, 0, sepColor)
+
+	_, upDown, leftRight, _, b, _, _, _, _, guide := hintIcons()
+
+	lstack := float32(75) * menu.ratio
+	rstack := float32(w) - 96*menu.ratio
+	stackHintLeft(&lstack, upDown, "Navigate", h)
+	stackHintRight(&rstack, leftRight, "Set", h)
+	stackHintRight(&rstack, b, "Back", h)
+	if state.CoreRunning {
+		stackHintRight(&rstack, guide, "Resume", h)
+	}
+}
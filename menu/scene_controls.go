@@ -0,0 +1,72 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneControls struct {
+	entry
+}
+
+// buildControls lists what each button does in the running game, as
+// described by the core
+func buildControls() Scene {
+	var list sceneControls
+	list.label = "Controls"
+
+	if state.Core == nil || len(state.Core.InputDescriptors) == 0 {
+		list.children = append(list.children, entry{
+			label: "No controls described",
+			icon:  "subsetting",
+		})
+		list.segueMount()
+		return &list
+	}
+
+	for _, d := range state.Core.InputDescriptors {
+		value := fmt.Sprintf("P%d %s", d.Port+1, input.ButtonName(d.Device, d.Index, d.ID))
+		list.children = append(list.children, entry{
+			label: strings.Replace(d.Description, "%", "%%", -1),
+			icon:  "subsetting",
+			stringValue: func() string {
+				return value
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneControls) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneControls) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneControls) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneControls) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneControls) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneControls) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneControls) drawHintBar() {
+	genericDrawHintBar()
+}
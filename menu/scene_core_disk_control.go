@@ -1,8 +1,11 @@
 package menu
 
 import (
-	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
 
+	"github.com/libretro/ludo/core"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/state"
 )
@@ -15,25 +18,27 @@ func buildCoreDiskControl() Scene {
 	var list sceneCoreDiskControl
 	list.label = "Core Disk Control"
 
-	for i := uint(0); i < state.Core.DiskControlCallback.GetNumImages(); i++ {
+	dcc := state.Core.DiskControlCallback
+	for i := uint(0); i < dcc.GetNumImages(); i++ {
 		index := i
 		list.children = append(list.children, entry{
-			label: fmt.Sprintf("Disk %d", index+1),
+			label: strings.Replace(core.DiskLabel(index), "%", "%%", -1),
 			icon:  "subsetting",
 			stringValue: func() string {
-				if index == state.Core.DiskControlCallback.GetImageIndex() {
+				if index == dcc.GetImageIndex() {
 					return "Active"
 				}
 				return ""
 			},
 			callbackOK: func() {
-				if index == state.Core.DiskControlCallback.GetImageIndex() {
+				if index == dcc.GetImageIndex() {
 					return
 				}
-				state.Core.DiskControlCallback.SetEjectState(true)
-				state.Core.DiskControlCallback.SetImageIndex(index)
-				state.Core.DiskControlCallback.SetEjectState(false)
-				ntf.DisplayAndLog(ntf.Success, "Menu", "Switched to disk %d.", index+1)
+				if err := core.SwapDisk(index); err != nil {
+					ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+					return
+				}
+				ntf.DisplayAndLog(ntf.Success, "Menu", "Switched to %s.", core.DiskLabel(index))
 				state.MenuActive = false
 			},
 		})
@@ -46,11 +51,43 @@ func buildCoreDiskControl() Scene {
 		})
 	}
 
+	if dcc.AddImageIndex != nil && dcc.ReplaceImageIndex != nil {
+		list.children = append(list.children, entry{
+			label: "Insert Disk From File",
+			icon:  "add",
+			callbackOK: func() {
+				list.segueNext()
+				dir := filepath.Dir(state.GamePath)
+				if state.GamePath == "" {
+					usr, _ := user.Current()
+					dir = usr.HomeDir
+				}
+				menu.Push(buildExplorer(dir, nil, diskExplorerCb, nil, nil))
+			},
+		})
+	}
+
 	list.segueMount()
 
 	return &list
 }
 
+// triggered when a file is picked to be inserted as a new disk
+func diskExplorerCb(path string) {
+	index, err := core.AppendDisk(path)
+	if err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+		return
+	}
+	if err := core.SwapDisk(index); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+		return
+	}
+	ntf.DisplayAndLog(ntf.Success, "Menu", "Inserted %s.", filepath.Base(path))
+	menu.WarpToQuickMenu()
+	state.MenuActive = false
+}
+
 func (s *sceneCoreDiskControl) Entry() *entry {
 	return &s.entry
 }
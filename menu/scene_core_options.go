@@ -5,54 +5,146 @@ import (
 
 	"github.com/libretro/ludo/core"
 	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/options"
 	"github.com/libretro/ludo/state"
 )
 
 type sceneCoreOptions struct {
 	entry
+	category string // key of the category listed, empty for the top level
 }
 
 func buildCoreOptions() Scene {
-	var list sceneCoreOptions
-	list.label = "Core Options"
+	return buildCoreOptionsCategory("", "Core Options")
+}
+
+func buildCoreOptionsCategory(category, label string) Scene {
+	list := &sceneCoreOptions{category: category}
+	list.label = label
+	list.children = list.entries()
 
+	list.segueMount()
+
+	return list
+}
+
+// entries lists the categories having visible options at the top level,
+// followed by the visible options of the category
+func (s *sceneCoreOptions) entries() []entry {
 	if core.Options == nil {
-		list.children = append(list.children, entry{
+		return []entry{{
 			label: "No options",
 			icon:  "subsetting",
+		}}
+	}
+
+	children := []entry{}
+
+	if s.category == "" && len(core.Options.Layers()) > 1 {
+		children = append(children, entry{
+			label: "Save To",
+			icon:  "subsetting",
+			stringValue: func() string {
+				return core.Options.SaveLayer.String()
+			},
+			incr: func(direction int) {
+				layers := core.Options.Layers()
+				i := 0
+				for j, l := range layers {
+					if l == core.Options.SaveLayer {
+						i = j
+					}
+				}
+				core.Options.SaveLayer = layers[(i+direction+len(layers))%len(layers)]
+			},
 		})
-		list.segueMount()
-		return &list
 	}
 
-	for _, v := range core.Options.Vars {
+	if s.category == "" {
+		for _, c := range core.Options.Categories {
+			c := c
+			if len(core.Options.Visible(c.Key)) == 0 {
+				continue
+			}
+			children = append(children, entry{
+				label:    strings.Replace(c.Desc, "%", "%%", -1),
+				subLabel: strings.Replace(c.Info, "%", "%%", -1),
+				icon:     "folder",
+				callbackOK: func() {
+					s.segueNext()
+					menu.Push(buildCoreOptionsCategory(c.Key, c.Desc))
+				},
+			})
+		}
+	}
+
+	for _, v := range core.Options.Visible(s.category) {
 		v := v
-		list.children = append(list.children, entry{
-			label: strings.Replace(v.Desc, "%", "%%", -1),
-			icon:  "subsetting",
+		children = append(children, entry{
+			label:    strings.Replace(v.Desc, "%", "%%", -1),
+			subLabel: strings.Replace(v.Info, "%", "%%", -1),
+			icon:     "subsetting",
 			stringValue: func() string {
-				val := v.Choices[v.Choice]
-				return strings.Replace(val, "%", "%%", -1)
+				val := strings.Replace(v.Choices[v.Choice], "%", "%%", -1)
+				if v.Layer > options.LayerCore {
+					val += " (" + v.Layer.String() + ")"
+				}
+				return val
 			},
 			incr: func(direction int) {
-				v.Choice += direction
-				if v.Choice < 0 {
-					v.Choice = len(v.Choices) - 1
-				} else if v.Choice > len(v.Choices)-1 {
-					v.Choice = 0
+				choice := v.Choice + direction
+				if choice < 0 {
+					choice = len(v.Choices) - 1
+				} else if choice > len(v.Choices)-1 {
+					choice = 0
 				}
-				core.Options.Updated = true
+				core.Options.Choose(v, choice)
 				err := core.Options.Save()
 				if err != nil {
 					ntf.DisplayAndLog(ntf.Error, "Core", "Error saving core options: %v", err.Error())
 				}
+				if core.UpdateOptionsDisplay() {
+					s.refresh()
+				}
 			},
 		})
 	}
 
-	list.segueMount()
+	if len(children) == 0 {
+		children = append(children, entry{
+			label: "No options",
+			icon:  "subsetting",
+		})
+	}
+
+	return children
+}
 
-	return &list
+// refresh rebuilds the list after the core changed the visibility of some
+// options, keeping the cursor on the same entry
+func (s *sceneCoreOptions) refresh() {
+	old := s.children
+	label := ""
+	if s.ptr < len(old) {
+		label = old[s.ptr].label
+	}
+
+	s.children = s.entries()
+	s.ptr = 0
+	for i := range s.children {
+		e := &s.children[i]
+		if e.label == label {
+			s.ptr = i
+		}
+		for _, o := range old {
+			if o.label == e.label {
+				e.yp, e.scale = o.yp, o.scale
+				e.labelAlpha, e.iconAlpha = o.labelAlpha, o.iconAlpha
+			}
+		}
+	}
+
+	genericAnimate(&s.entry)
 }
 
 func (s *sceneCoreOptions) Entry() *entry {
@@ -68,7 +160,7 @@ func (s *sceneCoreOptions) segueNext() {
 }
 
 func (s *sceneCoreOptions) segueBack() {
-	genericAnimate(&s.entry)
+	s.refresh()
 }
 
 func (s *sceneCoreOptions) update(dt float32) {
@@ -77,6 +169,25 @@ func (s *sceneCoreOptions) update(dt float32) {
 
 func (s *sceneCoreOptions) render() {
 	genericRender(&s.entry)
+	s.drawInfo()
+}
+
+// drawInfo displays the detailed description of the active entry under it
+func (s *sceneCoreOptions) drawInfo() {
+	if s.ptr >= len(s.children) || s.children[s.ptr].subLabel == "" {
+		return
+	}
+	e := s.children[s.ptr]
+	_, h := menu.GetFramebufferSize()
+	fontOffset := 64 * 0.7 * menu.ratio * 0.3
+
+	menu.ScissorStart(int32(530*menu.ratio), 0, int32(1310*menu.ratio), int32(h))
+	menu.Font.SetColor(mediumGrey.Alpha(e.subLabelAlpha))
+	menu.Font.Printf(
+		670*menu.ratio,
+		float32(h)*e.yp+fontOffset+50*menu.ratio,
+		0.4*menu.ratio, e.subLabel)
+	menu.ScissorEnd()
 }
 
 func (s *sceneCoreOptions) drawHintBar() {
@@ -0,0 +1,100 @@
+package menu
+
+import (
+	"strings"
+
+	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/coreinfo"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+type sceneCores struct {
+	entry
+}
+
+// buildCores lists the installed cores able to run a game
+func buildCores(gamePath string, cores []string) Scene {
+	var list sceneCores
+	list.label = "Select a Core"
+
+	for _, corePath := range cores {
+		corePath := corePath
+		list.children = append(list.children, entry{
+			label: strings.Replace(prettifyCoreName(utils.FileName(corePath)), "%", "%%", -1),
+			icon:  "subsetting",
+			callbackOK: func() {
+				loadGameWithCore(corePath, gamePath)
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// loadGameWithCore loads the core if needed, then the game
+func loadGameWithCore(corePath, gamePath string) {
+	if state.CorePath != corePath {
+		if err := core.Load(corePath); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Core", err.Error())
+			return
+		}
+	}
+	gameExplorerCb(gamePath)
+}
+
+// pickCore picks the core to run a game with. The current core is kept when
+// it supports the game, otherwise the compatible core is loaded, or a list of
+// the compatible cores is shown when there are several of them.
+func pickCore(gamePath string) {
+	if state.Core != nil {
+		if info, ok := coreinfo.Get(state.CorePath); !ok || info.Supports(gamePath) {
+			gameExplorerCb(gamePath)
+			return
+		}
+	}
+
+	cores := coreinfo.Compatible(gamePath)
+	switch {
+	case len(cores) == 1:
+		loadGameWithCore(cores[0], gamePath)
+	case len(cores) > 1:
+		menu.stack[len(menu.stack)-1].segueNext()
+		menu.Push(buildCores(gamePath, cores))
+	case state.Core != nil:
+		gameExplorerCb(gamePath)
+	default:
+		ntf.DisplayAndLog(ntf.Warning, "Menu", "No compatible core found.")
+	}
+}
+
+func (s *sceneCores) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneCores) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneCores) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneCores) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneCores) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneCores) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneCores) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -0,0 +1,111 @@
+package menu
+
+import (
+	"strings"
+
+	"github.com/libretro/ludo/firmware"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+type sceneFirmware struct {
+	entry
+}
+
+// firmwareLabels are the words shown for each firmware status
+var firmwareLabels = map[firmware.Status]string{
+	firmware.Present:      "Present",
+	firmware.Missing:      "Missing",
+	firmware.WrongVersion: "Wrong version",
+}
+
+// buildFirmware lists the installed cores expecting firmware, with a summary
+// of the problems of each
+func buildFirmware() Scene {
+	var list sceneFirmware
+	list.label = "Firmware"
+
+	for _, corePath := range firmware.Cores() {
+		corePath := corePath
+		list.children = append(list.children, entry{
+			label: strings.Replace(prettifyCoreName(utils.FileName(corePath)), "%", "%%", -1),
+			icon:  "subsetting",
+			stringValue: func() string {
+				if firmware.Ready(corePath, settings.Current.SystemDirectory) {
+					return "Ready"
+				}
+				return "Missing files"
+			},
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildCoreFirmware(corePath))
+			},
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "No core needs firmware",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// buildCoreFirmware shows the status of each firmware file of a core
+func buildCoreFirmware(corePath string) Scene {
+	var list sceneFirmware
+	list.label = prettifyCoreName(utils.FileName(corePath))
+
+	for _, r := range firmware.Check(corePath, settings.Current.SystemDirectory) {
+		r := r
+		label := r.Path
+		if r.Optional {
+			label += " (optional)"
+		} else if r.Group != "" {
+			label += " (or another " + r.Group + ")"
+		}
+		list.children = append(list.children, entry{
+			label: strings.Replace(label, "%", "%%", -1),
+			icon:  "subsetting",
+			stringValue: func() string {
+				return firmwareLabels[r.Status]
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneFirmware) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneFirmware) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneFirmware) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneFirmware) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneFirmware) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneFirmware) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneFirmware) drawHintBar() {
+	genericDrawHintBar()
+}
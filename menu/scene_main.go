@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/coreinfo"
 	"github.com/libretro/ludo/history"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/settings"
@@ -54,6 +55,9 @@ var prettyCoreNames = map[string]string{
 }
 
 func prettifyCoreName(in string) string {
+	if info, ok := coreinfo.Infos[in]; ok && info.DisplayName != "" {
+		return info.DisplayName
+	}
 	name, ok := prettyCoreNames[in]
 	if ok {
 		return name
@@ -97,12 +101,12 @@ func buildMainMenu() Scene {
 		label: "Load Game",
 		icon:  "subsetting",
 		callbackOK: func() {
-			if state.Core != nil {
+			if state.Core != nil || len(coreinfo.Infos) > 0 {
 				list.segueNext()
 				menu.Push(buildExplorer(
 					usr.HomeDir,
 					nil,
-					gameExplorerCb,
+					pickCore,
 					nil,
 					nil,
 				))
@@ -112,6 +116,41 @@ func buildMainMenu() Scene {
 		},
 	})
 
+	if state.Core != nil && state.Core.SupportsNoGame {
+		list.children = append(list.children, entry{
+			label: "Start Core",
+			icon:  "subsetting",
+			callbackOK: func() {
+				if err := core.LoadNoGame(); err != nil {
+					ntf.DisplayAndLog(ntf.Error, "Core", err.Error())
+					return
+				}
+				menu.WarpToQuickMenu()
+				state.MenuActive = false
+			},
+		})
+	}
+
+	if state.Core != nil && len(state.Core.Subsystems) > 0 {
+		list.children = append(list.children, entry{
+			label: "Load Subsystem",
+			icon:  "subsetting",
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildSubsystems())
+			},
+		})
+	}
+
+	list.children = append(list.children, entry{
+		label: "Firmware",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildFirmware())
+		},
+	})
+
 	if state.LudOS {
 		list.children = append(list.children, entry{
 			label: "Updater",
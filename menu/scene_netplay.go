@@ -0,0 +1,77 @@
+package menu
+
+import (
+	"github.com/libretro/ludo/core"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneNetplay struct {
+	entry
+}
+
+func buildNetplay() Scene {
+	var list sceneNetplay
+	list.label = "Netplay"
+
+	if core.NetplayActive() {
+		list.children = append(list.children, entry{
+			label: "Stop Netplay",
+			icon:  "subsetting",
+			callbackOK: func() {
+				core.StopNetplay()
+				ntf.DisplayAndLog(ntf.Info, "Netplay", "Netplay stopped.")
+				state.MenuActive = false
+			},
+		})
+	} else {
+		list.children = append(list.children, entry{
+			label: "Host Game",
+			icon:  "subsetting",
+			callbackOK: func() {
+				core.HostNetplay()
+				state.MenuActive = false
+			},
+		})
+		list.children = append(list.children, entry{
+			label: "Join LAN Game",
+			icon:  "subsetting",
+			callbackOK: func() {
+				core.JoinNetplay()
+				state.MenuActive = false
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneNetplay) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneNetplay) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneNetplay) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneNetplay) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneNetplay) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneNetplay) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneNetplay) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -1,11 +1,25 @@
 package menu
 
 import (
+	"os"
+	"strconv"
+
+	"github.com/libretro/ludo/core"
 	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
 	"github.com/libretro/ludo/utils"
+	"github.com/libretro/ludo/voucher"
 )
 
+// AddPlayTime credits free minutes to the running session. It is set by the
+// kiosk runner, and voucher codes can't be entered from the menu without it.
+var AddPlayTime func(minutes int)
+
+// maxRunAheadFrames is the highest run-ahead offered in the quick menu. More
+// frames cost more CPU and few games have more lag than that.
+const maxRunAheadFrames = 4
+
 type sceneQuick struct {
 	entry
 }
@@ -46,7 +60,7 @@ func buildQuickMenu() Scene {
 		label: "Take Screenshot",
 		icon:  "screenshot",
 		callbackOK: func() {
-			name := utils.DatedName(state.GamePath)
+			name := utils.DatedName(state.ContentPath())
 			err := menu.TakeScreenshot(name)
 			if err != nil {
 				ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
@@ -65,6 +79,51 @@ func buildQuickMenu() Scene {
 		},
 	})
 
+	list.children = append(list.children, entry{
+		label: "Run-Ahead Frames",
+		icon:  "subsetting",
+		stringValue: func() string {
+			if core.RunAheadFrames() == 0 {
+				return "Off"
+			}
+			return strconv.Itoa(core.RunAheadFrames())
+		},
+		incr: func(direction int) {
+			frames := (core.RunAheadFrames() + direction + maxRunAheadFrames + 1) % (maxRunAheadFrames + 1)
+			core.SetRunAheadFrames(frames)
+			if err := settings.Save(); err != nil {
+				ntf.DisplayAndLog(ntf.Error, "Settings", err.Error())
+			}
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Netplay",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildNetplay())
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Controllers",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildControllers())
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Controls",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildControls())
+		},
+	})
+
 	if state.Core != nil && state.Core.DiskControlCallback != nil {
 		list.children = append(list.children, entry{
 			label: "Disk Control",
@@ -76,11 +135,111 @@ func buildQuickMenu() Scene {
 		})
 	}
 
+	if len(core.Achievements()) > 0 {
+		list.children = append(list.children, entry{
+			label: "Achievements",
+			icon:  "subsetting",
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildAchievements())
+			},
+		})
+	}
+
+	// Kiosk players get the cheats chosen by the operator
+	if len(core.Cheats) > 0 && !state.Kiosk {
+		list.children = append(list.children, entry{
+			label: "Cheats",
+			icon:  "subsetting",
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildCheats())
+			},
+		})
+	}
+
+	// Movies restore savestates, which would let kiosk players skip ahead
+	if !state.Kiosk {
+		list.children = append(list.children, entry{
+			label: "Record Movie",
+			icon:  "subsetting",
+			stringValue: func() string {
+				if core.MovieRecording() {
+					return "Recording"
+				}
+				return ""
+			},
+			callbackOK: func() {
+				if core.MovieRecording() {
+					if err := core.StopMovie(); err != nil {
+						ntf.DisplayAndLog(ntf.Error, "Movie", err.Error())
+					} else {
+						ntf.DisplayAndLog(ntf.Success, "Movie", "Saved the movie.")
+					}
+					return
+				}
+				if err := core.RecordMovie(core.MoviePath()); err != nil {
+					ntf.DisplayAndLog(ntf.Error, "Movie", err.Error())
+					return
+				}
+				ntf.DisplayAndLog(ntf.Info, "Movie", "Recording the inputs.")
+				state.MenuActive = false
+			},
+		})
+	}
+
+	if _, err := os.Stat(core.MoviePath()); err == nil && !state.Kiosk {
+		list.children = append(list.children, entry{
+			label: "Play Movie",
+			icon:  "resume",
+			callbackOK: func() {
+				if err := core.PlayMovie(core.MoviePath()); err != nil {
+					ntf.DisplayAndLog(ntf.Error, "Movie", err.Error())
+					return
+				}
+				state.MenuActive = false
+				state.FastForward = false
+			},
+		})
+	}
+
+	if AddPlayTime != nil && settings.Current.VoucherSecret != "" {
+		list.children = append(list.children, entry{
+			label: "Redeem Voucher",
+			icon:  "subsetting",
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildKeyboard("Voucher code", redeemVoucher))
+			},
+		})
+	}
+
 	list.segueMount()
 
 	return &list
 }
 
+// redeemVoucher is called when a code has been typed on the virtual keyboard.
+// Only minutes vouchers make sense here, discounts apply at checkout.
+func redeemVoucher(code string) {
+	secret := []byte(settings.Current.VoucherSecret)
+	v, err := voucher.Local.Check(secret, code)
+	if err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Voucher", err.Error())
+		return
+	}
+	if v.Kind != voucher.Minutes {
+		ntf.DisplayAndLog(ntf.Warning, "Voucher", "%s can only be used at checkout", v)
+		return
+	}
+	if _, err := voucher.Local.Redeem(secret, code); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Voucher", err.Error())
+		return
+	}
+	AddPlayTime(v.Value)
+	ntf.DisplayAndLog(ntf.Success, "Voucher", "Redeemed %s", v)
+}
+
 func (s *sceneQuick) Entry() *entry {
 	return &s.entry
 }
@@ -25,7 +25,7 @@ func buildSavestates() Scene {
 		label: "Save State",
 		icon:  "savestate",
 		callbackOK: func() {
-			name := utils.DatedName(state.GamePath)
+			name := utils.DatedName(state.ContentPath())
 			err := menu.TakeScreenshot(name)
 			if err != nil {
 				ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
@@ -41,7 +41,7 @@ func buildSavestates() Scene {
 		},
 	})
 
-	gameName := utils.FileName(state.GamePath)
+	gameName := utils.FileName(state.ContentPath())
 	gameName = strings.Replace(gameName, "[", "\\[", -1)
 	gameName = strings.Replace(gameName, "]", "\\]", -1)
 	paths, _ := filepath.Glob(settings.Current.SavestatesDirectory + "/" + gameName + "@*.state")
@@ -209,6 +209,36 @@ var incrCallbacks = map[string]callbackIncrement{
 		f.Set(v)
 		settings.Save()
 	},
+	"RewindEnabled": func(f *structs.Field, direction int) {
+		v := f.Value().(bool)
+		v = !v
+		f.Set(v)
+		settings.Save()
+	},
+	"RewindBufferSize": func(f *structs.Field, direction int) {
+		v := f.Value().(int)
+		v += 16 * direction
+		if v < 16 {
+			v = 16
+		}
+		if v > 1024 {
+			v = 1024
+		}
+		f.Set(v)
+		settings.Save()
+	},
+	"RewindGranularity": func(f *structs.Field, direction int) {
+		v := f.Value().(int)
+		v += direction
+		if v < 1 {
+			v = 1
+		}
+		if v > 60 {
+			v = 60
+		}
+		f.Set(v)
+		settings.Save()
+	},
 	"AudioVolume": func(f *structs.Field, direction int) {
 		v := f.Value().(float32)
 		v += 0.1 * float32(direction)
@@ -0,0 +1,123 @@
+package menu
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/libretro"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneSubsystems struct {
+	entry
+}
+
+func buildSubsystems() Scene {
+	var list sceneSubsystems
+	list.label = "Load Subsystem"
+
+	usr, _ := user.Current()
+
+	for _, s := range state.Core.Subsystems {
+		s := s
+		list.children = append(list.children, entry{
+			label: strings.Replace(s.Desc, "%", "%%", -1),
+			icon:  "subsetting",
+			callbackOK: func() {
+				list.segueNext()
+				pickSubsystemROM(s, nil, usr.HomeDir)
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// subsystemExtensions turns the valid extensions of a subsystem ROM into a
+// filter for the explorer
+func subsystemExtensions(rom libretro.SubsystemROMInfo) []string {
+	if rom.ValidExtensions == "" {
+		return nil
+	}
+	exts := []string{}
+	for _, ext := range strings.Split(rom.ValidExtensions, "|") {
+		exts = append(exts, "."+ext)
+	}
+	return exts
+}
+
+// pickSubsystemROM opens an explorer to pick the next ROM of the subsystem,
+// starting in dir. Optional ROMs can be skipped. The game is loaded once all
+// the ROMs are picked.
+func pickSubsystemROM(subsystem libretro.SubsystemInfo, paths []string, dir string) {
+	if len(paths) == len(subsystem.ROMs) {
+		subsystemExplorerCb(subsystem, paths)
+		return
+	}
+
+	rom := subsystem.ROMs[len(paths)]
+	ntf.DisplayAndLog(ntf.Info, "Menu", "Select the %s.", rom.Desc)
+
+	var skip *entry
+	if !rom.Required {
+		skip = &entry{
+			label: "<Skip " + strings.Replace(rom.Desc, "%", "%%", -1) + ">",
+			icon:  "scan",
+		}
+	}
+
+	menu.Push(buildExplorer(dir, subsystemExtensions(rom), func(path string) {
+		// The skip entry passes the directory being explored
+		next := append(append([]string{}, paths...), "")
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			next[len(next)-1] = path
+			path = filepath.Dir(path)
+		}
+		menu.stack[len(menu.stack)-1].segueNext()
+		pickSubsystemROM(subsystem, next, path)
+	}, skip, nil))
+}
+
+// triggered when the last ROM of a subsystem is picked
+func subsystemExplorerCb(subsystem libretro.SubsystemInfo, paths []string) {
+	if err := core.LoadSubsystem(subsystem, paths); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Core", err.Error())
+		return
+	}
+	menu.WarpToQuickMenu()
+	state.MenuActive = false
+}
+
+func (s *sceneSubsystems) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneSubsystems) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneSubsystems) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneSubsystems) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneSubsystems) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneSubsystems) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneSubsystems) drawHintBar() {
+	genericDrawHintBar()
+}
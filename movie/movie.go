@@ -0,0 +1,234 @@
+// Package movie records the inputs of a game frame by frame, from a
+// savestate, so the game can be played back exactly as it was played. Movies
+// are used for attract mode, to reproduce bugs and to regression-test games.
+// Checksums of the state are recorded regularly to detect playbacks going out
+// of sync.
+package movie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/libretro/ludo/utils"
+)
+
+// Ports is the number of ports recorded, like input.MaxPlayers
+const Ports = 5
+
+// HashInterval is the number of frames between two checksums of the state
+const HashInterval = 60
+
+// Version is the version of the file format written by Encode
+const Version = 1
+
+const magic = "LUDOMOVI"
+
+// maxStateSize bounds the savestate read from a file, to refuse corrupted
+// files before allocating their memory
+const maxStateSize = 256 << 20
+
+// Errors of a playback
+var (
+	ErrFormat  = errors.New("not a movie file")
+	ErrVersion = errors.New("unsupported movie version")
+	ErrDesync  = errors.New("the playback is out of sync")
+)
+
+// Frame holds the inputs of all the ports for a frame
+type Frame struct {
+	Buttons [Ports]uint16      // joypad buttons, one bit per libretro joypad ID
+	Analog  [Ports][2][2]int16 // left and right sticks, X and Y
+}
+
+// Movie is a savestate followed by the inputs of each frame
+type Movie struct {
+	Core   string         // name of the core
+	Game   string         // name of the game, empty for cores running without one
+	State  []byte         // savestate the movie starts from
+	Frames []Frame        // inputs, one per frame
+	Hashes map[int]uint32 // CRC32 of the state after some frames, by number of frames run
+}
+
+// New starts a movie from a savestate
+func New(core, game string, state []byte) *Movie {
+	return &Movie{
+		Core:   core,
+		Game:   game,
+		State:  append([]byte{}, state...),
+		Hashes: map[int]uint32{},
+	}
+}
+
+// Path returns the movie file of a game in a directory
+func Path(dir, gamePath string) string {
+	return filepath.Join(dir, utils.FileName(gamePath)+".lmv")
+}
+
+// HashDue tells if the state should be hashed after a number of frames
+func HashDue(frames int) bool {
+	return frames > 0 && frames%HashInterval == 0
+}
+
+// SetHash records the checksum of the state after a number of frames
+func (m *Movie) SetHash(frames int, state []byte) {
+	m.Hashes[frames] = crc32.ChecksumIEEE(state)
+}
+
+// Check compares the state after a number of frames to the recorded one. It
+// returns ErrDesync if they differ, and nil if no checksum was recorded.
+func (m *Movie) Check(frames int, state []byte) error {
+	want, ok := m.Hashes[frames]
+	if !ok || crc32.ChecksumIEEE(state) == want {
+		return nil
+	}
+	return fmt.Errorf("%w at frame %d", ErrDesync, frames)
+}
+
+// header is the fixed part of a movie file, after the magic string
+type header struct {
+	Version uint32
+	Frames  uint32
+	Hashes  uint32
+	State   uint32 // size of the savestate
+}
+
+type hash struct {
+	Frame uint32
+	CRC   uint32
+}
+
+// Encode writes a movie. All integers are little endian. The file starts
+// with the magic string and a header, followed by the names of the core and
+// the game, the savestate, the frames and the checksums.
+func (m *Movie) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(magic)
+	h := header{
+		Version: Version,
+		Frames:  uint32(len(m.Frames)),
+		Hashes:  uint32(len(m.Hashes)),
+		State:   uint32(len(m.State)),
+	}
+	binary.Write(bw, binary.LittleEndian, h)
+	writeString(bw, m.Core)
+	writeString(bw, m.Game)
+	bw.Write(m.State)
+	binary.Write(bw, binary.LittleEndian, m.Frames)
+	for f := 0; f <= len(m.Frames); f++ {
+		if crc, ok := m.Hashes[f]; ok {
+			binary.Write(bw, binary.LittleEndian, hash{uint32(f), crc})
+		}
+	}
+	return bw.Flush()
+}
+
+// Decode reads a movie written by Encode
+func Decode(r io.Reader) (*Movie, error) {
+	br := bufio.NewReader(r)
+	b := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, b); err != nil || string(b) != magic {
+		return nil, ErrFormat
+	}
+	var h header
+	if err := binary.Read(br, binary.LittleEndian, &h); err != nil {
+		return nil, unexpected(err)
+	}
+	if h.Version != Version {
+		return nil, fmt.Errorf("%w %d", ErrVersion, h.Version)
+	}
+	if h.State > maxStateSize {
+		return nil, ErrFormat
+	}
+
+	m := &Movie{Hashes: map[int]uint32{}}
+	var err error
+	if m.Core, err = readString(br); err != nil {
+		return nil, unexpected(err)
+	}
+	if m.Game, err = readString(br); err != nil {
+		return nil, unexpected(err)
+	}
+	m.State = make([]byte, h.State)
+	if _, err := io.ReadFull(br, m.State); err != nil {
+		return nil, unexpected(err)
+	}
+	// Frames are read one by one so a corrupted count fails at the end of
+	// the file instead of allocating the memory
+	for i := uint32(0); i < h.Frames; i++ {
+		var f Frame
+		if err := binary.Read(br, binary.LittleEndian, &f); err != nil {
+			return nil, unexpected(err)
+		}
+		m.Frames = append(m.Frames, f)
+	}
+	for i := uint32(0); i < h.Hashes; i++ {
+		var hs hash
+		if err := binary.Read(br, binary.LittleEndian, &hs); err != nil {
+			return nil, unexpected(err)
+		}
+		m.Hashes[int(hs.Frame)] = hs.CRC
+	}
+	return m, nil
+}
+
+// Load reads a movie file
+func Load(path string) (*Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Save writes a movie file
+func (m *Movie) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = m.Encode(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeString writes a string prefixed by its 16-bit length
+func writeString(w io.Writer, s string) {
+	if len(s) > 0xffff {
+		s = s[:0xffff]
+	}
+	binary.Write(w, binary.LittleEndian, uint16(len(s)))
+	io.WriteString(w, s)
+}
+
+// readString reads a string written by writeString
+func readString(r io.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// unexpected reports a file ending in the middle of a movie as corrupted
+func unexpected(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated file", ErrFormat)
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package movie
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func sample() *Movie {
+	m := New("snes9x_libretro", "Super Mario World (USA)", []byte{1, 2, 3, 4})
+	for i := 0; i < 2*HashInterval+5; i++ {
+		var f Frame
+		f.Buttons[0] = uint16(i)
+		f.Buttons[1] = 1 << 3
+		f.Analog[0][1][0] = int16(-i)
+		m.Frames = append(m.Frames, f)
+		if HashDue(len(m.Frames)) {
+			m.SetHash(len(m.Frames), []byte{byte(i)})
+		}
+	}
+	return m
+}
+
+func TestEncode(t *testing.T) {
+	m := sample()
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("got = %+v, want %+v", got, m)
+	}
+
+	t.Run("Saves and loads files", func(t *testing.T) {
+		path := Path(filepath.Join(t.TempDir(), "movies"), "/roms/Super Mario World (USA).sfc")
+		if err := m.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("got = %+v, want %+v", got, m)
+		}
+	})
+
+	t.Run("Rejects other files", func(t *testing.T) {
+		if _, err := Decode(bytes.NewReader([]byte("RIFF1234"))); !errors.Is(err, ErrFormat) {
+			t.Errorf("got = %v, want %v", err, ErrFormat)
+		}
+	})
+
+	t.Run("Rejects truncated files", func(t *testing.T) {
+		b := buf.Bytes()[:buf.Len()-3]
+		if _, err := Decode(bytes.NewReader(b)); !errors.Is(err, ErrFormat) {
+			t.Errorf("got = %v, want %v", err, ErrFormat)
+		}
+	})
+
+	t.Run("Rejects other versions", func(t *testing.T) {
+		b := append([]byte{}, buf.Bytes()...)
+		b[len(magic)] = Version + 1
+		if _, err := Decode(bytes.NewReader(b)); !errors.Is(err, ErrVersion) {
+			t.Errorf("got = %v, want %v", err, ErrVersion)
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	m := New("core", "game", nil)
+	m.SetHash(HashInterval, []byte("state"))
+
+	tests := []struct {
+		name   string
+		frames int
+		state  string
+		want   error
+	}{
+		{name: "Accepts the recorded state", frames: HashInterval, state: "state", want: nil},
+		{name: "Detects a different state", frames: HashInterval, state: "other", want: ErrDesync},
+		{name: "Ignores frames without checksum", frames: HashInterval + 1, state: "other", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Check(tt.frames, []byte(tt.state)); !errors.Is(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
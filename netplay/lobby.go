@@ -0,0 +1,96 @@
+package netplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Default UDP ports of the sessions and of the LAN lobby
+const (
+	DefaultPort = 55435
+	LobbyPort   = 55436
+)
+
+// lobbyMagic marks the announcements of Ludo hosts
+const lobbyMagic = "ludo-netplay"
+
+// Announcement describes a session waiting for a player on the LAN
+type Announcement struct {
+	Name string `json:"name"` // host name of the cabinet
+	Game string `json:"game"` // game ID passed to Host
+	Port int    `json:"port"` // UDP port passed to Host
+	Addr string `json:"-"`    // address to Join, set by Discover
+}
+
+type lobbyPacket struct {
+	Magic string `json:"magic"`
+	Announcement
+}
+
+// Announce broadcasts a every second to addr, typically
+// "255.255.255.255:55436", until ctx is done
+func Announce(ctx context.Context, addr string, a Announcement) error {
+	raddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	b, _ := json.Marshal(lobbyPacket{Magic: lobbyMagic, Announcement: a})
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if _, err := conn.WriteTo(b, raddr); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Discover listens on addr, typically ":55436", for the announcements of
+// the hosts of the LAN during wait
+func Discover(addr string, wait time.Duration) ([]Announcement, error) {
+	conn, err := net.ListenPacket("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(wait))
+
+	found := []Announcement{}
+	seen := map[string]bool{}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return found, nil
+			}
+			return found, err
+		}
+		var p lobbyPacket
+		if json.Unmarshal(buf[:n], &p) != nil || p.Magic != lobbyMagic {
+			continue
+		}
+		host, _, _ := net.SplitHostPort(from.String())
+		p.Addr = net.JoinHostPort(host, strconv.Itoa(p.Port))
+		if seen[p.Addr] {
+			continue
+		}
+		seen[p.Addr] = true
+		found = append(found, p.Announcement)
+	}
+}
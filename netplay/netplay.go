@@ -0,0 +1,424 @@
+// Package netplay lets two Ludo instances play the same game over UDP. Each
+// instance runs the whole game and only the inputs are exchanged. The input
+// of the other player is predicted when it is late, and the game is rolled
+// back and replayed with savestates once it arrives. Checksums of the states
+// are compared regularly to detect desynchronizations.
+package netplay
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"net"
+	"time"
+)
+
+// Players is the number of players of a session. The host plays on port 0
+// and the other player on port 1.
+const Players = 2
+
+const (
+	// maxRollback is how many frames the game can run ahead of the inputs
+	// of the other player before waiting for them
+	maxRollback = 8
+	// crcInterval is the number of frames between two checksums
+	crcInterval = 60
+	// ringSize is the number of frames of history kept
+	ringSize = 64
+	// timeout is how long the other player can stay silent
+	timeout = 5 * time.Second
+	// resendInterval is the delay between two resends of the inputs while
+	// waiting for the other player
+	resendInterval = 16 * time.Millisecond
+)
+
+// Errors ending a session
+var (
+	ErrDesync       = errors.New("the games are out of sync")
+	ErrTimeout      = errors.New("the other player is not responding")
+	ErrDisconnected = errors.New("the other player left")
+)
+
+// Emulator is the part of a libretro core needed by netplay. Its Run must
+// be deterministic.
+type Emulator interface {
+	Run()
+	SerializeSize() uint
+	Serialize(size uint) ([]byte, error)
+	Unserialize(bytes []byte, size uint) error
+}
+
+// Config connects a Session to the frontend
+type Config struct {
+	// SetInputs is called before each frame with the joypad buttons of each
+	// port, one bit per libretro joypad ID
+	SetInputs func(inputs [Players]uint16)
+	// SetAVEnabled hides the audio and video of the frames replayed after a
+	// rollback
+	SetAVEnabled func(bool)
+}
+
+// Peer is a connection to the other player, established by Host or Join
+type Peer struct {
+	conn  net.PacketConn
+	addr  net.Addr
+	Local int // port of the local player
+}
+
+// Host waits on addr, like ":55435", for a player running the same game to
+// join, until ctx is done. game identifies the game, both players must use
+// the same one.
+func Host(ctx context.Context, addr, game string) (*Peer, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	stop := interruptReads(ctx, conn)
+	defer stop()
+
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			conn.Close()
+			return nil, contextError(ctx, err)
+		}
+		if n == 0 || buf[0] != packetHello {
+			continue
+		}
+		if string(buf[1:n]) != game {
+			conn.WriteTo(textPacket(packetRefuse, "not the same game"), from)
+			continue
+		}
+		conn.WriteTo([]byte{packetWelcome}, from)
+		return &Peer{conn: conn, addr: from, Local: 0}, nil
+	}
+}
+
+// Join joins the player hosting game at addr, like "192.168.1.20:55435",
+// retrying until ctx is done
+func Join(ctx context.Context, addr, game string) (*Peer, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	stop := interruptReads(ctx, conn)
+	defer stop()
+
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		conn.WriteTo(textPacket(packetHello, game), raddr)
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil || n == 0 {
+			continue
+		}
+		switch buf[0] {
+		case packetWelcome:
+			conn.SetReadDeadline(time.Time{})
+			return &Peer{conn: conn, addr: raddr, Local: 1}, nil
+		case packetRefuse:
+			conn.Close()
+			return nil, errors.New("refused: " + string(buf[1:n]))
+		}
+	}
+	conn.Close()
+	return nil, contextError(ctx, ErrTimeout)
+}
+
+// interruptReads unblocks the reads of conn once ctx is done. The returned
+// function stops watching ctx and clears the read deadline.
+func interruptReads(ctx context.Context, conn net.PacketConn) func() {
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	return func() {
+		if stop() {
+			conn.SetReadDeadline(time.Time{})
+		}
+	}
+}
+
+// contextError reports an expired ctx as a timeout and a cancelled one as
+// such, and err otherwise
+func contextError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return ErrTimeout
+	case context.Canceled:
+		return context.Canceled
+	}
+	return err
+}
+
+// Session runs a game in sync with the other player
+type Session struct {
+	peer *Peer
+	emu  Emulator
+	cfg  Config
+
+	frame      int // next frame to run
+	remoteNext int // next frame whose remote input is expected
+	acked      int // the other player has our inputs up to this frame
+	rollback   int // earliest frame run with a wrong prediction, or -1
+	lastCRC    int // last frame whose checksum was sent
+	lastRecv   time.Time
+	lastSend   time.Time
+
+	local     [ringSize]uint16
+	remote    [ringSize]uint16
+	predicted [ringSize]uint16
+	states    [ringSize][]byte
+	crcs      map[int]uint32 // our checksums waiting for the other player's
+	peerCRCs  map[int]uint32 // their checksums waiting for ours
+
+	packets chan []byte // packets of the other player
+	readErr chan error  // error that stopped the reads
+}
+
+// NewSession starts a session with a connected peer. Both players must
+// start from the same state, the one sent by the host with SendState.
+func NewSession(p *Peer, emu Emulator, cfg Config) *Session {
+	s := &Session{
+		peer:     p,
+		emu:      emu,
+		cfg:      cfg,
+		rollback: -1,
+		lastCRC:  -1,
+		lastRecv: time.Now(),
+		crcs:     map[int]uint32{},
+		peerCRCs: map[int]uint32{},
+		packets:  make(chan []byte, 256),
+		readErr:  make(chan error, 1),
+	}
+	go s.read()
+	return s
+}
+
+// read forwards the packets of the other player to Update until the
+// connection is closed
+func (s *Session) read() {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := s.peer.conn.ReadFrom(buf)
+		if err != nil {
+			s.readErr <- err
+			return
+		}
+		if n == 0 || from.String() != s.peer.addr.String() {
+			continue
+		}
+		s.packets <- append([]byte(nil), buf[:n]...)
+	}
+}
+
+// Frame returns the number of frames run
+func (s *Session) Frame() int {
+	return s.frame
+}
+
+// Update runs the next frame with the local input. It returns false when
+// the game has to wait for the other player. An error ends the session.
+func (s *Session) Update(local uint16) (bool, error) {
+	if err := s.receive(); err != nil {
+		return false, err
+	}
+
+	if s.rollback >= 0 {
+		s.replay()
+	}
+
+	if err := s.checkCRC(); err != nil {
+		return false, err
+	}
+
+	if s.frame-s.remoteNext >= maxRollback {
+		if time.Since(s.lastSend) > resendInterval {
+			s.sendInputs()
+		}
+		if time.Since(s.lastRecv) > timeout {
+			return false, ErrTimeout
+		}
+		return false, nil
+	}
+
+	s.local[s.frame%ringSize] = local
+	s.sendInputs()
+	if err := s.run(s.frame); err != nil {
+		return false, err
+	}
+	s.cfg.SetAVEnabled(true)
+	s.frame++
+	return true, nil
+}
+
+// Close tells the other player that we left and closes the connection
+func (s *Session) Close() error {
+	s.peer.conn.WriteTo([]byte{packetBye}, s.peer.addr)
+	return s.peer.conn.Close()
+}
+
+// run saves the state before frame f and runs it
+func (s *Session) run(f int) error {
+	size := s.emu.SerializeSize()
+	state, err := s.emu.Serialize(size)
+	if err != nil {
+		return err
+	}
+	s.states[f%ringSize] = state
+
+	var inputs [Players]uint16
+	inputs[s.peer.Local] = s.local[f%ringSize]
+	inputs[1-s.peer.Local] = s.prediction(f)
+	s.predicted[f%ringSize] = inputs[1-s.peer.Local]
+	s.cfg.SetInputs(inputs)
+	s.emu.Run()
+	return nil
+}
+
+// prediction returns the remote input of frame f, or the last one received
+// if it is not there yet
+func (s *Session) prediction(f int) uint16 {
+	if f < s.remoteNext {
+		return s.remote[f%ringSize]
+	}
+	if s.remoteNext == 0 {
+		return 0
+	}
+	return s.remote[(s.remoteNext-1)%ringSize]
+}
+
+// replay rolls back to the first frame run with a wrong prediction and runs
+// the frames again, hidden, with the inputs received since
+func (s *Session) replay() {
+	state := s.states[s.rollback%ringSize]
+	s.emu.Unserialize(state, uint(len(state)))
+	s.cfg.SetAVEnabled(false)
+	for f := s.rollback; f < s.frame; f++ {
+		s.run(f)
+	}
+	s.cfg.SetAVEnabled(true)
+	s.rollback = -1
+}
+
+// receive handles the packets received since the last frame
+func (s *Session) receive() error {
+	for {
+		var b []byte
+		select {
+		case b = <-s.packets:
+		case err := <-s.readErr:
+			return err
+		default:
+			return nil
+		}
+		s.lastRecv = time.Now()
+		switch b[0] {
+		case packetHello:
+			// Our welcome was lost
+			s.peer.conn.WriteTo([]byte{packetWelcome}, s.peer.addr)
+		case packetInput:
+			p, err := decodeInput(b)
+			if err == nil {
+				s.handleInputs(p)
+			}
+		case packetCRC:
+			p, err := decodeCRC(b)
+			if err == nil {
+				s.peerCRCs[int(p.frame)] = p.crc
+			}
+		case packetState:
+			// Our acknowledgement of the last chunk was lost
+			p, err := decodeState(b)
+			if err == nil {
+				s.peer.conn.WriteTo(stateAck(p.total), s.peer.addr)
+			}
+		case packetBye:
+			return ErrDisconnected
+		}
+	}
+}
+
+func (s *Session) handleInputs(p inputPacket) {
+	if int(p.ack) > s.acked {
+		s.acked = int(p.ack)
+	}
+	for i, in := range p.inputs {
+		f := int(p.start) + i
+		if f != s.remoteNext {
+			continue
+		}
+		s.remote[f%ringSize] = in
+		s.remoteNext++
+		if f < s.frame && s.predicted[f%ringSize] != in && (s.rollback < 0 || f < s.rollback) {
+			s.rollback = f
+		}
+	}
+}
+
+// sendInputs sends the local inputs the other player hasn't acknowledged
+func (s *Session) sendInputs() {
+	start := s.acked
+	end := s.frame + 1
+	if s.frame-s.remoteNext >= maxRollback {
+		end = s.frame // no new input while waiting
+	}
+	if end-start > maxInputsPerPacket {
+		start = end - maxInputsPerPacket
+	}
+	if end <= start {
+		return
+	}
+	p := inputPacket{ack: uint32(s.remoteNext), start: uint32(start)}
+	for f := start; f < end; f++ {
+		p.inputs = append(p.inputs, s.local[f%ringSize])
+	}
+	s.peer.conn.WriteTo(p.encode(), s.peer.addr)
+	s.lastSend = time.Now()
+}
+
+// checkCRC sends the checksum of the states every crcInterval frames, once
+// all the inputs leading to them are known, and compares them with the ones
+// of the other player
+func (s *Session) checkCRC() error {
+	f := s.lastCRC + crcInterval
+	if s.lastCRC < 0 {
+		f = crcInterval
+	}
+	if f < s.frame && f <= s.remoteNext {
+		s.lastCRC = f
+		// Too late for this one if the state left the history
+		if s.frame-f < ringSize {
+			crc := crc32.ChecksumIEEE(s.states[f%ringSize])
+			s.crcs[f] = crc
+			s.peer.conn.WriteTo(crcPacket{frame: uint32(f), crc: crc}.encode(), s.peer.addr)
+		}
+	}
+	for frame, crc := range s.crcs {
+		peer, ok := s.peerCRCs[frame]
+		if !ok {
+			continue
+		}
+		delete(s.crcs, frame)
+		delete(s.peerCRCs, frame)
+		if peer != crc {
+			return ErrDesync
+		}
+	}
+	// Forget the checksums whose counterpart was lost
+	for frame := range s.crcs {
+		if frame < s.frame-10*crcInterval {
+			delete(s.crcs, frame)
+		}
+	}
+	for frame := range s.peerCRCs {
+		if frame < s.frame-10*crcInterval {
+			delete(s.peerCRCs, frame)
+		}
+	}
+	return nil
+}
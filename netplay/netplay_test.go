@@ -0,0 +1,286 @@
+package netplay
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeEmulator is a deterministic game whose state depends on every input
+type fakeEmulator struct {
+	state    uint32
+	inputs   [Players]uint16
+	rollback int
+	desyncAt int // adds a bug at this frame when positive
+	frames   int
+}
+
+func (e *fakeEmulator) Run() {
+	e.frames++
+	e.state = e.state*31 + uint32(e.inputs[0])*7 + uint32(e.inputs[1])*13
+	if e.desyncAt > 0 && e.frames == e.desyncAt {
+		e.state++
+	}
+}
+
+func (e *fakeEmulator) SerializeSize() uint { return 8 }
+
+func (e *fakeEmulator) Serialize(size uint) ([]byte, error) {
+	b := make([]byte, size)
+	binary.LittleEndian.PutUint32(b, e.state)
+	binary.LittleEndian.PutUint32(b[4:], uint32(e.frames))
+	return b, nil
+}
+
+func (e *fakeEmulator) Unserialize(b []byte, size uint) error {
+	e.state = binary.LittleEndian.Uint32(b)
+	e.frames = int(binary.LittleEndian.Uint32(b[4:]))
+	e.rollback++
+	return nil
+}
+
+// script is the input of a player at a frame
+func script(port, frame int) uint16 {
+	return uint16((frame/(port+3))%4) << port
+}
+
+// expected runs the game locally with the inputs of both players
+func expected(frames int) uint32 {
+	e := &fakeEmulator{}
+	for f := 0; f < frames; f++ {
+		e.inputs = [Players]uint16{script(0, f), script(1, f)}
+		e.Run()
+	}
+	return e.state
+}
+
+func freeAddr(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+func connect(t *testing.T, hostGame, joinGame string) (*Peer, *Peer, error) {
+	addr := freeAddr(t)
+	hosted := make(chan *Peer)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		p, _ := Host(ctx, addr, hostGame)
+		hosted <- p
+	}()
+	joined, err := Join(ctx, addr, joinGame)
+	if err != nil {
+		cancel()
+	}
+	return <-hosted, joined, err
+}
+
+func newTestSession(p *Peer, e *fakeEmulator) *Session {
+	return NewSession(p, e, Config{
+		SetInputs:    func(in [Players]uint16) { e.inputs = in },
+		SetAVEnabled: func(bool) {},
+	})
+}
+
+// play runs s until both players confirmed the inputs of the first frames
+func play(s *Session, frames int, delay time.Duration) error {
+	for s.Frame() <= frames || s.remoteNext < frames {
+		if _, err := s.Update(script(s.peer.Local, s.Frame())); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+func TestSession(t *testing.T) {
+	t.Run("Both players end up with the same game", func(t *testing.T) {
+		host, guest, err := connect(t, "nova", "nova")
+		if err != nil {
+			t.Fatal(err)
+		}
+		he, ge := &fakeEmulator{}, &fakeEmulator{}
+		hs, gs := newTestSession(host, he), newTestSession(guest, ge)
+		defer hs.Close()
+		defer gs.Close()
+
+		const frames = 300
+		done := make(chan error)
+		go func() { done <- play(gs, frames, 2*time.Millisecond) }()
+		if err := play(hs, frames, time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+
+		want := expected(frames)
+		got := []uint32{
+			binary.LittleEndian.Uint32(hs.states[frames%ringSize]),
+			binary.LittleEndian.Uint32(gs.states[frames%ringSize]),
+		}
+		if !reflect.DeepEqual(got, []uint32{want, want}) {
+			t.Errorf("got = %v, want %v", got, []uint32{want, want})
+		}
+		if he.rollback == 0 {
+			t.Errorf("got = %v rollbacks, want some", he.rollback)
+		}
+	})
+
+	t.Run("Detects desynchronizations", func(t *testing.T) {
+		host, guest, err := connect(t, "nova", "nova")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hs := newTestSession(host, &fakeEmulator{desyncAt: 30})
+		gs := newTestSession(guest, &fakeEmulator{})
+
+		// The first one to notice leaves, and the other one stops too
+		done := make(chan error)
+		go func() {
+			err := play(gs, 600, time.Millisecond)
+			gs.Close()
+			done <- err
+		}()
+		errHost := play(hs, 600, time.Millisecond)
+		hs.Close()
+		errGuest := <-done
+		if !errors.Is(errHost, ErrDesync) && !errors.Is(errGuest, ErrDesync) {
+			t.Errorf("got = %v %v, want %v", errHost, errGuest, ErrDesync)
+		}
+	})
+
+	t.Run("Stops when the other player leaves", func(t *testing.T) {
+		host, guest, err := connect(t, "nova", "nova")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hs := newTestSession(host, &fakeEmulator{})
+		newTestSession(guest, &fakeEmulator{}).Close()
+		time.Sleep(10 * time.Millisecond)
+		if _, err := hs.Update(0); !errors.Is(err, ErrDisconnected) {
+			t.Errorf("got = %v, want %v", err, ErrDisconnected)
+		}
+		hs.Close()
+	})
+}
+
+func TestSendState(t *testing.T) {
+	t.Run("The other player gets the state of the host", func(t *testing.T) {
+		host, guest, err := connect(t, "nova", "nova")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer host.Close()
+		defer guest.Close()
+
+		want := make([]byte, 100*stateChunk+17)
+		for i := range want {
+			want[i] = byte(i * 7)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sent := make(chan error)
+		go func() { sent <- host.SendState(ctx, want) }()
+		got, err := guest.ReceiveState(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := <-sent; err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %d bytes, want %d", len(got), len(want))
+		}
+	})
+
+	t.Run("Stops when the other player leaves", func(t *testing.T) {
+		host, guest, err := connect(t, "nova", "nova")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer host.Close()
+		guest.conn.WriteTo([]byte{packetBye}, guest.addr)
+		guest.Close()
+
+		if err := host.SendState(context.Background(), []byte{1, 2, 3}); err != ErrDisconnected {
+			t.Errorf("got = %v, want %v", err, ErrDisconnected)
+		}
+	})
+}
+
+func TestJoin(t *testing.T) {
+	t.Run("Refuses players running another game", func(t *testing.T) {
+		_, _, err := connect(t, "nova", "retro hero")
+		if err == nil || err.Error() != "refused: not the same game" {
+			t.Errorf("got = %v, want %v", err, "refused: not the same game")
+		}
+	})
+
+	t.Run("Gives up when nobody hosts the game", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if _, err := Join(ctx, freeAddr(t), "nova"); !errors.Is(err, ErrTimeout) {
+			t.Errorf("got = %v, want %v", err, ErrTimeout)
+		}
+	})
+
+	t.Run("Stops hosting when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := Host(ctx, freeAddr(t), "nova"); !errors.Is(err, context.Canceled) {
+			t.Errorf("got = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	addr := freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	found := make(chan []Announcement)
+	go func() {
+		a, _ := Discover(addr, 300*time.Millisecond)
+		found <- a
+	}()
+	time.Sleep(50 * time.Millisecond)
+	go Announce(ctx, addr, Announcement{Name: "cabinet-1", Game: "nova", Port: DefaultPort})
+
+	got := <-found
+	want := []Announcement{{Name: "cabinet-1", Game: "nova", Port: DefaultPort, Addr: "127.0.0.1:55435"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestInputPacket(t *testing.T) {
+	p := inputPacket{ack: 12, start: 40, inputs: []uint16{1, 0, 0x8001}}
+	got, err := decodeInput(p.encode())
+	if err != nil || !reflect.DeepEqual(got, p) {
+		t.Errorf("got = %v %v, want %v", got, err, p)
+	}
+	if _, err := decodeInput(p.encode()[:11]); err == nil {
+		t.Errorf("got = %v, want %v", err, errMalformed)
+	}
+}
+
+func TestStatePacket(t *testing.T) {
+	p := statePacket{offset: 1024, total: 2048, data: []byte{1, 2, 3}}
+	got, err := decodeState(p.encode())
+	if err != nil || !reflect.DeepEqual(got, p) {
+		t.Errorf("got = %v %v, want %v", got, err, p)
+	}
+	p.offset = 2046
+	if _, err := decodeState(p.encode()); err == nil {
+		t.Errorf("got = %v, want %v", err, errMalformed)
+	}
+}
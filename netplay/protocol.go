@@ -0,0 +1,131 @@
+package netplay
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Packet types
+const (
+	packetHello    byte = iota + 1 // a player asks to join, with the game ID
+	packetWelcome                  // the host accepts the player
+	packetRefuse                   // the host refuses the player, with a reason
+	packetInput                    // inputs of consecutive frames
+	packetCRC                      // checksum of the state at the start of a frame
+	packetBye                      // the peer left the session
+	packetState                    // a chunk of the state the game starts from
+	packetStateAck                 // the bytes of the state received so far
+)
+
+// maxInputsPerPacket bounds the number of unacknowledged inputs resent in
+// each input packet
+const maxInputsPerPacket = 64
+
+var errMalformed = errors.New("malformed packet")
+
+// inputPacket carries the inputs of the frames [start, start+len(inputs)),
+// and acknowledges the inputs of the receiver up to ack, excluded
+type inputPacket struct {
+	ack    uint32
+	start  uint32
+	inputs []uint16
+}
+
+func (p inputPacket) encode() []byte {
+	b := make([]byte, 10, 10+2*len(p.inputs))
+	b[0] = packetInput
+	binary.LittleEndian.PutUint32(b[1:], p.ack)
+	binary.LittleEndian.PutUint32(b[5:], p.start)
+	b[9] = byte(len(p.inputs))
+	for _, in := range p.inputs {
+		b = binary.LittleEndian.AppendUint16(b, in)
+	}
+	return b
+}
+
+func decodeInput(b []byte) (inputPacket, error) {
+	if len(b) < 10 || len(b) != 10+2*int(b[9]) {
+		return inputPacket{}, errMalformed
+	}
+	p := inputPacket{
+		ack:    binary.LittleEndian.Uint32(b[1:]),
+		start:  binary.LittleEndian.Uint32(b[5:]),
+		inputs: make([]uint16, b[9]),
+	}
+	for i := range p.inputs {
+		p.inputs[i] = binary.LittleEndian.Uint16(b[10+2*i:])
+	}
+	return p, nil
+}
+
+// crcPacket carries the checksum of the state at the start of a frame
+type crcPacket struct {
+	frame uint32
+	crc   uint32
+}
+
+func (p crcPacket) encode() []byte {
+	b := make([]byte, 9)
+	b[0] = packetCRC
+	binary.LittleEndian.PutUint32(b[1:], p.frame)
+	binary.LittleEndian.PutUint32(b[5:], p.crc)
+	return b
+}
+
+func decodeCRC(b []byte) (crcPacket, error) {
+	if len(b) != 9 {
+		return crcPacket{}, errMalformed
+	}
+	return crcPacket{
+		frame: binary.LittleEndian.Uint32(b[1:]),
+		crc:   binary.LittleEndian.Uint32(b[5:]),
+	}, nil
+}
+
+// statePacket carries the bytes [offset, offset+len(data)) of a state of
+// total bytes
+type statePacket struct {
+	offset uint32
+	total  uint32
+	data   []byte
+}
+
+func (p statePacket) encode() []byte {
+	b := make([]byte, 9, 9+len(p.data))
+	b[0] = packetState
+	binary.LittleEndian.PutUint32(b[1:], p.offset)
+	binary.LittleEndian.PutUint32(b[5:], p.total)
+	return append(b, p.data...)
+}
+
+func decodeState(b []byte) (statePacket, error) {
+	if len(b) < 9 {
+		return statePacket{}, errMalformed
+	}
+	p := statePacket{
+		offset: binary.LittleEndian.Uint32(b[1:]),
+		total:  binary.LittleEndian.Uint32(b[5:]),
+		data:   append([]byte(nil), b[9:]...),
+	}
+	if uint64(p.offset)+uint64(len(p.data)) > uint64(p.total) {
+		return statePacket{}, errMalformed
+	}
+	return p, nil
+}
+
+// stateAck acknowledges the first received bytes of the state
+func stateAck(received uint32) []byte {
+	return binary.LittleEndian.AppendUint32([]byte{packetStateAck}, received)
+}
+
+func decodeStateAck(b []byte) (uint32, error) {
+	if len(b) != 5 {
+		return 0, errMalformed
+	}
+	return binary.LittleEndian.Uint32(b[1:]), nil
+}
+
+// textPacket is a packet type followed by a string, for hello and refuse
+func textPacket(kind byte, s string) []byte {
+	return append([]byte{kind}, s...)
+}
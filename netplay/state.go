@@ -0,0 +1,140 @@
+package netplay
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+const (
+	// stateChunk is the number of bytes of the state sent in each packet
+	stateChunk = 1024
+	// stateWindow is the number of chunks sent before waiting for their
+	// acknowledgement
+	stateWindow = 32
+	// maxStateSize bounds the state accepted from the host
+	maxStateSize = 64 << 20
+)
+
+// SendState sends the state the game starts from to the other player, who
+// loads it with ReceiveState. The host calls it once connected, before
+// NewSession, so both players start from the same state.
+func (p *Peer) SendState(ctx context.Context, state []byte) error {
+	if len(state) == 0 {
+		return errors.New("the state is empty")
+	}
+	defer p.conn.SetReadDeadline(time.Time{})
+
+	acked, sent := 0, 0
+	lastRecv := time.Now()
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		// Chunks not acknowledged in time are sent again
+		for sent < len(state) && sent < acked+stateWindow*stateChunk {
+			end := min(sent+stateChunk, len(state))
+			p.conn.WriteTo(statePacket{
+				offset: uint32(sent),
+				total:  uint32(len(state)),
+				data:   state[sent:end],
+			}.encode(), p.addr)
+			sent = end
+		}
+
+		p.conn.SetReadDeadline(time.Now().Add(4 * resendInterval))
+		n, from, err := p.conn.ReadFrom(buf)
+		if isTimeout(err) {
+			if time.Since(lastRecv) > timeout {
+				return ErrTimeout
+			}
+			sent = acked
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 || from.String() != p.addr.String() {
+			continue
+		}
+		lastRecv = time.Now()
+		switch buf[0] {
+		case packetHello:
+			// Our welcome was lost
+			p.conn.WriteTo([]byte{packetWelcome}, p.addr)
+		case packetStateAck:
+			received, err := decodeStateAck(buf[:n])
+			if err != nil || int(received) <= acked || int(received) > len(state) {
+				continue
+			}
+			acked = int(received)
+			if acked == len(state) {
+				return nil
+			}
+		case packetBye:
+			return ErrDisconnected
+		}
+	}
+	return contextError(ctx, ErrTimeout)
+}
+
+// ReceiveState waits for the state sent by the host with SendState
+func (p *Peer) ReceiveState(ctx context.Context) ([]byte, error) {
+	defer p.conn.SetReadDeadline(time.Time{})
+
+	var state []byte
+	received := 0
+	lastRecv := time.Now()
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		p.conn.SetReadDeadline(time.Now().Add(4 * resendInterval))
+		n, from, err := p.conn.ReadFrom(buf)
+		if isTimeout(err) {
+			if time.Since(lastRecv) > timeout {
+				return nil, ErrTimeout
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || from.String() != p.addr.String() {
+			continue
+		}
+		lastRecv = time.Now()
+		switch buf[0] {
+		case packetState:
+			sp, err := decodeState(buf[:n])
+			if err != nil || sp.total > maxStateSize {
+				continue
+			}
+			if state == nil {
+				state = make([]byte, sp.total)
+			}
+			if int(sp.total) != len(state) {
+				continue
+			}
+			// Chunks arriving out of order are sent again
+			if int(sp.offset) == received {
+				received += copy(state[received:], sp.data)
+			}
+			p.conn.WriteTo(stateAck(uint32(received)), p.addr)
+			if received == len(state) {
+				return state, nil
+			}
+		case packetBye:
+			return nil, ErrDisconnected
+		}
+	}
+	return nil, contextError(ctx, ErrTimeout)
+}
+
+// Close closes the connection of a peer that didn't start a session
+func (p *Peer) Close() error {
+	return p.conn.Close()
+}
+
+// isTimeout tells if a read failed because of its deadline
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,129 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// Layer is a level of configuration. The values of a layer override the ones
+// of the layers below.
+type Layer int
+
+// The layers, from the lowest to the highest
+const (
+	LayerDefault   Layer = iota // the default values of the core
+	LayerCore                   // saved for every game of the core
+	LayerDirectory              // saved for the games of a content directory
+	LayerGame                   // saved for a single game
+	layerCount
+)
+
+func (l Layer) String() string {
+	switch l {
+	case LayerCore:
+		return "Core"
+	case LayerDirectory:
+		return "Content Directory"
+	case LayerGame:
+		return "Game"
+	}
+	return "Default"
+}
+
+// path returns the file of a layer. Directory and game overrides are stored
+// in a folder named after the core.
+func (o *Options) path(layer Layer) string {
+	name := utils.FileName(state.CorePath)
+	dir := filepath.Join(xdg.ConfigHome, "ludo")
+	switch layer {
+	case LayerDirectory:
+		return filepath.Join(dir, name, "directories", directoryKey(o.game)+".toml")
+	case LayerGame:
+		return filepath.Join(dir, name, "games", utils.FileName(o.game)+".toml")
+	}
+	return filepath.Join(dir, name+".toml")
+}
+
+// directoryKey names the overrides of the content directory of a game. The
+// checksum of the full path keeps apart the directories with the same name.
+func directoryKey(game string) string {
+	content := filepath.Clean(filepath.Dir(game))
+	return fmt.Sprintf("%s-%08x", filepath.Base(content), crc32.ChecksumIEEE([]byte(content)))
+}
+
+// SetGame applies the overrides of the content directory and of a game. An
+// empty path removes them.
+func (o *Options) SetGame(path string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	o.game = path
+	var err error
+	for _, l := range []Layer{LayerDirectory, LayerGame} {
+		o.layers[l] = map[string]string{}
+		if path == "" {
+			continue
+		}
+		if lerr := o.load(l); lerr != nil && !errors.Is(lerr, fs.ErrNotExist) {
+			err = lerr
+		}
+	}
+	if path == "" && o.SaveLayer > LayerCore {
+		o.SaveLayer = LayerCore
+	}
+	o.apply()
+	o.Updated = true
+	return err
+}
+
+// Layers returns the layers changes can be saved to
+func (o *Options) Layers() []Layer {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.game == "" {
+		return []Layer{LayerCore}
+	}
+	return []Layer{LayerCore, LayerDirectory, LayerGame}
+}
+
+// Choose sets the value of a variable in the save layer
+func (o *Options) Choose(v *Variable, choice int) {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.layers[o.SaveLayer] == nil {
+		o.layers[o.SaveLayer] = map[string]string{}
+	}
+	o.layers[o.SaveLayer][v.Key] = v.Choices[choice]
+	o.apply()
+	o.Updated = true
+}
+
+// apply sets the value of each variable from the highest layer defining it.
+// The lock must be held.
+func (o *Options) apply() {
+	for _, v := range o.Vars {
+		v.Choice = utils.IndexOfString(v.Default, v.Choices)
+		v.Layer = LayerDefault
+		for l := LayerCore; l < layerCount; l++ {
+			val, ok := o.layers[l][v.Key]
+			if !ok {
+				continue
+			}
+			for j, c := range v.Choices {
+				if c == val {
+					v.Choice = j
+					v.Layer = l
+				}
+			}
+		}
+	}
+}
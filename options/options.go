@@ -11,8 +11,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/adrg/xdg"
-	"github.com/libretro/ludo/state"
 	"github.com/libretro/ludo/utils"
 	"github.com/pelletier/go-toml"
 )
@@ -21,17 +19,33 @@ import (
 // values. The possibilities are stored in v.Choices. The current value
 // can be accessed with v.Choices[v.Choice]
 type Variable struct {
-	Key     string   // unique id of the variable
-	Desc    string   // human readable name of the variable
-	Choices []string // available values
-	Choice  int      // index of the current value
-	Default string
+	Key      string   // unique id of the variable
+	Desc     string   // human readable name of the variable
+	Info     string   // detailed description of the variable
+	Category string   // key of the category of the variable, if any
+	Choices  []string // available values
+	Choice   int      // index of the current value
+	Default  string
+	Hidden   bool  // the core asked not to display the variable
+	Layer    Layer // the layer the current value comes from
+}
+
+// Category groups variables in a submenu
+type Category struct {
+	Key  string
+	Desc string
+	Info string
 }
 
 // Options is a container type for core options internals
 type Options struct {
-	Vars    []*Variable // the variables exposed by the core
-	Updated bool        // notify the core that values have been updated
+	Vars       []*Variable // the variables exposed by the core
+	Categories []Category  // the categories of the variables, v2 options only
+	Updated    bool        // notify the core that values have been updated
+	SaveLayer  Layer       // the layer changes are saved to
+
+	game   string                        // path of the game whose overrides apply
+	layers [layerCount]map[string]string // values saved in each layer
 
 	sync.Mutex
 }
@@ -44,6 +58,16 @@ type VariableInterface interface {
 	DefaultValue() string
 }
 
+// infoVariable is implemented by v1 and v2 options
+type infoVariable interface {
+	Info() string
+}
+
+// categoryVariable is implemented by v2 options
+type categoryVariable interface {
+	Category() string
+}
+
 // New instantiate a core options manager
 func New(vars []VariableInterface) (*Options, error) {
 	o := &Options{}
@@ -51,35 +75,106 @@ func New(vars []VariableInterface) (*Options, error) {
 	// Cache core options
 	for _, v := range vars {
 		v := v
-		o.Vars = append(o.Vars, &Variable{
+		variable := &Variable{
 			Key:     v.Key(),
 			Desc:    v.Desc(),
 			Choices: v.Choices(),
 			Default: v.DefaultValue(),
 			Choice:  utils.IndexOfString(v.DefaultValue(), v.Choices()),
-		})
+		}
+		if iv, ok := v.(infoVariable); ok {
+			variable.Info = iv.Info()
+		}
+		if cv, ok := v.(categoryVariable); ok {
+			variable.Category = cv.Category()
+		}
+		o.Vars = append(o.Vars, variable)
 	}
 	o.Updated = true
-	err := o.load()
+	o.SaveLayer = LayerCore
+	o.Lock()
+	err := o.load(LayerCore)
+	o.apply()
+	o.Unlock()
 	return o, err
 }
 
-// Save core options to a file
+// SetCategories sets the categories of the variables. Variables whose
+// category doesn't exist are displayed without category.
+func (o *Options) SetCategories(categories []Category) {
+	o.Lock()
+	defer o.Unlock()
+
+	o.Categories = categories
+	for _, v := range o.Vars {
+		found := false
+		for _, c := range categories {
+			found = found || c.Key == v.Category
+		}
+		if !found {
+			v.Category = ""
+		}
+	}
+}
+
+// SetVisible shows or hides a variable
+func (o *Options) SetVisible(key string, visible bool) {
+	o.Lock()
+	defer o.Unlock()
+
+	for _, v := range o.Vars {
+		if v.Key == key {
+			v.Hidden = !visible
+		}
+	}
+}
+
+// Visible returns the variables of a category that should be displayed,
+// or the ones without category
+func (o *Options) Visible(category string) []*Variable {
+	o.Lock()
+	defer o.Unlock()
+
+	vars := []*Variable{}
+	for _, v := range o.Vars {
+		if !v.Hidden && v.Category == category {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// Save writes the values of the save layer to its file
 func (o *Options) Save() error {
 	o.Lock()
 	defer o.Unlock()
 
+	values := o.layers[o.SaveLayer]
+	if o.SaveLayer == LayerCore {
+		// The core layer has a value for every variable
+		values = map[string]string{}
+		for _, v := range o.Vars {
+			values[v.Key] = v.Default
+			if c, ok := o.layers[LayerCore][v.Key]; ok {
+				values[v.Key] = c
+			}
+		}
+	}
+
 	m := make(map[string]string)
-	for _, v := range o.Vars {
-		m[strings.Replace(v.Key, ".", "___", 1)] = v.Choices[v.Choice]
+	for k, v := range values {
+		m[strings.Replace(k, ".", "___", 1)] = v
 	}
 	b, err := toml.Marshal(m)
 	if err != nil {
 		return err
 	}
 
-	name := utils.FileName(state.CorePath)
-	fd, err := os.Create(filepath.Join(xdg.ConfigHome, "ludo", name+".toml"))
+	path := o.path(o.SaveLayer)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	fd, err := os.Create(path)
 	if err != nil {
 		return err
 	}
@@ -93,13 +188,11 @@ func (o *Options) Save() error {
 	return fd.Sync()
 }
 
-// Load core options from a file
-func (o *Options) load() error {
-	o.Lock()
-	defer o.Unlock()
+// load reads the values of a layer from its file
+func (o *Options) load(layer Layer) error {
+	o.layers[layer] = map[string]string{}
 
-	name := utils.FileName(state.CorePath)
-	b, err := os.ReadFile(filepath.Join(xdg.ConfigHome, "ludo", name+".toml"))
+	b, err := os.ReadFile(o.path(layer))
 	if err != nil {
 		return err
 	}
@@ -111,15 +204,7 @@ func (o *Options) load() error {
 	}
 
 	for optk, optv := range opts {
-		for _, variable := range o.Vars {
-			if variable.Key == strings.Replace(optk, "___", ".", 1) {
-				for j, c := range variable.Choices {
-					if c == optv {
-						variable.Choice = j
-					}
-				}
-			}
-		}
+		o.layers[layer][strings.Replace(optk, "___", ".", 1)] = optv
 	}
 
 	return nil
@@ -0,0 +1,115 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/state"
+)
+
+func testOptions() *Options {
+	return &Options{Vars: []*Variable{
+		{Key: "core_region", Category: "system"},
+		{Key: "core_renderer", Category: "video"},
+		{Key: "core_scanlines", Category: "video"},
+		{Key: "core_turbo", Category: "missing"},
+	}}
+}
+
+func keys(vars []*Variable) []string {
+	k := []string{}
+	for _, v := range vars {
+		k = append(k, v.Key)
+	}
+	return k
+}
+
+func TestOptions_SetCategories(t *testing.T) {
+	t.Run("Drops the categories that don't exist", func(t *testing.T) {
+		o := testOptions()
+		o.SetCategories([]Category{{Key: "system"}, {Key: "video"}})
+		got := keys(o.Visible(""))
+		if len(got) != 1 || got[0] != "core_turbo" {
+			t.Errorf("got = %v, want %v", got, []string{"core_turbo"})
+		}
+	})
+}
+
+func TestOptions_Visible(t *testing.T) {
+	t.Run("Lists the variables of a category", func(t *testing.T) {
+		o := testOptions()
+		got := keys(o.Visible("video"))
+		if len(got) != 2 || got[0] != "core_renderer" || got[1] != "core_scanlines" {
+			t.Errorf("got = %v, want %v", got, []string{"core_renderer", "core_scanlines"})
+		}
+	})
+
+	t.Run("Skips the variables hidden by the core", func(t *testing.T) {
+		o := testOptions()
+		o.SetVisible("core_scanlines", false)
+		got := keys(o.Visible("video"))
+		if len(got) != 1 || got[0] != "core_renderer" {
+			t.Errorf("got = %v, want %v", got, []string{"core_renderer"})
+		}
+		o.SetVisible("core_scanlines", true)
+		if got := keys(o.Visible("video")); len(got) != 2 {
+			t.Errorf("got = %v, want %v", got, []string{"core_renderer", "core_scanlines"})
+		}
+	})
+}
+
+type fakeVariable struct {
+	key     string
+	choices []string
+}
+
+func (v fakeVariable) Key() string          { return v.key }
+func (v fakeVariable) Desc() string         { return v.key }
+func (v fakeVariable) Choices() []string    { return v.choices }
+func (v fakeVariable) DefaultValue() string { return v.choices[0] }
+
+func TestOptions_SetGame(t *testing.T) {
+	xdg.ConfigHome = t.TempDir()
+	state.CorePath = "/cores/test_libretro.so"
+	vars := []VariableInterface{
+		fakeVariable{"test_region", []string{"auto", "ntsc", "pal"}},
+		fakeVariable{"test_scale", []string{"1x", "2x", "4x"}},
+	}
+
+	o, _ := New(vars)
+	o.SetGame("/roms/snes/Mario.sfc")
+	o.SaveLayer = LayerCore
+	o.Choose(o.Vars[0], 1)
+	o.Save()
+	o.SaveLayer = LayerDirectory
+	o.Choose(o.Vars[1], 1)
+	o.Save()
+	o.SaveLayer = LayerGame
+	o.Choose(o.Vars[0], 2)
+	o.Save()
+
+	tests := []struct {
+		name       string
+		game       string
+		wantChoice [2]int
+		wantLayer  [2]Layer
+	}{
+		{"Applies the overrides of the game over the others", "/roms/snes/Mario.sfc", [2]int{2, 1}, [2]Layer{LayerGame, LayerDirectory}},
+		{"Applies the overrides of the content directory to its games", "/roms/snes/Zelda.sfc", [2]int{1, 1}, [2]Layer{LayerCore, LayerDirectory}},
+		{"Only applies the core values to other directories", "/roms/nes/Zelda.nes", [2]int{1, 0}, [2]Layer{LayerCore, LayerCore}},
+		{"Only applies the core values to other directories with the same name", "/backup/snes/Zelda.sfc", [2]int{1, 0}, [2]Layer{LayerCore, LayerCore}},
+		{"Applies the overrides of the content directory to unclean paths", "/roms//snes/./Zelda.sfc", [2]int{1, 1}, [2]Layer{LayerCore, LayerDirectory}},
+		{"Only applies the core values without game", "", [2]int{1, 0}, [2]Layer{LayerCore, LayerCore}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, _ := New(vars)
+			o.SetGame(tt.game)
+			for i, v := range o.Vars {
+				if v.Choice != tt.wantChoice[i] || v.Layer != tt.wantLayer[i] {
+					t.Errorf("got = %v %v, want %v %v", v.Choice, v.Layer, tt.wantChoice[i], tt.wantLayer[i])
+				}
+			}
+		})
+	}
+}
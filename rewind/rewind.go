@@ -0,0 +1,97 @@
+// Package rewind keeps a history of savestates so players can step the game
+// backwards. Only the newest state is kept whole. Older states are stored as
+// the compressed XOR of two consecutive states, which are mostly zeros.
+package rewind
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"log"
+)
+
+// Buffer is a ring of savestates bounded by the memory used by the deltas
+type Buffer struct {
+	capacity int      // maximum size of the deltas, in bytes
+	size     int      // current size of the deltas, in bytes
+	deltas   [][]byte // compressed deltas, the oldest first
+	current  []byte   // newest state
+}
+
+// New creates a Buffer using at most capacity bytes for the deltas
+func New(capacity int) *Buffer {
+	return &Buffer{capacity: capacity}
+}
+
+// Push records a new state
+func (b *Buffer) Push(state []byte) {
+	if b.current != nil && len(b.current) == len(state) {
+		d := compress(xor(b.current, state))
+		b.deltas = append(b.deltas, d)
+		b.size += len(d)
+		for b.size > b.capacity && len(b.deltas) > 0 {
+			b.size -= len(b.deltas[0])
+			b.deltas = b.deltas[1:]
+		}
+	} else {
+		// States of different sizes can't be diffed, forget the history
+		b.Reset()
+	}
+	b.current = append(b.current[:0], state...)
+}
+
+// Pop steps back to the state preceding the newest one, which is dropped,
+// and returns it. Once the history is exhausted, the oldest state is
+// returned again. Pop returns nil if no state was recorded. The returned
+// slice is only valid until the next Push.
+func (b *Buffer) Pop() []byte {
+	if len(b.deltas) == 0 {
+		return b.current
+	}
+	last := len(b.deltas) - 1
+	d, err := decompress(b.deltas[last], len(b.current))
+	b.size -= len(b.deltas[last])
+	b.deltas = b.deltas[:last]
+	if err != nil {
+		log.Println("[Rewind]:", err)
+		b.deltas = nil
+		b.size = 0
+		return b.current
+	}
+	b.current = xor(b.current, d)
+	return b.current
+}
+
+// Len returns the number of states that can be stepped back to
+func (b *Buffer) Len() int {
+	return len(b.deltas)
+}
+
+// Reset forgets all the states
+func (b *Buffer) Reset() {
+	b.deltas = nil
+	b.size = 0
+	b.current = nil
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func decompress(data []byte, size int) ([]byte, error) {
+	out := make([]byte, size)
+	_, err := io.ReadFull(flate.NewReader(bytes.NewReader(data)), out)
+	return out, err
+}
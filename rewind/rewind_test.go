@@ -0,0 +1,77 @@
+package rewind
+
+import (
+	"bytes"
+	"testing"
+)
+
+// state fakes a savestate where only a counter changes between frames
+func state(frame byte) []byte {
+	s := make([]byte, 4096)
+	s[100] = frame
+	s[2000] = frame * 3
+	return s
+}
+
+func TestBuffer(t *testing.T) {
+	t.Run("Steps back through the recorded states", func(t *testing.T) {
+		b := New(1 << 20)
+		for i := byte(0); i < 5; i++ {
+			b.Push(state(i))
+		}
+		for i := byte(3); i > 0; i-- {
+			if got := b.Pop(); !bytes.Equal(got, state(i)) {
+				t.Errorf("got = %v, want %v", got[100], i)
+			}
+		}
+	})
+
+	t.Run("Stays on the oldest state once exhausted", func(t *testing.T) {
+		b := New(1 << 20)
+		b.Push(state(1))
+		b.Push(state(2))
+		b.Pop()
+		if got := b.Pop(); !bytes.Equal(got, state(1)) {
+			t.Errorf("got = %v, want %v", got[100], 1)
+		}
+		if got := New(10).Pop(); got != nil {
+			t.Errorf("got = %v, want %v", got, nil)
+		}
+	})
+
+	t.Run("Compresses the deltas", func(t *testing.T) {
+		b := New(1 << 20)
+		for i := byte(0); i < 10; i++ {
+			b.Push(state(i))
+		}
+		if b.size >= 9*len(state(0))/10 {
+			t.Errorf("got = %v, want less than %v", b.size, 9*len(state(0))/10)
+		}
+	})
+
+	t.Run("Drops the oldest states when full", func(t *testing.T) {
+		b := New(1 << 20)
+		b.Push(state(0))
+		b.Push(state(1))
+		b.capacity = b.size * 3
+		for i := byte(2); i < 10; i++ {
+			b.Push(state(i))
+		}
+		if b.Len() > 3 || b.size > b.capacity {
+			t.Errorf("got = %v %v, want at most 3 deltas within %v", b.Len(), b.size, b.capacity)
+		}
+		if got := b.Pop(); !bytes.Equal(got, state(8)) {
+			t.Errorf("got = %v, want %v", got[100], 8)
+		}
+	})
+
+	t.Run("Forgets the history when the state size changes", func(t *testing.T) {
+		b := New(1 << 20)
+		b.Push(state(0))
+		b.Push(state(1))
+		b.Push([]byte{1, 2, 3})
+		if b.Len() != 0 {
+			t.Errorf("got = %v, want %v", b.Len(), 0)
+		}
+	})
+}
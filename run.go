@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/ludo"
+	"github.com/libretro/ludo/settings"
+)
+
+// run implements "ludo run", which plays a game outside of the kiosk flow.
+// It returns the exit code of the program.
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	headless := flags.Bool("headless", false, "Run without window nor audio device")
+	frames := flags.Int("frames", 600, "Number of frames to run")
+	script := flags.String("input", "", "Input script to play, see input.ParseScript")
+	movie := flags.String("movie", "", "Movie file to play back, failing if it goes out of sync")
+	record := flags.String("record", "", "Record the inputs to this movie file")
+	dumpFrame := flags.String("dump-frame", "", "Write the last frame to this PNG file")
+	dumpAudio := flags.String("dump-audio", "", "Write the audio to this file, as signed 16-bit stereo PCM")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ludo run --headless [flags] core [game]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+	if !*headless {
+		fmt.Fprintln(os.Stderr, "ludo run only supports --headless for now")
+		return 2
+	}
+	if *movie != "" && *record != "" {
+		fmt.Fprintln(os.Stderr, "--movie and --record can't be used together")
+		return 2
+	}
+
+	if err := settings.Load(); err != nil {
+		fmt.Println("Failed to load settings, using defaults:", err)
+	}
+
+	cfg := ludo.HeadlessConfig{
+		CorePath: flags.Arg(0),
+		GamePath: flags.Arg(1),
+		Frames:   *frames,
+		Movie:    *movie,
+		Record:   *record,
+	}
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		cfg.Script, err = input.ParseScript(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *script, err)
+			return 1
+		}
+	}
+
+	res, err := ludo.RunHeadless(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if res.Frame == nil {
+		fmt.Fprintln(os.Stderr, "The core didn't render any frame")
+		return 1
+	}
+
+	if *dumpFrame != "" {
+		f, err := os.Create(*dumpFrame)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		err = png.Encode(f, res.Frame)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if *dumpAudio != "" {
+		if err := os.WriteFile(*dumpAudio, res.Audio, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	fmt.Printf("Ran %d frames, last frame %dx%d, %d audio samples at %d Hz\n",
+		cfg.Frames, res.Frame.Bounds().Dx(), res.Frame.Bounds().Dy(), len(res.Audio)/4, res.SampleRate)
+	return 0
+}
@@ -0,0 +1,88 @@
+// Package runahead removes the input lag inherent to some games. Each video
+// frame, the core is run a few frames ahead of time with audio and video
+// hidden, the last frame is shown, and the core is rolled back with a
+// savestate to the real frame.
+package runahead
+
+import "errors"
+
+// ErrNotSerializable is returned by Run for cores without savestates
+var ErrNotSerializable = errors.New("the core doesn't support savestates")
+
+// Emulator is the part of a libretro core needed to run ahead
+type Emulator interface {
+	Run()
+	SerializeSize() uint
+	Serialize(size uint) ([]byte, error)
+	Unserialize(bytes []byte, size uint) error
+}
+
+// Runner runs an Emulator ahead of time
+type Runner struct {
+	// Frames is the number of frames to run ahead. 0 disables run-ahead.
+	Frames int
+	// Secondary is an optional second instance of the core. When set, the
+	// frames ahead are run on it so the main instance is never rolled back,
+	// for cores whose savestates have side effects.
+	Secondary Emulator
+	// SetAVEnabled hides the audio and video of the frames run ahead
+	SetAVEnabled func(bool)
+
+	failed bool
+}
+
+// Run runs the real frame of e, and the frames ahead if enabled. After an
+// error, run-ahead stays disabled until Reset is called.
+func (r *Runner) Run(e Emulator) error {
+	if r.Frames <= 0 || r.failed {
+		e.Run()
+		return nil
+	}
+
+	size := e.SerializeSize()
+	if size == 0 {
+		r.failed = true
+		e.Run()
+		return ErrNotSerializable
+	}
+
+	r.SetAVEnabled(false)
+	defer r.SetAVEnabled(true)
+
+	e.Run()
+	s, err := e.Serialize(size)
+	if err != nil {
+		r.failed = true
+		return err
+	}
+
+	ahead := e
+	if r.Secondary != nil {
+		ahead = r.Secondary
+		if err := ahead.Unserialize(s, size); err != nil {
+			r.failed = true
+			return err
+		}
+	}
+
+	for i := 1; i < r.Frames; i++ {
+		ahead.Run()
+	}
+	r.SetAVEnabled(true)
+	ahead.Run()
+
+	if r.Secondary != nil {
+		return nil
+	}
+	if err := e.Unserialize(s, size); err != nil {
+		r.failed = true
+		return err
+	}
+	return nil
+}
+
+// Reset enables run-ahead again after an error, typically when a new game
+// is loaded
+func (r *Runner) Reset() {
+	r.failed = false
+}
@@ -0,0 +1,107 @@
+package runahead
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeEmulator counts frames and records the ones that were shown
+type fakeEmulator struct {
+	frame    uint32
+	av       *bool
+	shown    []uint32
+	noStates bool
+}
+
+func (e *fakeEmulator) Run() {
+	e.frame++
+	if *e.av {
+		e.shown = append(e.shown, e.frame)
+	}
+}
+
+func (e *fakeEmulator) SerializeSize() uint {
+	if e.noStates {
+		return 0
+	}
+	return 4
+}
+
+func (e *fakeEmulator) Serialize(size uint) ([]byte, error) {
+	b := make([]byte, size)
+	binary.LittleEndian.PutUint32(b, e.frame)
+	return b, nil
+}
+
+func (e *fakeEmulator) Unserialize(bytes []byte, size uint) error {
+	e.frame = binary.LittleEndian.Uint32(bytes)
+	return nil
+}
+
+func newRunner(frames int) (*Runner, *bool) {
+	av := true
+	return &Runner{Frames: frames, SetAVEnabled: func(b bool) { av = b }}, &av
+}
+
+func TestRunner(t *testing.T) {
+	t.Run("Shows the frames ahead and rolls back to the real frame", func(t *testing.T) {
+		r, av := newRunner(2)
+		e := &fakeEmulator{av: av}
+		for i := 0; i < 3; i++ {
+			if err := r.Run(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if e.frame != 3 {
+			t.Errorf("got = %v, want %v", e.frame, 3)
+		}
+		want := []uint32{3, 4, 5}
+		if !reflect.DeepEqual(e.shown, want) {
+			t.Errorf("got = %v, want %v", e.shown, want)
+		}
+		if !*av {
+			t.Errorf("got = %v, want %v", *av, true)
+		}
+	})
+
+	t.Run("Runs the frames ahead on the second instance", func(t *testing.T) {
+		r, av := newRunner(1)
+		e := &fakeEmulator{av: av}
+		r.Secondary = &fakeEmulator{av: av}
+		r.Run(e)
+		r.Run(e)
+		if e.frame != 2 || len(e.shown) != 0 {
+			t.Errorf("got = %v %v, want %v %v", e.frame, e.shown, 2, []uint32{})
+		}
+		want := []uint32{2, 3}
+		if got := r.Secondary.(*fakeEmulator).shown; !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Falls back to normal frames for cores without savestates", func(t *testing.T) {
+		r, av := newRunner(2)
+		e := &fakeEmulator{av: av, noStates: true}
+		if err := r.Run(e); !errors.Is(err, ErrNotSerializable) {
+			t.Errorf("got = %v, want %v", err, ErrNotSerializable)
+		}
+		if err := r.Run(e); err != nil {
+			t.Errorf("got = %v, want %v", err, nil)
+		}
+		want := []uint32{1, 2}
+		if !reflect.DeepEqual(e.shown, want) {
+			t.Errorf("got = %v, want %v", e.shown, want)
+		}
+	})
+
+	t.Run("Runs normal frames when disabled", func(t *testing.T) {
+		r, av := newRunner(0)
+		e := &fakeEmulator{av: av}
+		r.Run(e)
+		if !reflect.DeepEqual(e.shown, []uint32{1}) {
+			t.Errorf("got = %v, want %v", e.shown, []uint32{1})
+		}
+	})
+}
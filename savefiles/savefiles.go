@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"unsafe"
 
@@ -17,11 +18,54 @@ import (
 
 var mutex sync.Mutex
 
+// saveFile is a persistent memory of the core and the file it is saved to
+type saveFile struct {
+	path    string
+	memType uint32
+}
+
 // path returns the path of the SRAM file for the current core
 func path() string {
 	return filepath.Join(
 		settings.Current.SavefilesDirectory,
-		utils.FileName(state.GamePath)+".srm")
+		utils.FileName(state.ContentPath())+".srm")
+}
+
+// contentSet names a set of ROMs loaded together with a subsystem
+func contentSet(paths []string) string {
+	names := []string{}
+	for _, p := range paths {
+		if p != "" {
+			names = append(names, utils.FileName(p))
+		}
+	}
+	return strings.Join(names, " + ")
+}
+
+// saveFiles lists the memories to persist for the current game. Games loaded
+// with a subsystem get a directory per subsystem and per set of ROMs, so the
+// same Game Boy game keeps separate saves with and without the Super Game
+// Boy, and the extra memories of each ROM are saved next to the SRAM.
+func saveFiles() []saveFile {
+	if state.Subsystem == nil {
+		return []saveFile{{path(), libretro.MemorySaveRAM}}
+	}
+
+	set := contentSet(state.SubsystemPaths)
+	dir := filepath.Join(settings.Current.SavefilesDirectory, state.Subsystem.Ident, set)
+	files := []saveFile{{filepath.Join(dir, set+".srm"), libretro.MemorySaveRAM}}
+	for i, rom := range state.Subsystem.ROMs {
+		if i >= len(state.SubsystemPaths) || state.SubsystemPaths[i] == "" {
+			continue
+		}
+		for _, m := range rom.Memory {
+			files = append(files, saveFile{
+				filepath.Join(dir, utils.FileName(state.SubsystemPaths[i])+"."+m.Extension),
+				m.Type,
+			})
+		}
+	}
+	return files
 }
 
 // SaveSRAM saves the game SRAM to the filesystem
@@ -33,20 +77,35 @@ func SaveSRAM() error {
 		return errors.New("core not running")
 	}
 
-	len := state.Core.GetMemorySize(libretro.MemorySaveRAM)
-	ptr := state.Core.GetMemoryData(libretro.MemorySaveRAM)
-	if ptr == nil || len == 0 {
+	saved := false
+	for _, f := range saveFiles() {
+		len := state.Core.GetMemorySize(f.memType)
+		ptr := state.Core.GetMemoryData(f.memType)
+		if ptr == nil || len == 0 {
+			continue
+		}
+
+		// convert the C array to a go slice
+		bytes := C.GoBytes(ptr, C.int(len))
+		if err := writeFile(f.path, bytes); err != nil {
+			return err
+		}
+		saved = true
+	}
+
+	if !saved {
 		return errors.New("unable to get SRAM address")
 	}
+	return nil
+}
 
-	// convert the C array to a go slice
-	bytes := C.GoBytes(ptr, C.int(len))
-	err := os.MkdirAll(settings.Current.SavefilesDirectory, os.ModePerm)
+func writeFile(path string, bytes []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	fd, err := os.Create(path())
+	fd, err := os.Create(path)
 	if err != nil {
 		return err
 	}
@@ -74,20 +133,30 @@ func LoadSRAM() error {
 		return errors.New("core not running")
 	}
 
-	len := state.Core.GetMemorySize(libretro.MemorySaveRAM)
-	ptr := state.Core.GetMemoryData(libretro.MemorySaveRAM)
-	if ptr == nil || len == 0 {
-		return errors.New("unable to get SRAM address")
+	// A missing file doesn't prevent loading the other memories
+	loaded := false
+	err := errors.New("unable to get SRAM address")
+	for _, f := range saveFiles() {
+		len := state.Core.GetMemorySize(f.memType)
+		ptr := state.Core.GetMemoryData(f.memType)
+		if ptr == nil || len == 0 {
+			continue
+		}
+
+		// this *[1 << 30]byte points to the same memory as ptr, allowing to
+		// overwrite this memory
+		destination := (*[1 << 30]byte)(unsafe.Pointer(ptr))[:len:len]
+		source, readErr := os.ReadFile(f.path)
+		if readErr != nil {
+			err = readErr
+			continue
+		}
+		copy(destination, source)
+		loaded = true
 	}
 
-	// this *[1 << 30]byte points to the same memory as ptr, allowing to
-	// overwrite this memory
-	destination := (*[1 << 30]byte)(unsafe.Pointer(ptr))[:len:len]
-	source, err := os.ReadFile(path())
-	if err != nil {
+	if !loaded {
 		return err
 	}
-	copy(destination, source)
-
 	return nil
 }
@@ -0,0 +1,74 @@
+package savefiles
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+)
+
+func Test_saveFiles(t *testing.T) {
+	settings.Current.SavefilesDirectory = "/saves"
+	defer func() {
+		state.GamePath = ""
+		state.Subsystem = nil
+		state.SubsystemPaths = nil
+	}()
+
+	t.Run("Games save their SRAM next to the others", func(t *testing.T) {
+		state.GamePath = "/roms/Tetris.gb"
+		state.Subsystem = nil
+		got := saveFiles()
+		want := []saveFile{{"/saves/Tetris.srm", libretro.MemorySaveRAM}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Subsystems save each set of ROMs in its own directory", func(t *testing.T) {
+		state.GamePath = "/roms/Tetris.gb"
+		state.Subsystem = &libretro.SubsystemInfo{
+			Desc:  "Super GameBoy",
+			Ident: "sgb",
+			ROMs: []libretro.SubsystemROMInfo{
+				{Desc: "GameBoy", Required: true, Memory: []libretro.SubsystemMemoryInfo{{Extension: "sav", Type: 0x101}}},
+				{Desc: "Super GameBoy BIOS", Required: true},
+			},
+		}
+		state.SubsystemPaths = []string{"/roms/Tetris.gb", "/bios/sgb.sfc"}
+		got := saveFiles()
+		dir := filepath.Join("/saves", "sgb", "Tetris + sgb")
+		want := []saveFile{
+			{filepath.Join(dir, "Tetris + sgb.srm"), libretro.MemorySaveRAM},
+			{filepath.Join(dir, "Tetris.sav"), 0x101},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Skipped ROMs are not part of the set", func(t *testing.T) {
+		state.GamePath = "/bios/stbios.bin"
+		state.Subsystem = &libretro.SubsystemInfo{
+			Ident: "sufami",
+			ROMs: []libretro.SubsystemROMInfo{
+				{Desc: "BIOS", Required: true},
+				{Desc: "Slot A", Memory: []libretro.SubsystemMemoryInfo{{Extension: "srm", Type: 0x102}}},
+				{Desc: "Slot B", Memory: []libretro.SubsystemMemoryInfo{{Extension: "srm", Type: 0x103}}},
+			},
+		}
+		state.SubsystemPaths = []string{"/bios/stbios.bin", "/roms/Slot A.st", ""}
+		got := saveFiles()
+		dir := filepath.Join("/saves", "sufami", "stbios + Slot A")
+		want := []saveFile{
+			{filepath.Join(dir, "stbios + Slot A.srm"), libretro.MemorySaveRAM},
+			{filepath.Join(dir, "Slot A.srm"), 0x102},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/libretro/ludo/dat"
+	"github.com/libretro/ludo/discs"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/playlists"
 	"github.com/libretro/ludo/settings"
@@ -61,6 +62,9 @@ func ScanDir(dir string, doneCb func()) {
 			if len(game.Description) == 0 {
 				continue
 			}
+			if strings.ToLower(filepath.Ext(game.Path)) == ".m3u" {
+				game.Description = discs.StripDisc(game.Description)
+			}
 			f.WriteString(game.Path + "\t")
 			f.WriteString(game.Description + "\t")
 			if game.ROMs[0].CRC > 0 {
@@ -100,9 +104,38 @@ var headerSizes = map[string]uint{
 	".lnx": 64,
 }
 
+// multiDiscSets finds the games made of several discs in a list of roms and
+// generates their m3u playlists when missing. It maps each disc to its set.
+func multiDiscSets(roms []string, n *ntf.Notification) map[string]discs.Set {
+	sets := map[string]discs.Set{}
+	for _, set := range discs.FindSets(roms) {
+		if _, err := os.Stat(set.M3U); os.IsNotExist(err) {
+			if err := discs.WriteM3U(set); err != nil {
+				n.Update(ntf.Error, err.Error())
+				continue
+			}
+		}
+		for _, disc := range set.Discs {
+			sets[disc] = set
+		}
+	}
+	return sets
+}
+
 // Scan scans a list of roms against the database
 func Scan(dir string, roms []string, games chan (dat.Game), n *ntf.Notification) {
+	sets := multiDiscSets(roms, n)
 	for i, f := range roms {
+		// Multi-disc games are added once, through their m3u playlist. The first
+		// disc is used to find the game in the database.
+		path := f
+		if set, ok := sets[f]; ok {
+			if f != set.Discs[0] {
+				continue
+			}
+			path = set.M3U
+		}
+
 		ext := filepath.Ext(f)
 		switch ext {
 		case ".zip":
@@ -134,7 +167,7 @@ func Scan(dir string, roms []string, games chan (dat.Game), n *ntf.Notification)
 			z.Close()
 		case ".cue", ".pbp", ".m3u":
 			// Look for a matching game entry in the database
-			state.DB.FindByROMName(f, filepath.Base(f), 0, games)
+			state.DB.FindByROMName(path, filepath.Base(f), 0, games)
 			n.Update(ntf.Info, strconv.Itoa(i)+"/"+strconv.Itoa(len(roms))+" "+f)
 		case ".32x", ".a26", "a52", ".a78", ".col", ".crt", ".d64", ".pce", ".fds", ".gb", ".gba", ".gbc", ".gen", ".gg", ".ipf", ".j64", ".jag", ".lnx", ".md", ".n64", ".nes", ".ngc", ".nds", ".rom", ".sfc", ".sg", ".smc", ".smd", ".sms", ".ws", ".wsc", ".z64":
 			bytes, err := os.ReadFile(f)
@@ -148,10 +181,10 @@ func Scan(dir string, roms []string, games chan (dat.Game), n *ntf.Notification)
 				continue
 			}
 			crc := crc32.ChecksumIEEE(bytes)
-			state.DB.FindByCRC(f, utils.FileName(f), crc, s.Size(), games)
+			state.DB.FindByCRC(path, utils.FileName(f), crc, s.Size(), games)
 			if headerSize, ok := headerSizes[ext]; ok {
 				crcHeaderless := crc32.ChecksumIEEE(bytes[headerSize:])
-				state.DB.FindByCRC(f, utils.FileName(f), crcHeaderless, s.Size()-int64(headerSize), games)
+				state.DB.FindByCRC(path, utils.FileName(f), crcHeaderless, s.Size()-int64(headerSize), games)
 			}
 			n.Update(ntf.Info, strconv.Itoa(i)+"/"+strconv.Itoa(len(roms))+" "+f)
 		}
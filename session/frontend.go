@@ -0,0 +1,93 @@
+package session
+
+import (
+	"github.com/libretro/ludo/catalog"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/voucher"
+)
+
+// PricePerMinute is the price of one minute of play for one player
+const PricePerMinute = 0.5
+
+// Frontend renders the kiosk screens. The Controller calls it on every step
+// of the flow, and the frontend forwards the choices of the player back to
+// the Controller. Methods can be called from any goroutine.
+type Frontend interface {
+	// ShowCatalog shows the games the player is allowed to pick from
+	ShowCatalog(games catalog.Catalog)
+	// ShowTimeSelect asks how long to play the game, for up to seats players
+	ShowTimeSelect(game catalog.Game, seats int)
+	// ShowPayment asks the players to pay for the offer
+	ShowPayment(offer Offer)
+	// ShowExtend asks how much time to add to a paused session
+	ShowExtend()
+	// OnGameLoaded is called when the game runs, after a launch or after the
+	// session was extended. The frontend should get out of the way.
+	OnGameLoaded()
+	// OnTimeout is called when every seat is out of time and the game is
+	// paused. The frontend should come back in front of the game window.
+	OnTimeout(window Window)
+}
+
+// VoucherEntry is implemented by frontends able to take voucher codes. The
+// voucher step is skipped for the others.
+type VoucherEntry interface {
+	// ShowVoucher asks for a voucher code. err is the reason the previous
+	// code was refused, or nil.
+	ShowVoucher(err error)
+}
+
+// SeatNotifier is implemented by frontends showing the seats of multiplayer
+// cabinets
+type SeatNotifier interface {
+	OnSeatExpired(seat int)
+	OnSeatExtended(seat, minutes int)
+}
+
+// TimeoutWarner is implemented by frontends warning the players before the
+// game pauses
+type TimeoutWarner interface {
+	OnPrepareTimeout()
+}
+
+// DeviceNotifier is implemented by frontends showing what the game does with
+// the rumble motors, LEDs and sensors. OnDeviceEvent must not block.
+type DeviceNotifier interface {
+	OnDeviceEvent(e input.DeviceEvent)
+}
+
+// Window is the position and size of the game window when it was paused
+type Window struct {
+	X, Y, Width, Height int
+}
+
+// Offer is what the players are about to pay for
+type Offer struct {
+	Game      catalog.Game
+	Minutes   int              // Minutes bought by each player
+	Players   int              // Number of seats paid for
+	Extension bool             // Adds time to a paused session
+	Voucher   *voucher.Voucher // Applies to the first seat only
+}
+
+// SeatMinutes returns the minutes of play of each seat
+func (o Offer) SeatMinutes() []int {
+	minutes := make([]int, o.Players)
+	for i := range minutes {
+		minutes[i] = o.Minutes
+	}
+	if o.Voucher != nil && len(minutes) > 0 {
+		minutes[0], _ = o.Voucher.Apply(o.Minutes, PricePerMinute)
+	}
+	return minutes
+}
+
+// Price is the total price of the offer
+func (o Offer) Price() float64 {
+	price := float64(o.Minutes*o.Players) * PricePerMinute
+	if o.Voucher != nil && o.Players > 0 {
+		_, first := o.Voucher.Apply(o.Minutes, PricePerMinute)
+		price += first - float64(o.Minutes)*PricePerMinute
+	}
+	return price
+}
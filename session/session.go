@@ -0,0 +1,537 @@
+// Package session drives the kiosk flow shared by every frontend: picking a
+// game, choosing a play time, paying, and extending the session when the
+// timer runs out. It owns the business rules (parental controls, vouchers,
+// seats, telemetry) so that frontends only render screens and forward the
+// choices of the players.
+package session
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/catalog"
+	"github.com/libretro/ludo/firmware"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/ludo"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/telemetry"
+	"github.com/libretro/ludo/voucher"
+)
+
+// State is a step of the kiosk flow
+type State int
+
+// The steps of the kiosk flow
+const (
+	SelectGame State = iota
+	TimeSelect
+	Payment
+	ExtendTime
+	ExtendPayment
+	GameLoading
+	GameActive
+	Voucher
+)
+
+// Controller holds the state of the kiosk session
+type Controller struct {
+	games      catalog.Catalog
+	policy     catalog.Policy
+	policyErr  error     // why the parental controls fell back to catalog.StrictPolicy
+	signalChan chan int  // signals of ludo, see Watch
+	timerChan  chan int  // seconds bought, sent to ludo
+	resumeChan chan bool // resumes the game paused by a timeout
+	recorder   *telemetry.Recorder
+	frontend   Frontend
+
+	mu           sync.Mutex
+	state        State
+	offer        Offer
+	voucherCode  string    // Entered before payment, redeemed at launch
+	sessionStart time.Time // Zero when no session is running
+
+	// run launches the game, it is replaced in tests
+	run func(game catalog.Game, seatSecs []int) error
+}
+
+// NewController creates the controller of a kiosk selling the games of the
+// catalog. The channels are shared with ludo.RunGame.
+func NewController(games catalog.Catalog, signalChan, timerChan chan int, resumeChan chan bool) *Controller {
+	policy, err := catalog.LoadPolicy(catalog.PolicyFiles...)
+	if err != nil {
+		log.Printf("Error loading parental controls, only showing games for all ages: %v", err)
+	}
+
+	c := &Controller{
+		games:      games,
+		policy:     policy,
+		policyErr:  err,
+		signalChan: signalChan,
+		timerChan:  timerChan,
+		resumeChan: resumeChan,
+		recorder: telemetry.NewRecorder(
+			filepath.Join(xdg.DataHome, "ludo", "telemetry"),
+			settings.Current.TelemetryRetentionDays,
+		),
+	}
+	c.run = func(game catalog.Game, seatSecs []int) error {
+		return ludo.RunGame(game.CorePath, game.GamePath, game.Cheats, sessionProfile(time.Now()), seatSecs, c.signalChan, c.timerChan, c.resumeChan)
+	}
+	return c
+}
+
+// sessionProfile names the achievements profile of a session started at
+// start. Kiosk players don't log in, so each session unlocks its own.
+func sessionProfile(start time.Time) string {
+	return "session-" + start.Format("20060102-150405")
+}
+
+// SetFrontend attaches the frontend rendering the session
+func (c *Controller) SetFrontend(f Frontend) {
+	c.frontend = f
+	if n, ok := f.(DeviceNotifier); ok {
+		input.SetDeviceListener(n.OnDeviceEvent)
+	}
+}
+
+// SetIlluminance changes the ambient light seen by games with a solar sensor
+func (c *Controller) SetIlluminance(lux float32) {
+	input.SetIlluminance(lux)
+}
+
+// Catalog returns the games allowed by the parental controls right now, and
+// whose core has the firmware it needs
+func (c *Controller) Catalog() catalog.Catalog {
+	games := catalog.Catalog{}
+	for _, g := range c.policy.Filter(c.games, time.Now()) {
+		if firmwareReady(g) {
+			games = append(games, g)
+		}
+	}
+	return games
+}
+
+// firmwareReady tells if no required firmware of the core of a game is
+// missing from the system directory
+func firmwareReady(game catalog.Game) bool {
+	return firmware.Ready(game.CorePath, settings.Current.SystemDirectory)
+}
+
+// CoreFirmware is the firmware status of a core, and the catalog games it runs
+type CoreFirmware struct {
+	Core  string            `json:"core"`
+	Ready bool              `json:"ready"`
+	Games []string          `json:"games"`
+	Files []firmware.Report `json:"files"`
+}
+
+// Firmware checks the firmware of the cores of the catalog and of the
+// installed cores
+func (c *Controller) Firmware() []CoreFirmware {
+	cores := []string{}
+	games := map[string][]string{}
+	for _, g := range c.games {
+		if _, ok := games[g.CorePath]; !ok {
+			cores = append(cores, g.CorePath)
+		}
+		games[g.CorePath] = append(games[g.CorePath], g.Name)
+	}
+	for _, path := range firmware.Cores() {
+		if _, ok := games[path]; !ok {
+			cores = append(cores, path)
+			games[path] = []string{}
+		}
+	}
+
+	status := []CoreFirmware{}
+	for _, path := range cores {
+		reports := firmware.Check(path, settings.Current.SystemDirectory)
+		if len(reports) == 0 {
+			continue
+		}
+		status = append(status, CoreFirmware{
+			Core:  filepath.Base(path),
+			Ready: firmware.Ready(path, settings.Current.SystemDirectory),
+			Games: games[path],
+			Files: reports,
+		})
+	}
+	return status
+}
+
+// PolicyError returns why the parental controls couldn't be loaded, or nil.
+// Only the games for all ages are shown until the operator fixes the files.
+func (c *Controller) PolicyError() error {
+	return c.policyErr
+}
+
+// Seats is the number of players that can buy time on this cabinet
+func (c *Controller) Seats() int {
+	if settings.Current.KioskSeats < 1 {
+		return 1
+	}
+	if settings.Current.KioskSeats > input.MaxPlayers {
+		return input.MaxPlayers
+	}
+	return settings.Current.KioskSeats
+}
+
+// Telemetry returns the recorder of the usage analytics
+func (c *Controller) Telemetry() *telemetry.Recorder {
+	return c.recorder
+}
+
+// State returns the current step of the flow
+func (c *Controller) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// setState moves to another step and records the analytics derived from it.
+// It must be called with the lock held.
+func (c *Controller) setState(next State) {
+	prev := c.state
+	c.state = next
+	if prev == next {
+		return
+	}
+
+	game := c.offer.Game.Name
+	switch {
+	case next == Payment:
+		c.recorder.Record(telemetry.PaymentStep, game, 0)
+	case prev == Payment && next == SelectGame:
+		c.recorder.Record(telemetry.PaymentAbandoned, game, 0)
+	case next == ExtendTime && prev != ExtendPayment:
+		c.recorder.Record(telemetry.Timeout, game, 0)
+	}
+
+	if next == SelectGame {
+		c.voucherCode = ""
+		c.offer = Offer{}
+	}
+}
+
+// vouchersEnabled tells if the voucher step should be shown before payment
+func (c *Controller) vouchersEnabled() bool {
+	_, ok := c.frontend.(VoucherEntry)
+	return ok && settings.Current.VoucherSecret != ""
+}
+
+// SelectGame is called when the player picks a game in the catalog
+func (c *Controller) SelectGame(name string) {
+	c.mu.Lock()
+	if c.state != SelectGame {
+		c.mu.Unlock()
+		return
+	}
+	game, ok := c.games.Find(name)
+	if ok && !firmwareReady(game) {
+		ok = false
+		log.Printf("Game %s is missing firmware: %s", name, firmware.Summary(firmware.Check(game.CorePath, settings.Current.SystemDirectory)))
+	}
+	if !ok || !c.policy.Allows(game, time.Now()) {
+		c.mu.Unlock()
+		log.Printf("Game %s is not available", name)
+		c.frontend.ShowCatalog(c.Catalog())
+		return
+	}
+	log.Printf("Game selected: %s", name)
+	c.offer = Offer{Game: game, Players: 1}
+	c.setState(TimeSelect)
+	c.mu.Unlock()
+
+	c.frontend.ShowTimeSelect(game, c.Seats())
+}
+
+// SelectTime is called when the players chose how long to play. During an
+// extension, only the first seat pays.
+func (c *Controller) SelectTime(minutes, players int) {
+	if minutes < 1 {
+		minutes = 1
+	}
+	if players < 1 || players > c.Seats() {
+		players = 1
+	}
+
+	c.mu.Lock()
+	switch c.state {
+	case TimeSelect:
+		c.offer.Minutes = minutes
+		c.offer.Players = players
+		if c.vouchersEnabled() {
+			c.setState(Voucher)
+			c.mu.Unlock()
+			c.frontend.(VoucherEntry).ShowVoucher(nil)
+			return
+		}
+		c.setState(Payment)
+	case ExtendTime:
+		c.offer = Offer{Game: c.offer.Game, Minutes: minutes, Players: 1, Extension: true}
+		c.setState(ExtendPayment)
+	case ExtendPayment:
+		// The player went back to change the time, no need to show it again
+		c.offer.Minutes = minutes
+		c.mu.Unlock()
+		return
+	default:
+		state := c.state
+		c.mu.Unlock()
+		log.Printf("Ignoring time selection in state %d", state)
+		return
+	}
+	offer := c.offer
+	c.mu.Unlock()
+
+	c.frontend.ShowPayment(offer)
+}
+
+// EnterVoucher checks a code typed by the player and remembers it until the
+// payment is made. The code is only redeemed once the game launches, so that
+// walking away from the payment screen doesn't burn it. An empty code skips
+// the voucher step.
+func (c *Controller) EnterVoucher(code string) {
+	c.mu.Lock()
+	if c.state != Voucher {
+		c.mu.Unlock()
+		return
+	}
+
+	if code != "" {
+		v, err := voucher.Local.Check([]byte(settings.Current.VoucherSecret), code)
+		if err != nil {
+			c.mu.Unlock()
+			log.Printf("Voucher rejected: %v", err)
+			c.frontend.(VoucherEntry).ShowVoucher(err)
+			return
+		}
+		log.Printf("Voucher accepted: %s", v)
+		c.voucherCode = code
+		c.offer.Voucher = &v
+	}
+
+	c.setState(Payment)
+	offer := c.offer
+	c.mu.Unlock()
+
+	c.frontend.ShowPayment(offer)
+}
+
+// Cancel brings the player back to the catalog before paying
+func (c *Controller) Cancel() {
+	c.mu.Lock()
+	switch c.state {
+	case TimeSelect, Voucher, Payment:
+		c.setState(SelectGame)
+		c.mu.Unlock()
+		c.frontend.ShowCatalog(c.Catalog())
+	default:
+		c.mu.Unlock()
+	}
+}
+
+// Pay is called when the players paid for the current offer. It launches
+// the game, or resumes it in case of an extension.
+func (c *Controller) Pay() {
+	c.mu.Lock()
+	switch c.state {
+	case Payment:
+		c.launch()
+	case ExtendTime, ExtendPayment:
+		c.extend()
+	default:
+		state := c.state
+		c.mu.Unlock()
+		log.Printf("Ignoring payment in state %d", state)
+	}
+}
+
+// launch redeems the voucher and runs the game. It must be called with the
+// lock held, and releases it.
+func (c *Controller) launch() {
+	offer := c.offer
+	if c.voucherCode != "" {
+		if _, err := voucher.Local.Redeem([]byte(settings.Current.VoucherSecret), c.voucherCode); err != nil {
+			log.Printf("Could not redeem voucher: %v", err)
+			offer.Voucher = nil
+		}
+		c.voucherCode = ""
+	}
+	c.setState(GameLoading)
+	c.mu.Unlock()
+
+	seatMinutes := offer.SeatMinutes()
+	log.Printf("Launching game: %s for %v minutes", offer.Game.Name, seatMinutes)
+
+	total := 0
+	seatSecs := make([]int, len(seatMinutes))
+	for i, minutes := range seatMinutes {
+		total += minutes
+		seatSecs[i] = minutes * ludo.SecondsPerMinute
+	}
+	c.recorder.Record(telemetry.SessionStart, offer.Game.Name, total)
+
+	go func() {
+		err := c.run(offer.Game, seatSecs)
+		c.endSession()
+		if err != nil {
+			log.Printf("Error launching game: %v", err)
+			c.mu.Lock()
+			c.setState(SelectGame)
+			c.mu.Unlock()
+			c.frontend.ShowCatalog(c.Catalog())
+		}
+	}()
+}
+
+// extend resumes a paused game with the time bought by the group, shared
+// between the seats that were playing. It must be called with the lock held,
+// and releases it.
+func (c *Controller) extend() {
+	minutes := c.offer.Minutes
+	game := c.offer.Game.Name
+	c.setState(GameActive)
+	c.mu.Unlock()
+
+	log.Printf("Extending the session by %d minutes", minutes)
+	c.recorder.Record(telemetry.Extension, game, minutes)
+
+	// The timer goroutine of ludo waits for the resume signal, then for the
+	// new duration
+	select {
+	case c.resumeChan <- true:
+	case <-time.After(500 * time.Millisecond):
+		log.Println("Warning: Resume channel is full or blocked")
+	}
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case c.timerChan <- minutes * ludo.SecondsPerMinute:
+	case <-time.After(500 * time.Millisecond):
+		log.Println("Warning: Timer channel is full or blocked")
+	}
+
+	c.frontend.OnGameLoaded()
+}
+
+// ExtendSeat adds time to one seat of the running session, while the others
+// keep playing
+func (c *Controller) ExtendSeat(seat, minutes int) {
+	if c.State() != GameActive || seat < 0 || seat >= c.Seats() || minutes <= 0 {
+		log.Printf("Ignoring time extension of %d minutes for seat %d", minutes, seat)
+		return
+	}
+	ludo.AddSeatTime(seat, minutes*ludo.SecondsPerMinute)
+	c.recorder.Record(telemetry.Extension, c.gameName(), minutes)
+
+	if n, ok := c.frontend.(SeatNotifier); ok {
+		n.OnSeatExtended(seat, minutes)
+	}
+}
+
+// Quit ends a paused session instead of extending it
+func (c *Controller) Quit() {
+	c.mu.Lock()
+	if c.state != ExtendTime && c.state != ExtendPayment {
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	log.Println("Player chose to quit game")
+	c.endSession()
+
+	c.mu.Lock()
+	c.setState(SelectGame)
+	c.mu.Unlock()
+	c.frontend.ShowCatalog(c.Catalog())
+}
+
+func (c *Controller) gameName() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offer.Game.Name
+}
+
+// endSession records the length of the session. It does nothing if the
+// session was already closed, so it can be called from every exit path.
+func (c *Controller) endSession() {
+	c.mu.Lock()
+	start := c.sessionStart
+	game := c.offer.Game.Name
+	c.sessionStart = time.Time{}
+	c.mu.Unlock()
+
+	if start.IsZero() {
+		return
+	}
+	c.recorder.Record(telemetry.SessionEnd, game, int(time.Since(start).Seconds()))
+}
+
+// Watch handles the signals sent by ludo through the signal channel. It
+// blocks forever.
+func (c *Controller) Watch() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for range ticker.C {
+		select {
+		case signal := <-c.signalChan:
+			c.handleSignal(signal)
+		default:
+		}
+	}
+}
+
+func (c *Controller) handleSignal(signal int) {
+	switch signal {
+	case ludo.SignalTimeout:
+		log.Println("Session: Received timer expired signal")
+		window := c.readWindow()
+		c.mu.Lock()
+		c.setState(ExtendTime)
+		c.mu.Unlock()
+		c.frontend.ShowExtend()
+		c.frontend.OnTimeout(window)
+
+	case ludo.SignalGameLoaded:
+		log.Println("Session: Received game loaded signal")
+		c.mu.Lock()
+		c.sessionStart = time.Now()
+		c.setState(GameActive)
+		c.mu.Unlock()
+		c.frontend.OnGameLoaded()
+
+	case ludo.SignalPrepareTimeout:
+		log.Println("Session: Received prepare timeout signal")
+		if w, ok := c.frontend.(TimeoutWarner); ok {
+			w.OnPrepareTimeout()
+		}
+
+	default:
+		if seat, ok := ludo.ExpiredSeat(signal); ok {
+			log.Println("Session: Received seat expired signal for seat", seat)
+			if n, ok := c.frontend.(SeatNotifier); ok {
+				n.OnSeatExpired(seat)
+			}
+		}
+	}
+}
+
+// readWindow reads the geometry of the game window, sent by ludo right after
+// the timeout signal
+func (c *Controller) readWindow() Window {
+	values := []int{0, 0, 800, 600}
+	timeout := time.After(200 * time.Millisecond)
+	for i := range values {
+		select {
+		case values[i] = <-c.signalChan:
+		case <-timeout:
+			log.Println("Timeout reading game window geometry")
+			return Window{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+		}
+	}
+	return Window{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+}
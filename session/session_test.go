@@ -0,0 +1,161 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/catalog"
+	"github.com/libretro/ludo/firmware"
+	"github.com/libretro/ludo/ludo"
+	"github.com/libretro/ludo/telemetry"
+	"github.com/libretro/ludo/voucher"
+)
+
+// fakeFrontend records the screens shown by the controller
+type fakeFrontend struct {
+	screens []string
+}
+
+func (f *fakeFrontend) ShowCatalog(games catalog.Catalog) { f.screens = append(f.screens, "catalog") }
+func (f *fakeFrontend) ShowTimeSelect(game catalog.Game, seats int) {
+	f.screens = append(f.screens, "time")
+}
+func (f *fakeFrontend) ShowPayment(offer Offer) { f.screens = append(f.screens, "payment") }
+func (f *fakeFrontend) ShowExtend()             { f.screens = append(f.screens, "extend") }
+func (f *fakeFrontend) OnGameLoaded()           { f.screens = append(f.screens, "loaded") }
+func (f *fakeFrontend) OnTimeout(window Window) { f.screens = append(f.screens, "timeout") }
+
+var testGames = catalog.Catalog{
+	{Name: "Nova", CorePath: "nestopia.so", GamePath: "nova.nes"},
+	{Name: "Retro Hero", Rating: catalog.Age18},
+}
+
+func newTestController(t *testing.T) (*Controller, *fakeFrontend) {
+	f := &fakeFrontend{}
+	c := &Controller{
+		games:      testGames,
+		policy:     catalog.Policy{MaxRating: catalog.Age12},
+		signalChan: make(chan int, 10),
+		timerChan:  make(chan int, 10),
+		resumeChan: make(chan bool, 10),
+		recorder:   telemetry.NewRecorder(t.TempDir(), 0),
+		frontend:   f,
+	}
+	return c, f
+}
+
+func kinds(t *testing.T, c *Controller) []telemetry.Kind {
+	events, err := c.recorder.Events(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []telemetry.Kind
+	for _, e := range events {
+		got = append(got, e.Kind)
+	}
+	return got
+}
+
+func TestController(t *testing.T) {
+	t.Run("Goes back to the catalog and records the abandoned payment", func(t *testing.T) {
+		c, f := newTestController(t)
+		c.SelectGame("Nova")
+		c.SelectTime(5, 1)
+		c.Cancel()
+
+		want := []string{"time", "payment", "catalog"}
+		if !reflect.DeepEqual(f.screens, want) {
+			t.Errorf("got = %v, want %v", f.screens, want)
+		}
+		wantKinds := []telemetry.Kind{telemetry.PaymentStep, telemetry.PaymentAbandoned}
+		if got := kinds(t, c); !reflect.DeepEqual(got, wantKinds) {
+			t.Errorf("got = %v, want %v", got, wantKinds)
+		}
+	})
+
+	t.Run("Refuses games blocked by the parental controls", func(t *testing.T) {
+		c, f := newTestController(t)
+		c.SelectGame("Retro Hero")
+		if c.State() != SelectGame || !reflect.DeepEqual(f.screens, []string{"catalog"}) {
+			t.Errorf("got = %v %v, want %v %v", c.State(), f.screens, SelectGame, []string{"catalog"})
+		}
+	})
+
+	t.Run("Refuses games whose core misses a required firmware", func(t *testing.T) {
+		firmware.Known["nestopia"] = []firmware.File{{Path: "ludo-test-missing.bin"}}
+		defer delete(firmware.Known, "nestopia")
+
+		c, f := newTestController(t)
+		if got := c.Catalog(); len(got) != 0 {
+			t.Errorf("got = %v, want %v", got, catalog.Catalog{})
+		}
+		c.SelectGame("Nova")
+		if c.State() != SelectGame || !reflect.DeepEqual(f.screens, []string{"catalog"}) {
+			t.Errorf("got = %v %v, want %v %v", c.State(), f.screens, SelectGame, []string{"catalog"})
+		}
+
+		fw := c.Firmware()
+		if len(fw) != 1 || fw[0].Ready || fw[0].Files[0].Status != firmware.Missing {
+			t.Errorf("got = %v, want %v", fw, "nestopia missing its firmware")
+		}
+	})
+
+	t.Run("Launches the game with the time of each seat", func(t *testing.T) {
+		c, _ := newTestController(t)
+		launched := make(chan []int, 1)
+		c.run = func(game catalog.Game, seatSecs []int) error {
+			launched <- seatSecs
+			return nil
+		}
+		c.SelectGame("Nova")
+		c.SelectTime(3, 1)
+		c.Pay()
+
+		got := <-launched
+		want := []int{3 * ludo.SecondsPerMinute}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+		if c.State() != GameLoading {
+			t.Errorf("got = %v, want %v", c.State(), GameLoading)
+		}
+	})
+
+	t.Run("Resumes a paused game with the extension", func(t *testing.T) {
+		c, f := newTestController(t)
+		c.offer = Offer{Game: testGames[0]}
+		c.state = GameActive
+		c.SelectTime(1, 1) // ignored while playing
+
+		c.handleSignal(ludo.SignalTimeout)
+		c.SelectTime(2, 1)
+		c.Pay()
+
+		if got := <-c.timerChan; got != 2*ludo.SecondsPerMinute {
+			t.Errorf("got = %v, want %v", got, 2*ludo.SecondsPerMinute)
+		}
+		want := []string{"extend", "timeout", "payment", "loaded"}
+		if !reflect.DeepEqual(f.screens, want) {
+			t.Errorf("got = %v, want %v", f.screens, want)
+		}
+	})
+}
+
+func TestOffer(t *testing.T) {
+	v := voucher.Voucher{Kind: voucher.Minutes, Value: 5}
+	o := Offer{Minutes: 3, Players: 2, Voucher: &v}
+
+	t.Run("Applies the voucher to the first seat", func(t *testing.T) {
+		got := o.SeatMinutes()
+		want := []int{5, 3}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Only the other seats pay", func(t *testing.T) {
+		if got, want := o.Price(), 3*PricePerMinute; got != want {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
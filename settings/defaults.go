@@ -59,15 +59,31 @@ func defaultSettings() Settings {
 			"SNK - Neo Geo Pocket":                           "mednafen_ngp_libretro",
 			"Sony - PlayStation":                             playstationCore,
 		},
-		FileDirectory:        usr.HomeDir,
-		CoresDirectory:       "./cores",
-		AssetsDirectory:      "./assets",
-		DatabaseDirectory:    "./database",
-		SavestatesDirectory:  filepath.Join(xdg.DataHome, "ludo", "savestates"),
-		SavefilesDirectory:   filepath.Join(xdg.DataHome, "ludo", "savefiles"),
-		ScreenshotsDirectory: filepath.Join(xdg.DataHome, "ludo", "screenshots"),
-		SystemDirectory:      filepath.Join(xdg.DataHome, "ludo", "system"),
-		PlaylistsDirectory:   filepath.Join(xdg.DataHome, "ludo", "playlists"),
-		ThumbnailsDirectory:  filepath.Join(xdg.DataHome, "ludo", "thumbnails"),
+		RunAheadFrames:         map[string]int{},
+		RunAheadSecondInstance: map[string]bool{},
+
+		FileDirectory:         usr.HomeDir,
+		CoresDirectory:        "./cores",
+		CoreInfoDirectory:     "./info",
+		AssetsDirectory:       "./assets",
+		DatabaseDirectory:     "./database",
+		SavestatesDirectory:   filepath.Join(xdg.DataHome, "ludo", "savestates"),
+		SavefilesDirectory:    filepath.Join(xdg.DataHome, "ludo", "savefiles"),
+		ScreenshotsDirectory:  filepath.Join(xdg.DataHome, "ludo", "screenshots"),
+		SystemDirectory:       filepath.Join(xdg.DataHome, "ludo", "system"),
+		PlaylistsDirectory:    filepath.Join(xdg.DataHome, "ludo", "playlists"),
+		ThumbnailsDirectory:   filepath.Join(xdg.DataHome, "ludo", "thumbnails"),
+		CheatsDirectory:       filepath.Join(xdg.DataHome, "ludo", "cheats"),
+		AchievementsDirectory: filepath.Join(xdg.DataHome, "ludo", "achievements"),
+		UnlocksDirectory:      filepath.Join(xdg.DataHome, "ludo", "unlocks"),
+		MoviesDirectory:       filepath.Join(xdg.DataHome, "ludo", "movies"),
+
+		RewindEnabled:          false,
+		RewindBufferSize:       64,
+		RewindGranularity:      1,
+		KioskSeats:             1,
+		KioskAllowRewind:       false,
+		TelemetryRetentionDays: 90,
+		PlayerName:             usr.Username,
 	}
 }
@@ -33,18 +33,41 @@ type Settings struct {
 
 	MapAxisToDPad bool `toml:"input_map_axis_to_dpad" label:"Map Sticks To DPad" fmt:"%t" widget:"switch"`
 
+	RewindEnabled     bool `toml:"rewind_enabled" label:"Rewind" fmt:"%t" widget:"switch"`
+	RewindBufferSize  int  `toml:"rewind_buffer_size" label:"Rewind Buffer Size" fmt:"%d MB"`
+	RewindGranularity int  `toml:"rewind_granularity" label:"Rewind Granularity" fmt:"%d frames"`
+
 	CoreForPlaylist map[string]string `hide:"always" toml:"core_for_playlist"`
 
-	FileDirectory        string `hide:"ludos" toml:"files_dir" label:"Files Directory" fmt:"%s" widget:"dir"`
-	CoresDirectory       string `hide:"ludos" toml:"cores_dir" label:"Cores Directory" fmt:"%s" widget:"dir"`
-	AssetsDirectory      string `hide:"ludos" toml:"assets_dir" label:"Assets Directory" fmt:"%s" widget:"dir"`
-	DatabaseDirectory    string `hide:"ludos" toml:"database_dir" label:"Database Directory" fmt:"%s" widget:"dir"`
-	SavestatesDirectory  string `hide:"ludos" toml:"savestates_dir" label:"Savestates Directory" fmt:"%s" widget:"dir"`
-	SavefilesDirectory   string `hide:"ludos" toml:"savefiles_dir" label:"Savefiles Directory" fmt:"%s" widget:"dir"`
-	ScreenshotsDirectory string `hide:"ludos" toml:"screenshots_dir" label:"Screenshots Directory" fmt:"%s" widget:"dir"`
-	SystemDirectory      string `hide:"ludos" toml:"system_dir" label:"System Directory" fmt:"%s" widget:"dir"`
-	PlaylistsDirectory   string `hide:"ludos" toml:"playlists_dir" label:"Playlists Directory" fmt:"%s" widget:"dir"`
-	ThumbnailsDirectory  string `hide:"ludos" toml:"thumbnail_dir" label:"Thumbnails Directory" fmt:"%s" widget:"dir"`
+	RunAheadFrames         map[string]int  `hide:"always" toml:"runahead_frames"`
+	RunAheadSecondInstance map[string]bool `hide:"always" toml:"runahead_second_instance"`
+
+	VoucherSecret string `hide:"always" toml:"voucher_secret"`
+	KioskSeats    int    `hide:"always" toml:"kiosk_seats"`
+
+	KioskAllowRewind bool `hide:"always" toml:"kiosk_allow_rewind"`
+
+	TelemetryRetentionDays int `hide:"always" toml:"telemetry_retention_days"`
+
+	// PlayerName is the profile achievements are unlocked for outside the
+	// kiosk, which unlocks them for each session instead
+	PlayerName string `hide:"always" toml:"player_name"`
+
+	FileDirectory         string `hide:"ludos" toml:"files_dir" label:"Files Directory" fmt:"%s" widget:"dir"`
+	CoresDirectory        string `hide:"ludos" toml:"cores_dir" label:"Cores Directory" fmt:"%s" widget:"dir"`
+	CoreInfoDirectory     string `hide:"ludos" toml:"coreinfo_dir" label:"Core Info Directory" fmt:"%s" widget:"dir"`
+	AssetsDirectory       string `hide:"ludos" toml:"assets_dir" label:"Assets Directory" fmt:"%s" widget:"dir"`
+	DatabaseDirectory     string `hide:"ludos" toml:"database_dir" label:"Database Directory" fmt:"%s" widget:"dir"`
+	SavestatesDirectory   string `hide:"ludos" toml:"savestates_dir" label:"Savestates Directory" fmt:"%s" widget:"dir"`
+	SavefilesDirectory    string `hide:"ludos" toml:"savefiles_dir" label:"Savefiles Directory" fmt:"%s" widget:"dir"`
+	ScreenshotsDirectory  string `hide:"ludos" toml:"screenshots_dir" label:"Screenshots Directory" fmt:"%s" widget:"dir"`
+	SystemDirectory       string `hide:"ludos" toml:"system_dir" label:"System Directory" fmt:"%s" widget:"dir"`
+	PlaylistsDirectory    string `hide:"ludos" toml:"playlists_dir" label:"Playlists Directory" fmt:"%s" widget:"dir"`
+	ThumbnailsDirectory   string `hide:"ludos" toml:"thumbnail_dir" label:"Thumbnails Directory" fmt:"%s" widget:"dir"`
+	CheatsDirectory       string `hide:"ludos" toml:"cheats_dir" label:"Cheats Directory" fmt:"%s" widget:"dir"`
+	AchievementsDirectory string `hide:"ludos" toml:"achievements_dir" label:"Achievements Directory" fmt:"%s" widget:"dir"`
+	UnlocksDirectory      string `hide:"ludos" toml:"unlocks_dir" label:"Unlocks Directory" fmt:"%s" widget:"dir"`
+	MoviesDirectory       string `hide:"ludos" toml:"movies_dir" label:"Movies Directory" fmt:"%s" widget:"dir"`
 
 	SSHService       bool `hide:"app" toml:"ssh_service" label:"SSH" widget:"switch" service:"sshd.service" path:"/storage/.cache/services/sshd.conf"`
 	SambaService     bool `hide:"app" toml:"samba_service" label:"Samba" widget:"switch" service:"smbd.service" path:"/storage/.cache/services/samba.conf"`
@@ -22,9 +22,27 @@ var Verbose bool
 // CorePath is the path of the current libretro core
 var CorePath string
 
-// GamePath is the path of the current game
+// GamePath is the path of the current game, empty when the core runs without
+// a game
 var GamePath string
 
+// ContentPath returns the path naming the saves of what is running: the game,
+// or the core itself when it runs without a game
+func ContentPath() string {
+	if GamePath == "" {
+		return CorePath
+	}
+	return GamePath
+}
+
+// Subsystem is set when the current game is made of several ROMs, like a Game
+// Boy game running in a Super Game Boy. GamePath is then its first ROM.
+var Subsystem *libretro.SubsystemInfo
+
+// SubsystemPaths are the ROMs of the current subsystem game, in the order of
+// Subsystem.ROMs. Skipped optional ROMs have an empty path.
+var SubsystemPaths []string
+
 // DB is the game database loaded on startup
 var DB dat.DB
 
@@ -33,3 +51,13 @@ var LudOS bool
 
 // FastForward will run the core as fast as possible
 var FastForward bool
+
+// Rewind steps the game backwards while the rewind hotkey is held
+var Rewind bool
+
+// Kiosk is true when the game was launched by the kiosk for paying players
+var Kiosk bool
+
+// Profile is the profile achievements are unlocked for, set by the kiosk for
+// each session. When empty, the player of the settings is used.
+var Profile string
@@ -0,0 +1,313 @@
+// Package telemetry records what happens on the kiosk and aggregates it for
+// business analytics: popular games, session lengths, extension rate and
+// abandonment at the payment step. Events are appended to one CSV file per
+// day, a summary file is exported when the day is over, and files older than
+// the retention period are deleted.
+package telemetry
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the type of an event
+type Kind string
+
+// Events derived from the kiosk flow
+const (
+	PaymentStep      Kind = "payment_step"      // The player reached the payment screen
+	PaymentAbandoned Kind = "payment_abandoned" // The player left the payment screen without paying
+	SessionStart     Kind = "session_start"     // Value is the number of minutes bought
+	Timeout          Kind = "timeout"           // The player ran out of time
+	Extension        Kind = "extension"         // Value is the number of minutes bought
+	SessionEnd       Kind = "session_end"       // Value is the length of the session in seconds
+)
+
+// Event is a line of the daily event files
+type Event struct {
+	Time  time.Time
+	Kind  Kind
+	Game  string
+	Value int
+}
+
+const (
+	dayLayout     = "2006-01-02"
+	eventsPrefix  = "events-"
+	summaryPrefix = "usage-"
+)
+
+// Recorder appends events to daily files in a directory
+type Recorder struct {
+	dir       string
+	retention int // days
+	mu        sync.Mutex
+	day       string
+
+	now func() time.Time
+}
+
+// NewRecorder creates a recorder writing to dir and keeping files for the
+// given number of days. A retention of zero keeps everything.
+func NewRecorder(dir string, retentionDays int) *Recorder {
+	return &Recorder{dir: dir, retention: retentionDays, now: time.Now}
+}
+
+// Record appends an event to the file of the day. Errors are logged, the
+// kiosk flow must not be disturbed by analytics.
+func (r *Recorder) Record(kind Kind, game string, value int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if err := r.roll(now); err != nil {
+		log.Println("[Telemetry]:", err)
+	}
+
+	err := appendEvent(r.eventsPath(now.Format(dayLayout)), Event{Time: now, Kind: kind, Game: game, Value: value})
+	if err != nil {
+		log.Println("[Telemetry]:", err)
+	}
+}
+
+// Events returns the events recorded during the last days, today included
+func (r *Recorder) Events(days int) ([]Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []Event
+	now := r.now()
+	for d := days - 1; d >= 0; d-- {
+		day := now.AddDate(0, 0, -d).Format(dayLayout)
+		evs, err := readEvents(r.eventsPath(day))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		events = append(events, evs...)
+	}
+	return events, nil
+}
+
+// roll exports the summaries of the past days that don't have one yet, and
+// enforces the retention policy. It runs once per day.
+func (r *Recorder) roll(now time.Time) error {
+	today := now.Format(dayLayout)
+	if r.day == today {
+		return nil
+	}
+	r.day = today
+
+	if err := os.MkdirAll(r.dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.AddDate(0, 0, -r.retention).Format(dayLayout)
+	for _, e := range entries {
+		name := e.Name()
+		day, ok := fileDay(name)
+		if !ok {
+			continue
+		}
+
+		if r.retention > 0 && day < cutoff {
+			if err := os.Remove(filepath.Join(r.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.HasPrefix(name, eventsPrefix) && day < today {
+			if _, err := os.Stat(r.summaryPath(day)); os.IsNotExist(err) {
+				events, err := readEvents(filepath.Join(r.dir, name))
+				if err != nil {
+					return err
+				}
+				if err := writeSummary(r.summaryPath(day), Summarize(events)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) eventsPath(day string) string {
+	return filepath.Join(r.dir, eventsPrefix+day+".csv")
+}
+
+func (r *Recorder) summaryPath(day string) string {
+	return filepath.Join(r.dir, summaryPrefix+day+".csv")
+}
+
+// fileDay extracts the date from the name of a daily file
+func fileDay(name string) (string, bool) {
+	if !strings.HasSuffix(name, ".csv") {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, ".csv")
+	for _, prefix := range []string{eventsPrefix, summaryPrefix} {
+		if strings.HasPrefix(name, prefix) {
+			day := strings.TrimPrefix(name, prefix)
+			if _, err := time.Parse(dayLayout, day); err == nil {
+				return day, true
+			}
+		}
+	}
+	return "", false
+}
+
+func appendEvent(path string, e Event) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{e.Time.Format(time.RFC3339), string(e.Kind), e.Game, strconv.Itoa(e.Value)})
+	w.Flush()
+	return w.Error()
+}
+
+func readEvents(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []Event
+	r := csv.NewReader(bufio.NewReader(file))
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(record) < 4 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, record[0])
+		v, _ := strconv.Atoi(record[3])
+		events = append(events, Event{Time: t, Kind: Kind(record[1]), Game: record[2], Value: v})
+	}
+	return events, nil
+}
+
+// GameCount is the number of sessions played on a game
+type GameCount struct {
+	Name     string `json:"name"`
+	Sessions int    `json:"sessions"`
+}
+
+// Summary aggregates a list of events
+type Summary struct {
+	Sessions         int         `json:"sessions"`
+	AverageSession   float64     `json:"averageSessionSeconds"`
+	Timeouts         int         `json:"timeouts"`
+	Extensions       int         `json:"extensions"`
+	ExtensionRate    float64     `json:"extensionRate"`
+	PaymentSteps     int         `json:"paymentSteps"`
+	PaymentAbandoned int         `json:"paymentAbandoned"`
+	AbandonmentRate  float64     `json:"abandonmentRate"`
+	MinutesSold      int         `json:"minutesSold"`
+	PopularGames     []GameCount `json:"popularGames"`
+}
+
+// Summarize computes the analytics of a list of events. The extension rate
+// is relative to the number of timeouts, the abandonment rate to the number
+// of times the payment screen was shown.
+func Summarize(events []Event) Summary {
+	var s Summary
+	var totalLength int
+	var ended int
+	games := map[string]int{}
+
+	for _, e := range events {
+		switch e.Kind {
+		case SessionStart:
+			s.Sessions++
+			s.MinutesSold += e.Value
+			games[e.Game]++
+		case SessionEnd:
+			ended++
+			totalLength += e.Value
+		case Timeout:
+			s.Timeouts++
+		case Extension:
+			s.Extensions++
+			s.MinutesSold += e.Value
+		case PaymentStep:
+			s.PaymentSteps++
+		case PaymentAbandoned:
+			s.PaymentAbandoned++
+		}
+	}
+
+	if ended > 0 {
+		s.AverageSession = float64(totalLength) / float64(ended)
+	}
+	if s.Timeouts > 0 {
+		s.ExtensionRate = float64(s.Extensions) / float64(s.Timeouts)
+	}
+	if s.PaymentSteps > 0 {
+		s.AbandonmentRate = float64(s.PaymentAbandoned) / float64(s.PaymentSteps)
+	}
+
+	s.PopularGames = []GameCount{}
+	for name, n := range games {
+		s.PopularGames = append(s.PopularGames, GameCount{Name: name, Sessions: n})
+	}
+	sort.Slice(s.PopularGames, func(i, j int) bool {
+		if s.PopularGames[i].Sessions != s.PopularGames[j].Sessions {
+			return s.PopularGames[i].Sessions > s.PopularGames[j].Sessions
+		}
+		return s.PopularGames[i].Name < s.PopularGames[j].Name
+	})
+
+	return s
+}
+
+// writeSummary exports a summary as metric,key,value lines
+func writeSummary(path string, s Summary) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	w.Write([]string{"metric", "key", "value"})
+	w.Write([]string{"sessions", "", strconv.Itoa(s.Sessions)})
+	w.Write([]string{"average_session_seconds", "", f(s.AverageSession)})
+	w.Write([]string{"minutes_sold", "", strconv.Itoa(s.MinutesSold)})
+	w.Write([]string{"timeouts", "", strconv.Itoa(s.Timeouts)})
+	w.Write([]string{"extensions", "", strconv.Itoa(s.Extensions)})
+	w.Write([]string{"extension_rate", "", f(s.ExtensionRate)})
+	w.Write([]string{"payment_steps", "", strconv.Itoa(s.PaymentSteps)})
+	w.Write([]string{"payment_abandoned", "", strconv.Itoa(s.PaymentAbandoned)})
+	w.Write([]string{"abandonment_rate", "", f(s.AbandonmentRate)})
+	for _, g := range s.PopularGames {
+		w.Write([]string{"game_sessions", g.Name, strconv.Itoa(g.Sessions)})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	events := []Event{
+		{Kind: PaymentStep, Game: "Nova"},
+		{Kind: SessionStart, Game: "Nova", Value: 5},
+		{Kind: Timeout, Game: "Nova"},
+		{Kind: Extension, Game: "Nova", Value: 3},
+		{Kind: Timeout, Game: "Nova"},
+		{Kind: SessionEnd, Game: "Nova", Value: 480},
+		{Kind: PaymentStep, Game: "Retro Hero"},
+		{Kind: PaymentAbandoned, Game: "Retro Hero"},
+		{Kind: PaymentStep, Game: "Retro Hero"},
+		{Kind: SessionStart, Game: "Retro Hero", Value: 2},
+		{Kind: SessionEnd, Game: "Retro Hero", Value: 120},
+		{Kind: PaymentStep, Game: "Nova"},
+		{Kind: SessionStart, Game: "Nova", Value: 1},
+	}
+
+	got := Summarize(events)
+	want := Summary{
+		Sessions:         3,
+		AverageSession:   300,
+		Timeouts:         2,
+		Extensions:       1,
+		ExtensionRate:    0.5,
+		PaymentSteps:     4,
+		PaymentAbandoned: 1,
+		AbandonmentRate:  0.25,
+		MinutesSold:      11,
+		PopularGames: []GameCount{
+			{Name: "Nova", Sessions: 2},
+			{Name: "Retro Hero", Sessions: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	r := NewRecorder(dir, 7)
+	r.now = func() time.Time { return day }
+
+	// An old file that is past the retention period
+	os.WriteFile(filepath.Join(dir, "events-2024-02-01.csv"), []byte{}, 0644)
+
+	r.Record(SessionStart, "Nova", 5)
+	r.Record(SessionEnd, "Nova", 300)
+
+	t.Run("Reads back the events of the day", func(t *testing.T) {
+		events, err := r.Events(1)
+		if err != nil || len(events) != 2 || events[1].Value != 300 {
+			t.Errorf("got = %v, %v, want 2 events", events, err)
+		}
+	})
+
+	day = day.AddDate(0, 0, 1)
+	r.Record(PaymentStep, "Nova", 0)
+
+	t.Run("Exports the summary of the previous day and prunes old files", func(t *testing.T) {
+		entries, _ := os.ReadDir(dir)
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		sort.Strings(got)
+		want := []string{"events-2024-03-10.csv", "events-2024-03-11.csv", "usage-2024-03-10.csv"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Reads events across days", func(t *testing.T) {
+		events, err := r.Events(2)
+		if err != nil || len(events) != 3 {
+			t.Errorf("got = %v, %v, want 3 events", events, err)
+		}
+	})
+}
@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"image/color"
 	"io/ioutil"
-	"runtime"
-	"sync"
 
 	"fyne.io/fyne/driver/desktop"
 	"fyne.io/fyne/v2"
@@ -15,21 +13,10 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/libretro/ludo/ludo"
+	"github.com/libretro/ludo/catalog"
+	"github.com/libretro/ludo/session"
 )
 
-// UIState defines the different states of the UI.
-type UIState int
-
-const (
-	stateSelectGame UIState = iota
-	stateTimeSelect
-	statePayment
-	stateExtendTime
-)
-
-const pricePerMinute = 0.5
-
 // Define our custom colors for dark theme
 var (
 	colorPrimary    = color.NRGBA{R: 0xcf, G: 0x2e, B: 0x2e, A: 0xff} // #cf2e2e - Red accent
@@ -248,7 +235,8 @@ func (g *GameTile) MouseOut() {
 	g.Refresh()
 }
 
-// UI wraps all Fyne UI elements and state.
+// UI wraps all Fyne UI elements. The flow itself is driven by the session
+// controller, see the methods implementing session.Frontend.
 type UI struct {
 	app           fyne.App
 	window        fyne.Window
@@ -262,20 +250,13 @@ type UI struct {
 	paymentPrompt *canvas.Text
 	content       *fyne.Container
 
-	keys        []string
+	games       catalog.Catalog
 	selectedIdx int
-	currentState UIState
-	stateMutex  sync.Mutex
-
-	games        map[string]string
-	cores        map[string]string
-	gamePictures map[string]string
-	timerChan    chan int
-	resumeChan   chan bool
+	session     *session.Controller
 }
 
-// NewUI creates and initializes a new UI instance.
-func NewUI(games, cores, gamePictures map[string]string, timerChan chan int, resumeChan chan bool) *UI {
+// NewUI creates and initializes a new UI instance for the given session.
+func NewUI(c *session.Controller) *UI {
 	a := app.New()
 	a.Settings().SetTheme(&arcadeTheme{})
 
@@ -283,22 +264,12 @@ func NewUI(games, cores, gamePictures map[string]string, timerChan chan int, res
 	w.SetFullScreen(true)
 	w.CenterOnScreen()
 
-	keys := make([]string, 0, len(games))
-	for k := range games {
-		keys = append(keys, k)
-	}
-
 	ui := &UI{
-		app:          a,
-		window:       w,
-		keys:         keys,
-		selectedIdx:  0,
-		currentState: stateSelectGame,
-		games:        games,
-		cores:        cores,
-		gamePictures: gamePictures,
-		timerChan:    timerChan,
-		resumeChan:   resumeChan,
+		app:         a,
+		window:      w,
+		games:       c.Catalog(),
+		selectedIdx: 0,
+		session:     c,
 	}
 
 	// Status text without time selection hint
@@ -421,33 +392,8 @@ func (ui *UI) createStylishHeader() *fyne.Container {
 
 // createGameGrid creates a fixed 8x4 grid of game tiles
 func (ui *UI) createGameGrid() {
-	ui.gameTiles = make([]*GameTile, len(ui.keys))
-	gridItems := make([]fyne.CanvasObject, len(ui.keys))
-
-	for i, gameName := range ui.keys {
-		// Get the image path from the gamePictures map
-		imagePath := ui.gamePictures[gameName]
-		if imagePath == "" {
-			// Fallback to a default image
-			imagePath = "/home/simon/Dev/ludo-spets/assets/spets/games/default.png"
-		}
-
-		// Create the game tile with selection callback
-		index := i // Capture loop variable
-		ui.gameTiles[i] = NewGameTile(gameName, imagePath, func() {
-			ui.selectGameTile(index)
-		})
-
-		// The first game is selected by default
-		if i == 0 {
-			ui.gameTiles[i].Selected = true
-		}
-
-		gridItems[i] = ui.gameTiles[i]
-	}
-
 	// Always use 8 columns
-	ui.gameGrid = container.New(layout.NewGridLayoutWithColumns(8), gridItems...)
+	ui.gameGrid = container.New(layout.NewGridLayoutWithColumns(8), ui.createGameTiles()...)
 
 	// Create a fixed-size container for the grid that will show exactly 8x4 tiles
 	paddedGrid := container.NewPadded(ui.gameGrid)
@@ -467,6 +413,35 @@ func (ui *UI) createGameGrid() {
 	ui.gameScroll.SetMinSize(fyne.NewSize(gridWidth, gridHeight))
 }
 
+// createGameTiles creates a tile for each game of the catalog
+func (ui *UI) createGameTiles() []fyne.CanvasObject {
+	ui.selectedIdx = 0
+	ui.gameTiles = make([]*GameTile, len(ui.games))
+	gridItems := make([]fyne.CanvasObject, len(ui.games))
+
+	for i, game := range ui.games {
+		imagePath := game.ImagePath
+		if imagePath == "" {
+			// Fallback to a default image
+			imagePath = "/home/simon/Dev/ludo-spets/assets/spets/games/default.png"
+		}
+
+		// Create the game tile with selection callback
+		index := i // Capture loop variable
+		ui.gameTiles[i] = NewGameTile(game.Name, imagePath, func() {
+			ui.selectGameTile(index)
+		})
+
+		// The first game is selected by default
+		if i == 0 {
+			ui.gameTiles[i].Selected = true
+		}
+
+		gridItems[i] = ui.gameTiles[i]
+	}
+	return gridItems
+}
+
 func (ui *UI) selectGameTile(index int) {
 	if index < 0 || index >= len(ui.gameTiles) {
 		return
@@ -514,28 +489,24 @@ func (ui *UI) scrollToSelectedTile() {
 
 func (ui *UI) updatePrice() {
 	mins := int(ui.timeSlider.Value)
-	price := float64(mins) * pricePerMinute
+	price := float64(mins) * session.PricePerMinute
 	ui.priceLabel.Text = fmt.Sprintf("TOTAL COST: $%.2f (%d minutes)", price, mins)
 	ui.priceLabel.Refresh()
 }
 
 func (ui *UI) setKeyHandler() {
 	ui.window.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
-		ui.stateMutex.Lock()
-		currentState := ui.currentState
-		ui.stateMutex.Unlock()
-
-		needsRefresh := false
+		state := ui.session.State()
 
-		switch currentState {
-		case stateSelectGame:
+		switch state {
+		case session.SelectGame:
 			switch key.Name {
 			case fyne.KeyDown:
 				// Always use 8 columns
 				cols := 8
 
 				// Move down in the grid
-				if ui.selectedIdx+cols < len(ui.keys) {
+				if ui.selectedIdx+cols < len(ui.games) {
 					ui.selectGameTile(ui.selectedIdx + cols)
 				}
 			case fyne.KeyUp:
@@ -551,22 +522,16 @@ func (ui *UI) setKeyHandler() {
 					ui.selectGameTile(ui.selectedIdx - 1)
 				}
 			case fyne.KeyRight:
-				if ui.selectedIdx < len(ui.keys)-1 {
+				if ui.selectedIdx < len(ui.games)-1 {
 					ui.selectGameTile(ui.selectedIdx + 1)
 				}
 			case fyne.KeyReturn, fyne.KeyEnter:
-				ui.stateMutex.Lock()
-				ui.currentState = stateTimeSelect
-				ui.stateMutex.Unlock()
-				ui.status.Text = "◄ ► ADJUST TIME    ENTER TO CONTINUE"
-				ui.gameScroll.Hide()
-				ui.timeLabel.Show()
-				ui.timeSlider.Show()
-				ui.priceLabel.Show()
-				needsRefresh = true
+				if ui.selectedIdx < len(ui.games) {
+					ui.session.SelectGame(ui.games[ui.selectedIdx].Name)
+				}
 			}
 
-		case stateTimeSelect:
+		case session.TimeSelect, session.ExtendTime:
 			switch key.Name {
 			case fyne.KeyRight:
 				if ui.timeSlider.Value < ui.timeSlider.Max {
@@ -579,97 +544,124 @@ func (ui *UI) setKeyHandler() {
 					ui.updatePrice()
 				}
 			case fyne.KeyReturn, fyne.KeyEnter:
-				ui.stateMutex.Lock()
-				ui.currentState = statePayment
-				ui.stateMutex.Unlock()
-				ui.status.Text = "PRESS 'P' TO INSERT COIN AND START GAME"
-				ui.timeLabel.Hide()
-				ui.timeSlider.Hide()
-				ui.priceLabel.Hide()
-				ui.paymentPrompt.Show()
-				needsRefresh = true
-			}
-
-		case statePayment:
-			if key.Name == fyne.KeyP {
-				ui.status.Text = "LAUNCHING GAME..."
-				needsRefresh = true
-
-				gameName := ui.keys[ui.selectedIdx]
-				corePath := ui.cores[gameName]
-				gamePath := ui.games[gameName]
-				mins := int(ui.timeSlider.Value)
-				durationSecs := mins * 2
-
-				fmt.Printf("Launching: %s with core: %s for %d seconds\n", gamePath, corePath, durationSecs)
-
-				ui.window.Hide()
-				ui.paymentPrompt.Hide()
-
-				go func() {
-					runtime.LockOSThread()
-					defer runtime.UnlockOSThread()
-					fmt.Println("About to call ludo.RunGame")
-					err := ludo.RunGame(corePath, gamePath, durationSecs, ui.timerChan, ui.resumeChan)
-					if err != nil {
-						fmt.Printf("Error running game: %v\n", err)
-					}
-				}()
+				if state == session.TimeSelect {
+					ui.session.SelectTime(int(ui.timeSlider.Value), 1)
+				}
+			case fyne.KeyP:
+				// The timeout screen takes the coin right away
+				if state == session.ExtendTime {
+					ui.session.SelectTime(int(ui.timeSlider.Value), 1)
+					ui.pay("RESUMING GAME...")
+				}
+			case fyne.KeyEscape:
+				if state == session.TimeSelect {
+					ui.session.Cancel()
+				} else {
+					ui.session.Quit()
+				}
 			}
 
-		case stateExtendTime:
+		case session.Payment, session.ExtendPayment:
 			switch key.Name {
-			case fyne.KeyRight:
-				if ui.timeSlider.Value < ui.timeSlider.Max {
-					ui.timeSlider.SetValue(ui.timeSlider.Value + 1)
-					ui.updatePrice()
-				}
-			case fyne.KeyLeft:
-				if ui.timeSlider.Value > ui.timeSlider.Min {
-					ui.timeSlider.SetValue(ui.timeSlider.Value - 1)
-					ui.updatePrice()
-				}
 			case fyne.KeyP:
-				ui.status.Text = "RESUMING GAME..."
-				needsRefresh = true
-				mins := int(ui.timeSlider.Value)
-
-				ui.timeLabel.Hide()
-				ui.timeSlider.Hide()
-				ui.priceLabel.Hide()
-				ui.paymentPrompt.Hide()
-				ui.window.Hide()
-
-				go func() {
-					ui.resumeChan <- true
-					ui.timerChan <- mins * 2
-				}()
+				ui.pay("LAUNCHING GAME...")
+			case fyne.KeyEscape:
+				ui.session.Cancel()
 			}
 		}
-		if needsRefresh {
-			ui.status.Refresh()
-		}
 	})
 }
 
+// pay hides the window and hands over to the game
+func (ui *UI) pay(status string) {
+	ui.status.Text = status
+	ui.status.Refresh()
+	ui.timeLabel.Hide()
+	ui.timeSlider.Hide()
+	ui.priceLabel.Hide()
+	ui.paymentPrompt.Hide()
+	ui.window.Hide()
+
+	go ui.session.Pay()
+}
+
 // Run starts the Fyne application and shows the main window.
 func (ui *UI) Run() {
 	ui.window.ShowAndRun()
 }
 
-// HandleTimeout is called when the game timer expires.
-func (ui *UI) HandleTimeout() {
-	ui.stateMutex.Lock()
-	ui.currentState = stateExtendTime
-	ui.stateMutex.Unlock()
+// ShowCatalog rebuilds the game grid, the parental controls may have
+// changed the allowed games since it was last shown.
+func (ui *UI) ShowCatalog(games catalog.Catalog) {
+	fyne.Do(func() {
+		ui.games = games
+		ui.gameGrid.Objects = ui.createGameTiles()
+		ui.gameGrid.Refresh()
+		ui.scrollToSelectedTile()
+
+		ui.status.Text = "◄ ► ▲ ▼ NAVIGATE    ENTER TO CONTINUE"
+		ui.status.Refresh()
+		ui.timeLabel.Hide()
+		ui.timeSlider.Hide()
+		ui.priceLabel.Hide()
+		ui.paymentPrompt.Hide()
+		ui.gameScroll.Show()
+		ui.window.Show()
+	})
+}
 
-	ui.window.Show()
-	ui.window.RequestFocus()
-	ui.status.Text = "TIME OUT! ◄ ► ADJUST TIME    PRESS 'P' TO PAY AND CONTINUE"
-	ui.status.Refresh()
-	ui.gameScroll.Hide()
-	ui.timeLabel.Show()
-	ui.timeSlider.Show()
-	ui.priceLabel.Show()
-	ui.paymentPrompt.Show()
-}
\ No newline at end of file
+// ShowTimeSelect shows the time slider
+func (ui *UI) ShowTimeSelect(game catalog.Game, seats int) {
+	fyne.Do(func() {
+		ui.status.Text = "◄ ► ADJUST TIME    ENTER TO CONTINUE    ESC TO GO BACK"
+		ui.status.Refresh()
+		ui.gameScroll.Hide()
+		ui.timeLabel.Show()
+		ui.timeSlider.Show()
+		ui.priceLabel.Show()
+	})
+}
+
+// ShowPayment asks for a coin
+func (ui *UI) ShowPayment(offer session.Offer) {
+	fyne.Do(func() {
+		ui.status.Text = "PRESS 'P' TO INSERT COIN AND START GAME    ESC TO GO BACK"
+		ui.status.Refresh()
+		ui.timeLabel.Hide()
+		ui.timeSlider.Hide()
+		ui.priceLabel.Hide()
+		ui.paymentPrompt.Text = fmt.Sprintf("PRESS 'P' TO INSERT $%.2f", offer.Price())
+		ui.paymentPrompt.Refresh()
+		ui.paymentPrompt.Show()
+	})
+}
+
+// ShowExtend shows the time slider over the paused game
+func (ui *UI) ShowExtend() {
+	fyne.Do(func() {
+		ui.status.Text = "TIME OUT! ◄ ► ADJUST TIME    PRESS 'P' TO PAY AND CONTINUE"
+		ui.status.Refresh()
+		ui.gameScroll.Hide()
+		ui.timeLabel.Show()
+		ui.timeSlider.Show()
+		ui.priceLabel.Show()
+		ui.paymentPrompt.Text = "PRESS 'P' TO INSERT COIN"
+		ui.paymentPrompt.Refresh()
+		ui.paymentPrompt.Show()
+	})
+}
+
+// OnGameLoaded hides the window while the game runs
+func (ui *UI) OnGameLoaded() {
+	fyne.Do(func() {
+		ui.window.Hide()
+	})
+}
+
+// OnTimeout shows the window in front of the paused game
+func (ui *UI) OnTimeout(window session.Window) {
+	fyne.Do(func() {
+		ui.window.Show()
+		ui.window.RequestFocus()
+	})
+}
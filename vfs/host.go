@@ -0,0 +1,54 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Host returns the file system of the computer, whose names are absolute
+// paths without their leading slash
+func Host() WriteFS {
+	return host{}
+}
+
+type host struct{}
+
+// native turns a name back into a path of the host
+func native(name string) string {
+	if filepath.VolumeName(name) != "" {
+		return filepath.FromSlash(name)
+	}
+	if name == "." {
+		return string(filepath.Separator)
+	}
+	return filepath.FromSlash("/" + name)
+}
+
+func (host) Open(name string) (fs.File, error) {
+	return os.Open(native(name))
+}
+
+func (host) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(native(name))
+}
+
+func (host) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(native(name), flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (host) Remove(name string) error {
+	return os.Remove(native(name))
+}
+
+func (host) Rename(oldname, newname string) error {
+	return os.Rename(native(oldname), native(newname))
+}
+
+func (host) Mkdir(name string, perm fs.FileMode) error {
+	return os.Mkdir(native(name), perm)
+}
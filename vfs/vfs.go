@@ -0,0 +1,408 @@
+// Package vfs implements the libretro VFS interface on top of io/fs. Cores
+// using it can read games from inside zip archives, with paths like
+// "/roms/game.zip#game.gb", and can be restricted to a few directories.
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/libretro/ludo/libretro"
+)
+
+// File is an open file of a WriteFS
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Truncate(size int64) error
+	Sync() error
+}
+
+// WriteFS is a file system cores can also modify
+type WriteFS interface {
+	fs.FS
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Remove(name string) error
+	Rename(oldname, newname string) error
+	Mkdir(name string, perm fs.FileMode) error
+}
+
+// FS gives cores access to the files of a file system. It is safe for
+// concurrent use.
+type FS struct {
+	fsys fs.FS
+
+	mu      sync.Mutex
+	allowed []string
+	next    uintptr
+	files   map[uintptr]File
+	dirs    map[uintptr]*dir
+}
+
+type dir struct {
+	entries []fs.DirEntry
+	pos     int
+}
+
+// New exposes fsys to the cores. Paths given by cores are cleaned and their
+// leading slash is removed before being opened in fsys.
+func New(fsys fs.FS) *FS {
+	return &FS{
+		fsys:  fsys,
+		files: map[uintptr]File{},
+		dirs:  map[uintptr]*dir{},
+	}
+}
+
+// Allow restricts the files cores can access to the given directories and
+// their subdirectories. Without directories, every file is accessible.
+func (v *FS) Allow(dirs ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.allowed = nil
+	for _, d := range dirs {
+		v.allowed = append(v.allowed, name(d))
+	}
+}
+
+// name turns the path given by a core into a name of the file system
+func name(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	p = strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// resolve checks that a core can access p and returns the file system and
+// the name to open. Paths inside zip archives are resolved in a file system
+// reading the archive, to close once done.
+func (v *FS) resolve(p string) (fs.FS, string, io.Closer, error) {
+	archive, entry, inArchive := SplitArchivePath(p)
+	n := name(archive)
+
+	v.mu.Lock()
+	ok := len(v.allowed) == 0
+	for _, d := range v.allowed {
+		ok = ok || d == "." || n == d || strings.HasPrefix(n, d+"/")
+	}
+	v.mu.Unlock()
+	if !ok {
+		return nil, "", nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrPermission}
+	}
+
+	if !inArchive {
+		return v.fsys, n, io.NopCloser(nil), nil
+	}
+	zfs, closer, err := openZip(v.fsys, n)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	entry = strings.Trim(path.Clean("/"+entry), "/")
+	if entry == "" {
+		entry = "."
+	}
+	return zfs, entry, closer, nil
+}
+
+// writable returns the file system to modify p
+func (v *FS) writable(p string) (WriteFS, string, error) {
+	if _, _, inArchive := SplitArchivePath(p); inArchive {
+		return nil, "", &fs.PathError{Op: "write", Path: p, Err: fs.ErrPermission}
+	}
+	fsys, n, _, err := v.resolve(p)
+	if err != nil {
+		return nil, "", err
+	}
+	wfs, ok := fsys.(WriteFS)
+	if !ok {
+		return nil, "", &fs.PathError{Op: "write", Path: p, Err: fs.ErrPermission}
+	}
+	return wfs, n, nil
+}
+
+// add registers an open file or directory and returns its handle
+func (v *FS) add(f File, d *dir) uintptr {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.next++
+	if f != nil {
+		v.files[v.next] = f
+	} else {
+		v.dirs[v.next] = d
+	}
+	return v.next
+}
+
+func (v *FS) file(h uintptr) (File, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f, ok := v.files[h]
+	if !ok {
+		return nil, fs.ErrClosed
+	}
+	return f, nil
+}
+
+// Open opens a file with the libretro access mode
+func (v *FS) Open(p string, mode uint32) (uintptr, error) {
+	if mode&libretro.VFSFileAccessWrite == 0 {
+		f, err := v.open(p)
+		if err != nil {
+			return 0, err
+		}
+		return v.add(f, nil), nil
+	}
+
+	wfs, n, err := v.writable(p)
+	if err != nil {
+		return 0, err
+	}
+	flag := os.O_WRONLY
+	if mode&libretro.VFSFileAccessRead != 0 {
+		flag = os.O_RDWR
+	}
+	if mode&libretro.VFSFileAccessUpdateExisting == 0 {
+		flag |= os.O_CREATE | os.O_TRUNC
+	}
+	f, err := wfs.OpenFile(n, flag, 0644)
+	if err != nil {
+		return 0, err
+	}
+	return v.add(f, nil), nil
+}
+
+// open opens a file for reading. Files that can't seek, like the ones in
+// archives, are read in memory.
+func (v *FS) open(p string) (File, error) {
+	fsys, n, closer, err := v.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	f, err := fsys.Open(n)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: p, Err: errors.New("is a directory")}
+	}
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return readOnly{rs, f}, nil
+	}
+	b, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	return readOnly{bytes.NewReader(b), io.NopCloser(nil)}, nil
+}
+
+// ReadFile reads a whole file
+func (v *FS) ReadFile(p string) ([]byte, error) {
+	f, err := v.open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Close closes a file
+func (v *FS) Close(h uintptr) error {
+	f, err := v.file(h)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	delete(v.files, h)
+	v.mu.Unlock()
+	return f.Close()
+}
+
+// Size returns the size of a file
+func (v *FS) Size(h uintptr) (int64, error) {
+	f, err := v.file(h)
+	if err != nil {
+		return 0, err
+	}
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	_, err = f.Seek(pos, io.SeekStart)
+	return size, err
+}
+
+// Truncate changes the size of a file
+func (v *FS) Truncate(h uintptr, size int64) error {
+	f, err := v.file(h)
+	if err != nil {
+		return err
+	}
+	return f.Truncate(size)
+}
+
+// Seek moves the read and write position of a file. The libretro seek
+// positions match the whence values of io.Seeker.
+func (v *FS) Seek(h uintptr, offset int64, whence int) (int64, error) {
+	f, err := v.file(h)
+	if err != nil {
+		return 0, err
+	}
+	return f.Seek(offset, whence)
+}
+
+// Read reads from a file
+func (v *FS) Read(h uintptr, p []byte) (int, error) {
+	f, err := v.file(h)
+	if err != nil {
+		return 0, err
+	}
+	return f.Read(p)
+}
+
+// Write writes to a file
+func (v *FS) Write(h uintptr, p []byte) (int, error) {
+	f, err := v.file(h)
+	if err != nil {
+		return 0, err
+	}
+	return f.Write(p)
+}
+
+// Flush commits the writes to a file
+func (v *FS) Flush(h uintptr) error {
+	f, err := v.file(h)
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Remove deletes a file
+func (v *FS) Remove(p string) error {
+	wfs, n, err := v.writable(p)
+	if err != nil {
+		return err
+	}
+	return wfs.Remove(n)
+}
+
+// Rename moves a file
+func (v *FS) Rename(oldpath, newpath string) error {
+	wfs, oldname, err := v.writable(oldpath)
+	if err != nil {
+		return err
+	}
+	_, newname, err := v.writable(newpath)
+	if err != nil {
+		return err
+	}
+	return wfs.Rename(oldname, newname)
+}
+
+// Stat describes a file
+func (v *FS) Stat(p string) (fs.FileInfo, error) {
+	fsys, n, closer, err := v.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return fs.Stat(fsys, n)
+}
+
+// Mkdir creates a directory
+func (v *FS) Mkdir(p string) error {
+	wfs, n, err := v.writable(p)
+	if err != nil {
+		return err
+	}
+	return wfs.Mkdir(n, 0755)
+}
+
+// OpenDir opens a directory to list its entries. Entries starting with a
+// dot are skipped unless hidden is set.
+func (v *FS) OpenDir(p string, hidden bool) (uintptr, error) {
+	fsys, n, closer, err := v.resolve(p)
+	if err != nil {
+		return 0, err
+	}
+	defer closer.Close()
+
+	entries, err := fs.ReadDir(fsys, n)
+	if err != nil {
+		return 0, err
+	}
+	d := &dir{}
+	for _, e := range entries {
+		if hidden || !strings.HasPrefix(e.Name(), ".") {
+			d.entries = append(d.entries, e)
+		}
+	}
+	sort.Slice(d.entries, func(i, j int) bool {
+		return d.entries[i].Name() < d.entries[j].Name()
+	})
+	return v.add(nil, d), nil
+}
+
+// ReadDir returns the next entry of a directory, or io.EOF
+func (v *FS) ReadDir(h uintptr) (fs.DirEntry, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	d, ok := v.dirs[h]
+	if !ok {
+		return nil, fs.ErrClosed
+	}
+	if d.pos >= len(d.entries) {
+		return nil, io.EOF
+	}
+	d.pos++
+	return d.entries[d.pos-1], nil
+}
+
+// CloseDir closes a directory
+func (v *FS) CloseDir(h uintptr) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.dirs[h]; !ok {
+		return fs.ErrClosed
+	}
+	delete(v.dirs, h)
+	return nil
+}
+
+// readOnly is a file opened for reading
+type readOnly struct {
+	io.ReadSeeker
+	io.Closer
+}
+
+func (readOnly) Write(p []byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+func (readOnly) Truncate(size int64) error {
+	return fs.ErrPermission
+}
+
+func (readOnly) Sync() error {
+	return nil
+}
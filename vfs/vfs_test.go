@@ -0,0 +1,217 @@
+package vfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/libretro/ludo/libretro"
+)
+
+func zipped(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testFS(t *testing.T) *FS {
+	return New(fstest.MapFS{
+		"roms/game.gb":     {Data: []byte("cartridge")},
+		"roms/.hidden":     {Data: []byte("secret")},
+		"roms/games.zip":   {Data: zipped(t, map[string]string{"disc.cue": "cue", "disc.bin": "track 1"})},
+		"bios/bios.bin":    {Data: []byte("bios")},
+		"home/private.txt": {Data: []byte("private")},
+	})
+}
+
+func TestFS_Open(t *testing.T) {
+	t.Run("Reads and seeks in plain files", func(t *testing.T) {
+		v := testFS(t)
+		h, err := v.Open("/roms/game.gb", libretro.VFSFileAccessRead)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer v.Close(h)
+		if size, _ := v.Size(h); size != 9 {
+			t.Errorf("got = %v, want %v", size, 9)
+		}
+		v.Seek(h, 4, io.SeekStart)
+		buf := make([]byte, 16)
+		n, _ := v.Read(h, buf)
+		if got := string(buf[:n]); got != "ridge" {
+			t.Errorf("got = %v, want %v", got, "ridge")
+		}
+	})
+
+	t.Run("Reads files inside zip archives", func(t *testing.T) {
+		v := testFS(t)
+		got, err := v.ReadFile("/roms/games.zip#disc.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "track 1" {
+			t.Errorf("got = %v, want %v", string(got), "track 1")
+		}
+	})
+
+	t.Run("Can't write to read only file systems", func(t *testing.T) {
+		v := testFS(t)
+		_, err := v.Open("/roms/game.gb", libretro.VFSFileAccessReadWrite)
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("got = %v, want %v", err, fs.ErrPermission)
+		}
+	})
+
+	t.Run("Refuses to open directories", func(t *testing.T) {
+		v := testFS(t)
+		if _, err := v.Open("/roms", libretro.VFSFileAccessRead); err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+	})
+}
+
+func TestFS_Allow(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want error
+	}{
+		{"Allows files of the allowed directories", "/roms/game.gb", nil},
+		{"Allows files inside allowed archives", "/roms/games.zip#disc.cue", nil},
+		{"Refuses files outside the allowed directories", "/home/private.txt", fs.ErrPermission},
+		{"Refuses to escape with dot dot", "/roms/../home/private.txt", fs.ErrPermission},
+		{"Refuses directories sharing a prefix", "/bios2/bios.bin", fs.ErrPermission},
+	}
+	v := testFS(t)
+	v.Allow("/roms", "/bios/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Stat(tt.path)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFS_OpenDir(t *testing.T) {
+	list := func(v *FS, p string, hidden bool) []string {
+		h, err := v.OpenDir(p, hidden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer v.CloseDir(h)
+		var names []string
+		for {
+			e, err := v.ReadDir(h)
+			if err != nil {
+				return names
+			}
+			names = append(names, e.Name())
+		}
+	}
+
+	t.Run("Skips hidden files", func(t *testing.T) {
+		got := list(testFS(t), "/roms", false)
+		want := []string{"game.gb", "games.zip"}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Lists hidden files when asked", func(t *testing.T) {
+		got := list(testFS(t), "/roms", true)
+		if len(got) != 3 || got[0] != ".hidden" {
+			t.Errorf("got = %v, want %v", got, []string{".hidden", "game.gb", "games.zip"})
+		}
+	})
+
+	t.Run("Lists zip archives", func(t *testing.T) {
+		got := list(testFS(t), "/roms/games.zip#", false)
+		want := []string{"disc.bin", "disc.cue"}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestHost(t *testing.T) {
+	dir := t.TempDir()
+	v := New(Host())
+	v.Allow(dir)
+
+	t.Run("Creates, updates and renames files", func(t *testing.T) {
+		p := filepath.Join(dir, "game.srm")
+		h, err := v.Open(p, libretro.VFSFileAccessWrite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.Write(h, []byte("save data"))
+		v.Close(h)
+
+		h, err = v.Open(p, libretro.VFSFileAccessReadWrite|libretro.VFSFileAccessUpdateExisting)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.Seek(h, 0, io.SeekStart)
+		v.Write(h, []byte("SAVE"))
+		v.Truncate(h, 6)
+		v.Close(h)
+
+		if err := v.Rename(p, filepath.Join(dir, "game.bak")); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := v.ReadFile(filepath.Join(dir, "game.bak"))
+		if string(got) != "SAVE d" {
+			t.Errorf("got = %v, want %v", string(got), "SAVE d")
+		}
+	})
+
+	t.Run("Reports existing directories", func(t *testing.T) {
+		if err := v.Mkdir(filepath.Join(dir, "saves")); err != nil {
+			t.Fatal(err)
+		}
+		err := v.Mkdir(filepath.Join(dir, "saves"))
+		if !errors.Is(err, fs.ErrExist) {
+			t.Errorf("got = %v, want %v", err, fs.ErrExist)
+		}
+	})
+
+	t.Run("Can't write outside the allowed directories", func(t *testing.T) {
+		_, err := v.Open(filepath.Join(filepath.Dir(dir), "evil"), libretro.VFSFileAccessWrite)
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("got = %v, want %v", err, fs.ErrPermission)
+		}
+	})
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	t.Run("Splits paths of zip archives", func(t *testing.T) {
+		archive, entry, ok := SplitArchivePath("/roms/Games.ZIP#dir/disc.cue")
+		if archive != "/roms/Games.ZIP" || entry != "dir/disc.cue" || !ok {
+			t.Errorf("got = %v %v %v, want %v %v %v", archive, entry, ok, "/roms/Games.ZIP", "dir/disc.cue", true)
+		}
+	})
+
+	t.Run("Leaves other paths alone", func(t *testing.T) {
+		archive, _, ok := SplitArchivePath("/roms/#1 game.gb")
+		if archive != "/roms/#1 game.gb" || ok {
+			t.Errorf("got = %v %v, want %v %v", archive, ok, "/roms/#1 game.gb", false)
+		}
+	})
+}
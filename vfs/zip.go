@@ -0,0 +1,56 @@
+package vfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SplitArchivePath splits paths like "/roms/game.zip#game.gb" into the path
+// of the archive and the path of the file inside it
+func SplitArchivePath(p string) (archive, entry string, ok bool) {
+	i := strings.Index(strings.ToLower(p), ".zip#")
+	if i < 0 {
+		return p, "", false
+	}
+	return p[:i+4], p[i+5:], true
+}
+
+// ArchivePath returns the path of a file inside a zip archive
+func ArchivePath(archive, entry string) string {
+	return archive + "#" + entry
+}
+
+// openZip opens a zip archive of fsys as a file system
+func openZip(fsys fs.FS, name string) (fs.FS, io.Closer, error) {
+	if strings.ToLower(path.Ext(name)) != ".zip" {
+		return nil, nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		ra = bytes.NewReader(b)
+	}
+	zr, err := zip.NewReader(ra, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return zr, f, nil
+}
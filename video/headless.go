@@ -0,0 +1,92 @@
+package video
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"unsafe"
+
+	"github.com/libretro/ludo/libretro"
+)
+
+// headless holds the last frame of the game when there is no window nor GL
+// context to render it
+type headless struct {
+	format        uint32 // libretro pixel format
+	frame         []byte // copy of the core framebuffer
+	width, height int32
+	pitch         int32
+}
+
+// NewHeadless returns a Video that keeps the frames of the game in memory
+// instead of displaying them. It doesn't need a GPU, which makes it usable to
+// run games in automated tests.
+func NewHeadless() *Video {
+	return &Video{headless: &headless{format: libretro.PixelFormat0RGB1555}}
+}
+
+// Headless is true for videos created by NewHeadless
+func (video *Video) Headless() bool {
+	return video.headless != nil
+}
+
+// refresh copies the frame, the core is free to reuse its buffer afterwards
+func (h *headless) refresh(data unsafe.Pointer, width int32, height int32, pitch int32) {
+	// A nil frame repeats the previous one
+	if data == nil {
+		return
+	}
+	size := int(pitch) * int(height)
+	if cap(h.frame) < size {
+		h.frame = make([]byte, size)
+	}
+	h.frame = h.frame[:size]
+	copy(h.frame, unsafe.Slice((*byte)(data), size))
+	h.width, h.height, h.pitch = width, height, pitch
+}
+
+// Frame returns the last frame of a headless video, or nil if the core
+// didn't render any yet or if the video isn't headless
+func (video *Video) Frame() *image.RGBA {
+	h := video.headless
+	if h == nil || h.frame == nil {
+		return nil
+	}
+	return frameToRGBA(h.frame, int(h.width), int(h.height), int(h.pitch), h.format)
+}
+
+// frameToRGBA converts a framebuffer in one of the libretro pixel formats
+func frameToRGBA(frame []byte, width, height, pitch int, format uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := frame[y*pitch:]
+		for x := 0; x < width; x++ {
+			var c color.RGBA
+			switch format {
+			case libretro.PixelFormatXRGB8888:
+				p := binary.NativeEndian.Uint32(row[x*4:])
+				c = color.RGBA{uint8(p >> 16), uint8(p >> 8), uint8(p), 0xff}
+			case libretro.PixelFormatRGB565:
+				p := binary.NativeEndian.Uint16(row[x*2:])
+				c = color.RGBA{expand5(p >> 11), expand6(p >> 5), expand5(p), 0xff}
+			default:
+				p := binary.NativeEndian.Uint16(row[x*2:])
+				c = color.RGBA{expand5(p >> 10), expand5(p >> 5), expand5(p), 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// expand5 scales the 5 lower bits of p to 8 bits
+func expand5(p uint16) uint8 {
+	v := uint8(p & 0x1f)
+	return v<<3 | v>>2
+}
+
+// expand6 scales the 6 lower bits of p to 8 bits
+func expand6(p uint16) uint8 {
+	v := uint8(p & 0x3f)
+	return v<<2 | v>>4
+}
@@ -0,0 +1,174 @@
+package video
+
+import (
+	"fmt"
+	"log"
+	"unsafe"
+
+	"github.com/go-gl/gl/v2.1/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/state"
+)
+
+// hwRender holds the framebuffer object hardware rendered cores draw into
+type hwRender struct {
+	cb            *libretro.HWRenderCallback
+	fbo           uint32 // framebuffer object
+	tex           uint32 // color attachment of the framebuffer object
+	rbo           uint32 // depth and stencil attachment
+	width, height int32  // dimensions of the framebuffer object
+	frame         bool   // true when the last frame was rendered by the core
+}
+
+// SupportsHWRender tells if our OpenGL context can host the hardware context
+// requested by a core
+func (video *Video) SupportsHWRender(cb *libretro.HWRenderCallback) bool {
+	if video.headless != nil {
+		return false
+	}
+	switch cb.ContextType {
+	case libretro.HWContextOpenGL:
+		return true
+	case libretro.HWContextOpenGLCore:
+		major, minor := parseGLVersion(gl.GoStr(gl.GetString(gl.VERSION)))
+		return major > int(cb.VersionMajor) ||
+			(major == int(cb.VersionMajor) && minor >= int(cb.VersionMinor))
+	}
+	return false
+}
+
+// InitHWRender creates the framebuffer object of a hardware rendered core
+// and lets the core create its GL resources. width and height should be the
+// maximum dimensions of the game.
+func (video *Video) InitHWRender(cb *libretro.HWRenderCallback, width, height int32) {
+	video.DeinitHWRender()
+	video.hw = &hwRender{cb: cb, width: width, height: height}
+	video.initFramebuffer()
+	cb.ContextReset()
+}
+
+// DeinitHWRender lets the core release its GL resources and deletes the
+// framebuffer object. It should be called when unloading a game.
+func (video *Video) DeinitHWRender() {
+	if video.hw == nil {
+		return
+	}
+	video.hw.cb.ContextDestroy()
+	video.deleteFramebuffer()
+	video.hw = nil
+}
+
+// ResizeHWRender grows the framebuffer object when the core changes the
+// maximum dimensions of the game
+func (video *Video) ResizeHWRender(width, height int32) {
+	if video.hw == nil || (width <= video.hw.width && height <= video.hw.height) {
+		return
+	}
+	video.deleteFramebuffer()
+	video.hw.width, video.hw.height = width, height
+	video.initFramebuffer()
+}
+
+// CurrentFramebuffer is the get_current_framebuffer callback passed to
+// hardware rendered cores
+func (video *Video) CurrentFramebuffer() uintptr {
+	if video.hw == nil {
+		return 0
+	}
+	return uintptr(video.hw.fbo)
+}
+
+// ProcAddress is the get_proc_address callback passed to hardware rendered
+// cores
+func (video *Video) ProcAddress(name string) unsafe.Pointer {
+	return glfw.GetProcAddress(name)
+}
+
+func (video *Video) initFramebuffer() {
+	hw := video.hw
+
+	gl.GenTextures(1, &hw.tex)
+	gl.BindTexture(gl.TEXTURE_2D, hw.tex)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, hw.width, hw.height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+
+	gl.GenFramebuffers(1, &hw.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, hw.fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, hw.tex, 0)
+
+	if hw.cb.Depth || hw.cb.Stencil {
+		gl.GenRenderbuffers(1, &hw.rbo)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, hw.rbo)
+		if hw.cb.Stencil {
+			gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, hw.width, hw.height)
+			gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, hw.rbo)
+		} else {
+			gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, hw.width, hw.height)
+			gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, hw.rbo)
+		}
+		gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+	}
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		log.Printf("[Video]: Incomplete framebuffer object: %d\n", status)
+	}
+
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.BindTexture(gl.TEXTURE_2D, video.texID)
+
+	if state.Verbose {
+		log.Printf("[Video]: Framebuffer object %dx%d\n", hw.width, hw.height)
+	}
+}
+
+func (video *Video) deleteFramebuffer() {
+	hw := video.hw
+	if hw.rbo != 0 {
+		gl.DeleteRenderbuffers(1, &hw.rbo)
+	}
+	gl.DeleteFramebuffers(1, &hw.fbo)
+	gl.DeleteTextures(1, &hw.tex)
+	hw.fbo, hw.tex, hw.rbo = 0, 0, 0
+}
+
+// restoreState undoes the GL state changes a hardware rendered core may
+// have left behind before we draw on the window
+func (video *Video) restoreState() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.STENCIL_TEST)
+	gl.Disable(gl.SCISSOR_TEST)
+	gl.Disable(gl.CULL_FACE)
+	gl.Disable(gl.BLEND)
+	gl.ActiveTexture(gl.TEXTURE0)
+	bindVertexArray(video.vao)
+	video.ResizeViewport()
+}
+
+// hwUV maps the texture coordinates of the game quad to the part of the
+// framebuffer object the core rendered to, flipping them for cores using
+// a bottom left origin
+func hwUV(va []float32, sx, sy float32, flip bool) []float32 {
+	for i := 0; i+3 < len(va); i += 4 {
+		v := va[i+3]
+		if flip {
+			v = 1 - v
+		}
+		va[i+2] *= sx
+		va[i+3] = v * sy
+	}
+	return va
+}
+
+// parseGLVersion extracts the major and minor version from a GL_VERSION
+// string like "4.5 (Compatibility Profile) Mesa 23.2.1"
+func parseGLVersion(version string) (major, minor int) {
+	fmt.Sscanf(version, "%d.%d", &major, &minor)
+	return
+}
@@ -0,0 +1,46 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_hwUV(t *testing.T) {
+	t.Run("Scales the coordinates to the rendered part of the framebuffer", func(t *testing.T) {
+		va := []float32{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 1, 1, 0, 0, 1, 0}
+		got := hwUV(va, 0.5, 0.25, false)
+		want := []float32{0, 0, 0, 0.25, 0, 0, 0, 0, 0, 0, 0.5, 0.25, 0, 0, 0.5, 0}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Flips the image of cores using a bottom left origin", func(t *testing.T) {
+		va := []float32{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 1, 1, 0, 0, 1, 0}
+		got := hwUV(va, 1, 0.5, true)
+		want := []float32{0, 0, 0, 0, 0, 0, 0, 0.5, 0, 0, 1, 0, 0, 0, 1, 0.5}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_parseGLVersion(t *testing.T) {
+	tests := []struct {
+		version   string
+		wantMajor int
+		wantMinor int
+	}{
+		{"4.5 (Compatibility Profile) Mesa 23.2.1", 4, 5},
+		{"2.1 Metal - 83.1", 2, 1},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			major, minor := parseGLVersion(tt.version)
+			if major != tt.wantMajor || minor != tt.wantMinor {
+				t.Errorf("got = %v.%v, want %v.%v", major, minor, tt.wantMajor, tt.wantMinor)
+			}
+		})
+	}
+}
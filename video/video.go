@@ -42,6 +42,9 @@ type Video struct {
 
 	needUpload bool // true when the texture needs to be uploaded to the GPU
 	data       unsafe.Pointer
+
+	hw       *hwRender // set while a hardware rendered core is running
+	headless *headless // set when frames are kept in memory instead of displayed
 }
 
 // Init instanciates the video package
@@ -53,10 +56,19 @@ func Init(fullscreen bool) *Video {
 
 // Reconfigure destroys and recreates the window with new attributes
 func (video *Video) Reconfigure(fullscreen bool) {
+	// The GL context of a hardware rendered core dies with the window
+	if video.hw != nil {
+		video.hw.cb.ContextDestroy()
+		video.deleteFramebuffer()
+	}
 	if video.Window != nil {
 		video.Window.Destroy()
 	}
 	video.Configure(fullscreen)
+	if video.hw != nil {
+		video.initFramebuffer()
+		video.hw.cb.ContextReset()
+	}
 }
 
 // GetFramebufferSize retrieves the size, in pixels, of the framebuffer of the specified window.
@@ -263,6 +275,13 @@ func (video *Video) SetPixelFormat(format uint32) bool {
 	// PixelStorei also needs to be updated whenever bpp changes
 	defer func() { video.needUpload = true }()
 
+	if video.headless != nil {
+		switch format {
+		case libretro.PixelFormat0RGB1555, libretro.PixelFormatXRGB8888, libretro.PixelFormatRGB565:
+			video.headless.format = format
+		}
+	}
+
 	switch format {
 	case libretro.PixelFormat0RGB1555:
 		video.pixFmt = gl.UNSIGNED_SHORT_5_5_5_1
@@ -324,6 +343,12 @@ func (video *Video) coreRatioViewport(fbWidth int, fbHeight int) (x, y, w, h flo
 
 	va := video.vertexArray(x, y, w, h, 1.0)
 	va = rotateUV(va, video.rot)
+	if video.hw != nil && video.hw.frame {
+		va = hwUV(va,
+			float32(video.width)/float32(video.hw.width),
+			float32(video.height)/float32(video.hw.height),
+			video.hw.cb.BottomLeftOrigin)
+	}
 	gl.BindBuffer(gl.ARRAY_BUFFER, video.vbo)
 	gl.BufferData(gl.ARRAY_BUFFER, len(va)*4, gl.Ptr(va), gl.STATIC_DRAW)
 
@@ -338,6 +363,9 @@ func (video *Video) ResizeViewport() {
 
 // Render the current frame
 func (video *Video) Render() {
+	if video.hw != nil {
+		video.restoreState()
+	}
 	if !state.CoreRunning {
 		gl.ClearColor(1, 1, 1, 1)
 		gl.Clear(gl.COLOR_BUFFER_BIT)
@@ -362,7 +390,13 @@ func (video *Video) Render() {
 
 	bindVertexArray(video.vao)
 
-	gl.BindTexture(gl.TEXTURE_2D, video.texID)
+	if video.hw != nil && video.hw.frame {
+		gl.Uniform2f(gl.GetUniformLocation(video.program, gl.Str("TextureSize\x00")), float32(video.hw.width), float32(video.hw.height))
+		gl.Uniform2f(gl.GetUniformLocation(video.program, gl.Str("InputSize\x00")), float32(video.width), float32(video.height))
+		gl.BindTexture(gl.TEXTURE_2D, video.hw.tex)
+	} else {
+		gl.BindTexture(gl.TEXTURE_2D, video.texID)
+	}
 	gl.BindBuffer(gl.ARRAY_BUFFER, video.vbo)
 
 	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
@@ -370,6 +404,23 @@ func (video *Video) Render() {
 
 // Refresh the texture framebuffer
 func (video *Video) Refresh(data unsafe.Pointer, width int32, height int32, pitch int32) {
+	if video.headless != nil {
+		video.headless.refresh(data, width, height, pitch)
+		return
+	}
+	if video.hw != nil {
+		// A duped frame keeps the previous one on screen
+		if data == nil {
+			return
+		}
+		// The pitch is meaningless for hardware frames, but Render waits
+		// for a non zero one
+		video.hw.frame = data == libretro.HWFrameBufferValid
+		if video.hw.frame {
+			data = nil
+			pitch = width * 4
+		}
+	}
 	video.needUpload = true
 	video.width = width
 	video.height = height
@@ -0,0 +1,126 @@
+package voucher
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// Store keeps track of the codes that have already been used on this cabinet.
+// Redemptions are appended to a CSV file so they survive restarts.
+type Store struct {
+	path     string
+	mu       sync.Mutex
+	loaded   bool
+	redeemed map[string]time.Time
+}
+
+// Local is the redemption store shared by the web UI and the in-game menu
+var Local = NewStore(filepath.Join(xdg.DataHome, "ludo", "vouchers.csv"))
+
+// NewStore creates a store backed by the given file. The file is read lazily.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Check verifies a code without redeeming it
+func (s *Store) Check(secret []byte, code string) (Voucher, error) {
+	v, err := Parse(secret, code)
+	if err != nil {
+		return v, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if _, ok := s.redeemed[Normalize(code)]; ok {
+		return v, ErrRedeemed
+	}
+	return v, nil
+}
+
+// Redeem verifies a code and marks it as used
+func (s *Store) Redeem(secret []byte, code string) (Voucher, error) {
+	v, err := Parse(secret, code)
+	if err != nil {
+		return v, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	key := Normalize(code)
+	if _, ok := s.redeemed[key]; ok {
+		return v, ErrRedeemed
+	}
+
+	now := time.Now()
+	if err := s.append(key, now); err != nil {
+		return v, err
+	}
+	s.redeemed[key] = now
+	return v, nil
+}
+
+// load reads the redemption file once. A missing file is an empty store.
+func (s *Store) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.redeemed = map[string]time.Time{}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("[Voucher]:", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	r := csv.NewReader(bufio.NewReader(file))
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Println("[Voucher]:", err)
+			return
+		}
+		if len(record) < 2 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, record[1])
+		s.redeemed[record[0]] = t
+	}
+}
+
+func (s *Store) append(key string, t time.Time) error {
+	err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{key, t.Format(time.RFC3339)})
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
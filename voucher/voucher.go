@@ -0,0 +1,146 @@
+// Package voucher implements promotional codes worth free minutes or a
+// percentage off. Codes are signed with an HMAC so a cabinet can verify them
+// without a network connection, and redemptions are recorded locally so that
+// each code can only be used once.
+package voucher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Kind is the type of reward a voucher grants
+type Kind byte
+
+// The two kinds of vouchers
+const (
+	Minutes Kind = 'M' // Value is a number of free minutes
+	Percent Kind = 'P' // Value is a discount in percent
+)
+
+// Voucher is the decoded content of a code
+type Voucher struct {
+	Kind   Kind
+	Value  int    // Free minutes or discount percentage
+	Serial uint32 // Distinguishes codes of the same kind and value
+}
+
+// MaxSerial is the highest serial number that fits in a code
+const MaxSerial = 1<<24 - 1
+
+// Errors returned when generating or checking codes
+var (
+	ErrInvalid  = errors.New("invalid voucher code")
+	ErrRedeemed = errors.New("voucher already redeemed")
+	ErrNoSecret = errors.New("vouchers are not configured")
+)
+
+const (
+	payloadLen = 6 // kind, value (2 bytes), serial (3 bytes)
+	macLen     = 4
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Generate signs the voucher with the secret and returns a printable code
+// like ABCD-EFGH-IJKL-MNOP.
+func Generate(secret []byte, v Voucher) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrNoSecret
+	}
+	switch v.Kind {
+	case Minutes:
+		if v.Value < 1 || v.Value > math.MaxUint16 {
+			return "", fmt.Errorf("minutes out of range: %d", v.Value)
+		}
+	case Percent:
+		if v.Value < 1 || v.Value > 100 {
+			return "", fmt.Errorf("percentage out of range: %d", v.Value)
+		}
+	default:
+		return "", fmt.Errorf("unknown voucher kind: %q", v.Kind)
+	}
+	if v.Serial > MaxSerial {
+		return "", fmt.Errorf("serial out of range: %d", v.Serial)
+	}
+
+	b := make([]byte, payloadLen, payloadLen+macLen)
+	b[0] = byte(v.Kind)
+	binary.BigEndian.PutUint16(b[1:3], uint16(v.Value))
+	b[3] = byte(v.Serial >> 16)
+	b[4] = byte(v.Serial >> 8)
+	b[5] = byte(v.Serial)
+	b = append(b, sign(secret, b)...)
+
+	s := encoding.EncodeToString(b)
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16], nil
+}
+
+// Parse verifies the signature of a code and decodes it
+func Parse(secret []byte, code string) (Voucher, error) {
+	if len(secret) == 0 {
+		return Voucher{}, ErrNoSecret
+	}
+
+	b, err := encoding.DecodeString(Normalize(code))
+	if err != nil || len(b) != payloadLen+macLen {
+		return Voucher{}, ErrInvalid
+	}
+	if !hmac.Equal(b[payloadLen:], sign(secret, b[:payloadLen])) {
+		return Voucher{}, ErrInvalid
+	}
+
+	v := Voucher{
+		Kind:   Kind(b[0]),
+		Value:  int(binary.BigEndian.Uint16(b[1:3])),
+		Serial: uint32(b[3])<<16 | uint32(b[4])<<8 | uint32(b[5]),
+	}
+	if v.Kind != Minutes && v.Kind != Percent {
+		return Voucher{}, ErrInvalid
+	}
+	return v, nil
+}
+
+// Normalize strips separators and fixes characters that are easily mistyped,
+// so that the same code always maps to the same string.
+func Normalize(code string) string {
+	r := strings.NewReplacer("-", "", " ", "", "0", "O", "1", "I", "8", "B")
+	return r.Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// Apply computes the minutes of play and the price of a session of the given
+// length once the voucher is taken into account. A minutes voucher makes the
+// first minutes free, so a player who picks less than the voucher's value still
+// gets all of it.
+func (v Voucher) Apply(minutes int, perMinute float64) (int, float64) {
+	switch v.Kind {
+	case Minutes:
+		if minutes <= v.Value {
+			return v.Value, 0
+		}
+		return minutes, float64(minutes-v.Value) * perMinute
+	case Percent:
+		return minutes, float64(minutes) * perMinute * float64(100-v.Value) / 100
+	}
+	return minutes, float64(minutes) * perMinute
+}
+
+// String describes the reward for display
+func (v Voucher) String() string {
+	if v.Kind == Percent {
+		return fmt.Sprintf("%d%% off", v.Value)
+	}
+	return fmt.Sprintf("%d free minutes", v.Value)
+}
+
+func sign(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)[:macLen]
+}
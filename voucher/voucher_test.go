@@ -0,0 +1,127 @@
+package voucher
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var secret = []byte("test secret")
+
+func TestGenerate(t *testing.T) {
+	t.Run("Round trips through Parse", func(t *testing.T) {
+		want := Voucher{Kind: Minutes, Value: 15, Serial: 4242}
+		code, err := Generate(secret, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(secret, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Rejects out of range percentages", func(t *testing.T) {
+		_, err := Generate(secret, Voucher{Kind: Percent, Value: 120})
+		if err == nil {
+			t.Errorf("got = %v, want an error", err)
+		}
+	})
+
+	t.Run("Needs a secret", func(t *testing.T) {
+		_, err := Generate(nil, Voucher{Kind: Percent, Value: 20})
+		if err != ErrNoSecret {
+			t.Errorf("got = %v, want %v", err, ErrNoSecret)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	code, _ := Generate(secret, Voucher{Kind: Percent, Value: 50, Serial: 1})
+
+	t.Run("Accepts lower case codes without dashes", func(t *testing.T) {
+		got, err := Parse(secret, strings.ToLower(strings.ReplaceAll(code, "-", "")))
+		want := Voucher{Kind: Percent, Value: 50, Serial: 1}
+		if err != nil || got != want {
+			t.Errorf("got = %v, %v, want %v", got, err, want)
+		}
+	})
+
+	t.Run("Rejects codes signed with another secret", func(t *testing.T) {
+		_, err := Parse([]byte("other secret"), code)
+		if err != ErrInvalid {
+			t.Errorf("got = %v, want %v", err, ErrInvalid)
+		}
+	})
+
+	t.Run("Rejects tampered codes", func(t *testing.T) {
+		b := []byte(code)
+		if b[0] == 'A' {
+			b[0] = 'B'
+		} else {
+			b[0] = 'A'
+		}
+		_, err := Parse(secret, string(b))
+		if err != ErrInvalid {
+			t.Errorf("got = %v, want %v", err, ErrInvalid)
+		}
+	})
+
+	t.Run("Rejects garbage", func(t *testing.T) {
+		_, err := Parse(secret, "hello")
+		if err != ErrInvalid {
+			t.Errorf("got = %v, want %v", err, ErrInvalid)
+		}
+	})
+}
+
+func TestVoucher_Apply(t *testing.T) {
+	tests := []struct {
+		name        string
+		v           Voucher
+		minutes     int
+		wantMinutes int
+		wantPrice   float64
+	}{
+		{"Minutes voucher covers a short session", Voucher{Kind: Minutes, Value: 10}, 5, 10, 0},
+		{"Minutes voucher makes the first minutes free", Voucher{Kind: Minutes, Value: 10}, 15, 15, 2.5},
+		{"Percent voucher discounts the price", Voucher{Kind: Percent, Value: 50}, 10, 10, 2.5},
+		{"Free play voucher", Voucher{Kind: Percent, Value: 100}, 10, 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMinutes, gotPrice := tt.v.Apply(tt.minutes, 0.5)
+			if gotMinutes != tt.wantMinutes || gotPrice != tt.wantPrice {
+				t.Errorf("got = %v %v, want %v %v", gotMinutes, gotPrice, tt.wantMinutes, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestStore_Redeem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vouchers.csv")
+	code, _ := Generate(secret, Voucher{Kind: Minutes, Value: 5, Serial: 7})
+
+	t.Run("Redeems a fresh code", func(t *testing.T) {
+		_, err := NewStore(path).Redeem(secret, code)
+		if err != nil {
+			t.Errorf("got = %v, want %v", err, nil)
+		}
+	})
+
+	t.Run("Refuses to redeem a code twice, even after a restart", func(t *testing.T) {
+		s := NewStore(path)
+		_, err := s.Check(secret, code)
+		if err != ErrRedeemed {
+			t.Errorf("got = %v, want %v", err, ErrRedeemed)
+		}
+		_, err = s.Redeem(secret, strings.ToLower(code))
+		if err != ErrRedeemed {
+			t.Errorf("got = %v, want %v", err, ErrRedeemed)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package webui
+
+import (
+	"github.com/libretro/ludo/catalog"
+	"github.com/libretro/ludo/session"
+)
+
+// ShowCatalog goes back to the game grid. The page fetches the games from
+// /api/games, as the parental controls depend on the time of day.
+func (s *Server) ShowCatalog(games catalog.Catalog) {
+	s.SetState(StateSelectGame)
+}
+
+// ShowTimeSelect shows the time slider
+func (s *Server) ShowTimeSelect(game catalog.Game, seats int) {
+	s.SetState(StateTimeSelect)
+}
+
+// ShowVoucher shows the voucher entry, or tells why the code was refused
+func (s *Server) ShowVoucher(err error) {
+	if err != nil {
+		s.broadcastMessage("voucher_result", map[string]interface{}{
+			"ok":      false,
+			"message": err.Error(),
+		})
+		return
+	}
+	s.SetState(StateVoucher)
+}
+
+// ShowPayment asks for coins. The effect of the voucher on the first seat is
+// sent before the state change so that the page can display it.
+func (s *Server) ShowPayment(offer session.Offer) {
+	if offer.Extension {
+		s.SetState(StateExtendPayment)
+		return
+	}
+
+	if offer.Voucher != nil {
+		minutes, price := offer.Voucher.Apply(offer.Minutes, session.PricePerMinute)
+		s.broadcastMessage("voucher_result", map[string]interface{}{
+			"ok":      true,
+			"message": offer.Voucher.String(),
+			"minutes": minutes,
+			"price":   price,
+		})
+	}
+	s.SetState(StatePayment)
+}
+
+// ShowExtend shows the timeout overlay
+func (s *Server) ShowExtend() {
+	s.SetState(StateExtendTime)
+}
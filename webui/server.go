@@ -8,7 +8,8 @@ import (
 	"sync"
 	"time"
 
-	"github.com/libretro/ludo/ludo"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/session"
 )
 
 // ServerState represents different states of the application
@@ -22,16 +23,12 @@ const (
 	StateExtendPayment
 	StateGameLoading // Add new state for when game is loading
 	StateGameActive  // New state for when game is active after extension
+	StateVoucher     // Optional voucher code entry before payment
 )
 
 // Server holds the web server state and data
 type Server struct {
-	games            map[string]string
-	cores            map[string]string
-	gamePictures     map[string]string
-	timerChan        chan int
-	resumeChan       chan bool
-	gameLoadedChan   chan bool // Add channel for game loading confirmation
+	session          *session.Controller
 	hub              *Hub
 	state            ServerState
 	stateMutex       sync.RWMutex
@@ -43,25 +40,27 @@ type Server struct {
 	browserCmd       *exec.Cmd   // Current browser process
 	browserPID       int         // Current browser PID
 	previousBrowsers []*exec.Cmd // Track previously opened browsers
+
+	deviceEvents chan input.DeviceEvent // Sent in order by sendDeviceEvents
 }
 
-const PricePerMinute = 0.5
+// deviceEventsQueue is the number of device events waiting to be sent before
+// new ones are dropped
+const deviceEventsQueue = 256
 
-// NewServer creates a new web server instance
-func NewServer(games, cores, gamePictures map[string]string, timerChan chan int, resumeChan chan bool) *Server {
+// NewServer creates a new web server instance, rendering the given session
+// in a browser
+func NewServer(c *session.Controller) *Server {
 	s := &Server{
-		games:          games,
-		cores:          cores,
-		gamePictures:   gamePictures,
-		timerChan:      timerChan,
-		resumeChan:     resumeChan,
-		gameLoadedChan: make(chan bool, 1), // Add buffered channel for game loading
-		state:          StateSelectGame,
+		session:      c,
+		state:        StateSelectGame,
+		deviceEvents: make(chan input.DeviceEvent, deviceEventsQueue),
 	}
 
 	// Create WebSocket hub
 	s.hub = newHub(s)
 	go s.hub.run()
+	go s.sendDeviceEvents()
 
 	return s
 }
@@ -74,6 +73,9 @@ func (s *Server) Start(addr string) error {
 
 	// API endpoints
 	http.HandleFunc("/api/games", s.handleGames)
+	http.HandleFunc("/api/cabinet", s.handleCabinet)
+	http.HandleFunc("/api/telemetry", s.handleTelemetry)
+	http.HandleFunc("/api/firmware", s.handleFirmware)
 
 	// WebSocket endpoint
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
@@ -158,61 +160,18 @@ func (s *Server) launchBrowserFullscreen(url string) {
 	}
 }
 
-// HandleTimeout is called when the game timer expires - updated to minimize game window
-func (s *Server) HandleTimeout() {
-	// Read window positioning information
-	var x, y, width, height int
-
-	// Use select with short timeout to avoid blocking if values aren't available
-	timeout := time.After(200 * time.Millisecond)
-
-	// Try to read X position
-	select {
-	case x = <-s.timerChan:
-	case <-timeout:
-		log.Println("Timeout reading game window X position")
-		x = 0
-	}
-
-	// Try to read Y position
-	select {
-	case y = <-s.timerChan:
-	case <-timeout:
-		log.Println("Timeout reading game window Y position")
-		y = 0
-	}
-
-	// Try to read width
-	select {
-	case width = <-s.timerChan:
-	case <-timeout:
-		log.Println("Timeout reading game window width")
-		width = 800
-	}
-
-	// Try to read height
-	select {
-	case height = <-s.timerChan:
-	case <-timeout:
-		log.Println("Timeout reading game window height")
-		height = 600
-	}
-
+// OnTimeout brings the browser back in front of the paused game
+func (s *Server) OnTimeout(window session.Window) {
 	// Store window information
 	s.gameWindowMutex.Lock()
-	s.gameWindowX = x
-	s.gameWindowY = y
-	s.gameWindowWidth = width
-	s.gameWindowHeight = height
+	s.gameWindowX = window.X
+	s.gameWindowY = window.Y
+	s.gameWindowWidth = window.Width
+	s.gameWindowHeight = window.Height
 	s.gameWindowMutex.Unlock()
 
 	log.Println("Timeout occurred, maximizing browser window to reveal web UI")
 
-	// IMPORTANT: Force the state change and ensure it's broadcast
-	log.Printf("Current state before timeout: %v", s.GetState())
-	s.SetState(StateExtendTime)
-	log.Printf("State after timeout: %v", s.GetState())
-
 	// Give a moment for the state to be processed
 	time.Sleep(100 * time.Millisecond)
 
@@ -229,8 +188,8 @@ func (s *Server) HandleTimeout() {
 	})
 }
 
-// PrepareTimeout just ensures browser is ready and warns about upcoming timeout
-func (s *Server) PrepareTimeout() {
+// OnPrepareTimeout just ensures browser is ready and warns about upcoming timeout
+func (s *Server) OnPrepareTimeout() {
 	log.Println("Preparing for timeout - game will be minimized in 10 seconds")
 
 	// Send a message to prepare the browser for timeout
@@ -245,18 +204,24 @@ func (s *Server) PrepareTimeout() {
 	s.hub.broadcast <- jsonMsg
 }
 
-// handleGames returns the list of available games
+// handleGames returns the list of games allowed by the parental controls at
+// the time of the request
 func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
 	type GameInfo struct {
-		Name      string `json:"name"`
-		ImagePath string `json:"imagePath"`
+		Name      string   `json:"name"`
+		ImagePath string   `json:"imagePath"`
+		Rating    string   `json:"rating"`
+		Tags      []string `json:"tags"`
 	}
 
-	games := make([]GameInfo, 0, len(s.games))
-	for name := range s.games {
+	allowed := s.session.Catalog()
+	games := make([]GameInfo, 0, len(allowed))
+	for _, g := range allowed {
 		games = append(games, GameInfo{
-			Name:      name,
-			ImagePath: s.gamePictures[name],
+			Name:      g.Name,
+			ImagePath: g.ImagePath,
+			Rating:    g.Rating.String(),
+			Tags:      g.Tags,
 		})
 	}
 
@@ -264,6 +229,27 @@ func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(games)
 }
 
+// handleCabinet describes the cabinet to the web page, with the error of the
+// parental controls for the operator
+func (s *Server) handleCabinet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	cabinet := map[string]interface{}{
+		"seats":          s.session.Seats(),
+		"pricePerMinute": session.PricePerMinute,
+	}
+	if err := s.session.PolicyError(); err != nil {
+		cabinet["parentalError"] = err.Error()
+	}
+	json.NewEncoder(w).Encode(cabinet)
+}
+
+// handleFirmware reports the present, missing and wrong versions of the BIOS
+// files needed by the cores
+func (s *Server) handleFirmware(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.session.Firmware())
+}
+
 // GetState returns the current UI state
 func (s *Server) GetState() ServerState {
 	s.stateMutex.RLock()
@@ -281,41 +267,16 @@ func (s *Server) SetState(state ServerState) {
 	s.hub.broadcastState()
 }
 
-// LaunchGame starts a game with the given name and time
-func (s *Server) LaunchGame(gameName string, minutes int) {
-	log.Printf("Launching game: %s for %d minutes", gameName, minutes)
-
-	// Set state to loading
+// showLoading tells the players that the game is starting
+func (s *Server) showLoading() {
 	s.SetState(StateGameLoading)
-
-	// Send loading message to clients
-	msg := Message{
-		Type: "game_loading",
-		Payload: map[string]interface{}{
-			"message": "Starting game...",
-		},
-	}
-
-	jsonMsg, _ := json.Marshal(msg)
-	s.hub.broadcast <- jsonMsg
-
-	corePath := s.cores[gameName]
-	gamePath := s.games[gameName]
-	durationSecs := minutes * 2 // Change to 2 minutes for testing
-
-	// Launch game in goroutine
-	go func() {
-		err := s.launchLudoGame(corePath, gamePath, durationSecs)
-		if err != nil {
-			log.Printf("Error launching game: %v", err)
-			// If game launch fails, go back to game selection
-			s.SetState(StateSelectGame)
-			return
-		}
-	}()
+	s.broadcastMessage("game_loading", map[string]interface{}{
+		"message": "Starting game...",
+	})
 }
 
-// OnGameLoaded should be called when Ludo has successfully loaded the game
+// OnGameLoaded is called when the game runs, after a launch or after the
+// session was extended
 func (s *Server) OnGameLoaded() {
 	log.Println("Server: Game loaded confirmation received")
 
@@ -333,17 +294,20 @@ func (s *Server) OnGameLoaded() {
 	jsonMsg, _ := json.Marshal(msg)
 	s.hub.broadcast <- jsonMsg
 
-	// Let the game channel know we received the signal
-	select {
-	case s.gameLoadedChan <- true:
-	default:
-		// Channel is full, ignore
-	}
-
 	// Use wmctrl to maximize the game window
 	s.maximizeGame()
 }
 
+// broadcastMessage sends a typed message to all clients
+func (s *Server) broadcastMessage(msgType string, payload interface{}) {
+	jsonMsg, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", msgType, err)
+		return
+	}
+	s.hub.broadcast <- jsonMsg
+}
+
 // broadcastWindowPosition sends the game window position to all clients
 func (s *Server) broadcastWindowPosition() {
 	s.gameWindowMutex.RLock()
@@ -375,13 +339,38 @@ func (s *Server) broadcastWindowPosition() {
 	s.hub.broadcast <- jsonData
 }
 
-// launchLudoGame launches a game using the ludo package
-func (s *Server) launchLudoGame(corePath, gamePath string, durationSecs int) error {
-	return ludo.RunGame(corePath, gamePath, durationSecs, s.timerChan, s.resumeChan)
+// OnSeatExpired is called when one player of a multiplayer session runs
+// out of time while the others keep playing
+func (s *Server) OnSeatExpired(seat int) {
+	log.Printf("Seat %d is out of time", seat)
+	s.broadcastMessage("seat_expired", map[string]interface{}{
+		"seat": seat,
+	})
 }
 
-// RunGame is a wrapper around ludo.RunGame
-func RunGame(corePath, gamePath string, durationSecs int, timerChan chan int, resumeChan chan bool) error {
-	// Forward the call to ludo package
-	return ludo.RunGame(corePath, gamePath, durationSecs, timerChan, resumeChan)
+// OnDeviceEvent shows the rumble, LED and sensor state of the game. It is
+// called with the input devices locked and must not block, so the event is
+// queued and sent later by sendDeviceEvents.
+func (s *Server) OnDeviceEvent(e input.DeviceEvent) {
+	select {
+	case s.deviceEvents <- e:
+	default:
+		log.Printf("Device events queue is full, dropping %s event", e.Kind)
+	}
+}
+
+// sendDeviceEvents sends the queued device events one by one, so that the
+// browser receives them in the order they happened
+func (s *Server) sendDeviceEvents() {
+	for e := range s.deviceEvents {
+		s.broadcastMessage("device", e)
+	}
+}
+
+// OnSeatExtended is called when a player bought more time during the game
+func (s *Server) OnSeatExtended(seat, minutes int) {
+	s.broadcastMessage("seat_extended", map[string]interface{}{
+		"seat":    seat,
+		"minutes": minutes,
+	})
 }
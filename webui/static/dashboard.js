@@ -0,0 +1,58 @@
+// Usage dashboard, reads the analytics recorded by the kiosk
+
+function percent(rate) {
+  return `${Math.round(rate * 100)}%`;
+}
+
+function duration(seconds) {
+  const minutes = Math.floor(seconds / 60);
+  const secs = Math.round(seconds % 60);
+  return `${minutes}:${secs.toString().padStart(2, "0")}`;
+}
+
+function fillTable(id, rows) {
+  const body = document.getElementById(id);
+  body.innerHTML = "";
+  rows.forEach((cells) => {
+    const tr = document.createElement("tr");
+    cells.forEach((cell) => {
+      const td = document.createElement("td");
+      td.textContent = cell;
+      tr.appendChild(td);
+    });
+    body.appendChild(tr);
+  });
+}
+
+function loadTelemetry() {
+  const days = document.getElementById("dashboard-days").value;
+  fetch(`/api/telemetry?days=${days}`)
+    .then((response) => response.json())
+    .then((data) => {
+      const s = data.summary;
+      document.getElementById("stat-sessions").textContent = s.sessions;
+      document.getElementById("stat-length").textContent = duration(s.averageSessionSeconds);
+      document.getElementById("stat-minutes").textContent = s.minutesSold;
+      document.getElementById("stat-extension").textContent = percent(s.extensionRate);
+      document.getElementById("stat-abandonment").textContent = percent(s.abandonmentRate);
+
+      fillTable(
+        "games-table",
+        s.popularGames.map((g) => [g.name, g.sessions])
+      );
+      fillTable(
+        "daily-table",
+        data.daily
+          .slice()
+          .reverse()
+          .map((d) => [d.day, d.summary.sessions, d.summary.minutesSold, d.summary.extensions, d.summary.paymentAbandoned])
+      );
+    })
+    .catch((error) => {
+      console.error("Error loading telemetry:", error);
+    });
+}
+
+document.getElementById("dashboard-days").addEventListener("change", loadTelemetry);
+loadTelemetry();
+setInterval(loadTelemetry, 60000);
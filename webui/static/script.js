@@ -7,6 +7,7 @@ const STATE = {
   EXTEND_PAYMENT: 4,
   GAME_LOADING: 5, // Add new loading state
   GAME_ACTIVE: 6, // Add new active game state
+  VOUCHER: 7, // Optional voucher code before payment
 };
 
 // Application state
@@ -17,6 +18,15 @@ let appState = {
   games: [],
   timeValue: 5,
   pricePerMinute: 0.5,
+  voucher: null, // Accepted voucher: { message, minutes, price }
+  maxSeats: 1, // Number of players that can buy time on this cabinet
+  players: 1, // Number of players in the next session
+  seatsPaid: 0, // Players who already paid at the payment step
+  expiredSeats: [], // Seats out of time while the others keep playing
+  extendSeat: null, // Seat picked for a time extension during the game
+  rumble: {}, // Strength of the rumble motors by port, while they run
+  leds: {}, // State of the LEDs driven by the game, while they are lit
+  illuminance: null, // Ambient light in lux when the game reads a solar sensor
 };
 
 // WebSocket connection
@@ -25,6 +35,7 @@ let socket;
 // Initialize the application
 document.addEventListener("DOMContentLoaded", () => {
   initWebSocket();
+  loadCabinet();
   loadGames();
   setupEventListeners();
 
@@ -91,6 +102,29 @@ function handleWebSocketMessage(message) {
       updateUIState(STATE.GAME_ACTIVE);
       break;
 
+    case "seat_expired":
+      if (!appState.expiredSeats.includes(message.payload.seat)) {
+        appState.expiredSeats.push(message.payload.seat);
+      }
+      showExpiredSeats();
+      break;
+
+    case "device":
+      updateDevice(message.payload);
+      showExpiredSeats();
+      break;
+
+    case "seat_extended":
+      appState.expiredSeats = appState.expiredSeats.filter(
+        (seat) => seat !== message.payload.seat
+      );
+      showExpiredSeats();
+      break;
+
+    case "voucher_result":
+      handleVoucherResult(message.payload);
+      break;
+
     case "prepare_timeout":
       // Game will timeout soon, prepare UI
       console.log("Preparing for timeout:", message.payload.message);
@@ -172,7 +206,7 @@ function handleTimeoutKeypress(event) {
         }
         
         // Send message to confirm time selection
-        sendMessage("selectTime", appState.timeValue);
+        sendMessage("selectTime", { minutes: appState.timeValue, players: 1 });
         
         // State will be updated via WebSocket message
         break;
@@ -457,7 +491,7 @@ function updateUIState(newState) {
   // If transitioning from EXTEND_TIME to EXTEND_PAYMENT, send an extra selectTime message
   if (appState.currentState === STATE.EXTEND_TIME && newState === STATE.EXTEND_PAYMENT) {
     console.log("Critical state transition: Sending additional selectTime message");
-    sendMessage("selectTime", appState.timeValue);
+    sendMessage("selectTime", { minutes: appState.timeValue, players: 1 });
   }
   
   // Update current state
@@ -466,12 +500,14 @@ function updateUIState(newState) {
   const gameGrid = document.getElementById("game-grid");
   const timeSelection = document.getElementById("time-selection");
   const paymentPrompt = document.getElementById("payment-prompt");
+  const voucherEntry = document.getElementById("voucher-entry");
   const statusText = document.getElementById("status-text");
 
   // Hide all sections first
   gameGrid.classList.add("hidden");
   timeSelection.classList.add("hidden");
   paymentPrompt.classList.add("hidden");
+  voucherEntry.classList.add("hidden");
 
   // Remove overlay if present and not in timeout states
   if (newState !== STATE.EXTEND_TIME && newState !== STATE.EXTEND_PAYMENT) {
@@ -490,17 +526,32 @@ function updateUIState(newState) {
     case STATE.SELECT_GAME:
       statusText.textContent = "◄ ► ▲ ▼ NAVIGATE    ENTER TO CONTINUE";
       gameGrid.classList.remove("hidden");
+      appState.voucher = null;
+      appState.players = 1;
+      appState.expiredSeats = [];
+      // Parental controls depend on the time of day, refresh the catalog
+      loadGames();
       break;
 
     case STATE.TIME_SELECT:
-      statusText.textContent = "◄ ► ADJUST TIME    ENTER TO CONTINUE";
+      statusText.textContent = "◄ ► ADJUST TIME    ENTER TO CONTINUE    ESC TO GO BACK";
       timeSelection.classList.remove("hidden");
+      showPlayers();
       updatePrice();
       break;
 
+    case STATE.VOUCHER:
+      statusText.textContent = "TYPE YOUR VOUCHER CODE    ENTER TO APPLY    ESC TO SKIP";
+      voucherEntry.classList.remove("hidden");
+      showVoucherEntry();
+      break;
+
     case STATE.PAYMENT:
-      statusText.textContent = "PRESS 'P' TO INSERT COIN AND START GAME";
+      statusText.textContent = "PRESS 'P' TO INSERT COIN AND START GAME    ESC TO GO BACK";
       paymentPrompt.classList.remove("hidden");
+      appState.seatsPaid = 0;
+      showVoucherInPayment(statusText);
+      showSeatPayment();
       break;
 
     case STATE.EXTEND_TIME:
@@ -532,6 +583,7 @@ function updateUIState(newState) {
     case STATE.GAME_ACTIVE:
       console.log("Game active state - hiding all UI elements");
       statusText.textContent = "GAME IN PROGRESS...";
+      showExpiredSeats();
       // Ensure all UI elements are hidden during active game
       const overlay = document.getElementById("game-overlay");
       if (overlay) {
@@ -561,6 +613,9 @@ function loadGames() {
     .then((response) => response.json())
     .then((games) => {
       appState.games = games;
+      if (appState.selectedGameIndex >= games.length) {
+        appState.selectedGameIndex = 0;
+      }
       renderGameGrid();
     })
     .catch((error) => {
@@ -601,6 +656,14 @@ function renderGameGrid() {
 
     imgContainer.appendChild(img);
     tile.appendChild(imgContainer);
+
+    if (game.rating && game.rating !== "All ages") {
+      const ratingBadge = document.createElement("div");
+      ratingBadge.className = "game-tile-rating";
+      ratingBadge.textContent = game.rating;
+      tile.appendChild(ratingBadge);
+    }
+
     tile.appendChild(nameDiv);
 
     tile.addEventListener("click", () => {
@@ -664,6 +727,9 @@ function updatePrice() {
   
   const price = appState.timeValue * appState.pricePerMinute;
   priceLabel.textContent = `TOTAL COST: $${price.toFixed(2)} (${appState.timeValue} minutes)`;
+  if (appState.players > 1 && appState.currentState === STATE.TIME_SELECT) {
+    priceLabel.textContent = `COST PER PLAYER: $${price.toFixed(2)} (${appState.timeValue} minutes x ${appState.players} players)`;
+  }
   
   // Also update the overlay price
   updateOverlayPrice();
@@ -694,6 +760,14 @@ function setupEventListeners() {
         handleTimeSelectionKeys(event);
         break;
 
+      case STATE.VOUCHER:
+        handleVoucherKeys(event);
+        break;
+
+      case STATE.GAME_ACTIVE:
+        handleGameActiveKeys(event);
+        break;
+
       case STATE.PAYMENT:
         handlePaymentKeys(event);
         break;
@@ -765,15 +839,116 @@ function handleTimeSelectionKeys(event) {
       }
       break;
 
+    case "ArrowUp":
+      if (appState.players < appState.maxSeats) {
+        appState.players++;
+        showPlayers();
+        updatePrice();
+      }
+      break;
+
+    case "ArrowDown":
+      if (appState.players > 1) {
+        appState.players--;
+        showPlayers();
+        updatePrice();
+      }
+      break;
+
+    case "Enter":
+      sendMessage("selectTime", { minutes: appState.timeValue, players: appState.players });
+      break;
+
+    case "Escape":
+      sendMessage("cancel", {});
+      break;
+  }
+}
+
+// Reset and focus the voucher input
+function showVoucherEntry() {
+  const input = document.getElementById("voucher-input");
+  const voucherMessage = document.getElementById("voucher-message");
+  input.value = "";
+  voucherMessage.textContent = "ENTER TO APPLY    ESC TO SKIP";
+  voucherMessage.classList.remove("error");
+  input.focus();
+}
+
+// Handle keyboard input in voucher state
+function handleVoucherKeys(event) {
+  const input = document.getElementById("voucher-input");
+
+  switch (event.key) {
     case "Enter":
-      sendMessage("selectTime", appState.timeValue);
+      if (input.value.trim() === "") {
+        sendMessage("skipVoucher", {});
+      } else {
+        sendMessage("voucher", { code: input.value });
+      }
+      break;
+
+    case "Escape":
+      sendMessage("skipVoucher", {});
       break;
   }
 }
 
-// Handle keyboard input in payment state - browser stays open
+// Handle the server's verdict on a voucher code
+function handleVoucherResult(result) {
+  if (!result.ok) {
+    const voucherMessage = document.getElementById("voucher-message");
+    voucherMessage.textContent = `INVALID CODE: ${result.message.toUpperCase()}`;
+    voucherMessage.classList.add("error");
+    document.getElementById("voucher-input").select();
+    return;
+  }
+
+  appState.voucher = {
+    message: result.message,
+    minutes: result.minutes,
+    price: result.price,
+  };
+}
+
+// Show the effect of the voucher on the payment prompt
+function showVoucherInPayment(statusText) {
+  const applied = document.getElementById("voucher-applied");
+  const paymentText = document.getElementById("payment-text");
+
+  if (!appState.voucher) {
+    applied.classList.add("hidden");
+    paymentText.textContent = "PRESS 'P' TO INSERT COIN";
+    return;
+  }
+
+  applied.classList.remove("hidden");
+  applied.textContent = `VOUCHER: ${appState.voucher.message.toUpperCase()} - ${appState.voucher.minutes} MINUTES FOR $${appState.voucher.price.toFixed(2)}`;
+
+  if (appState.voucher.price === 0) {
+    paymentText.textContent = "PRESS ENTER TO START FREE PLAY";
+    statusText.textContent = "PRESS ENTER TO START GAME";
+  } else {
+    paymentText.textContent = "PRESS 'P' TO INSERT COIN";
+  }
+}
+
+// Handle keyboard input in payment state - browser stays open. On multiplayer
+// cabinets each player inserts their own coin, the voucher only covers player 1.
 function handlePaymentKeys(event) {
-  if (event.key === "p" || event.key === "P") {
+  if (event.key === "Escape") {
+    sendMessage("cancel", {});
+    return;
+  }
+
+  const freePlay = appState.seatsPaid === 0 && appState.voucher && appState.voucher.price === 0;
+  if (event.key === "p" || event.key === "P" || (freePlay && event.key === "Enter")) {
+    appState.seatsPaid++;
+    if (appState.seatsPaid < appState.players) {
+      showSeatPayment();
+      return;
+    }
+
     // Show a loading indicator
     const statusText = document.getElementById("status-text");
     statusText.textContent = "STARTING GAME... PLEASE WAIT";
@@ -782,6 +957,7 @@ function handlePaymentKeys(event) {
     sendMessage("payment", {
       gameName: appState.selectedGameName,
       minutes: appState.timeValue,
+      players: appState.players,
     });
     
     // Update state to game loading
@@ -790,6 +966,130 @@ function handlePaymentKeys(event) {
   }
 }
 
+// Load the cabinet configuration from the API
+function loadCabinet() {
+  fetch("/api/cabinet")
+    .then((response) => response.json())
+    .then((cabinet) => {
+      appState.maxSeats = cabinet.seats;
+      appState.pricePerMinute = cabinet.pricePerMinute;
+      if (cabinet.parentalError) {
+        console.error("Parental controls broken, only showing games for all ages:", cabinet.parentalError);
+      }
+    })
+    .catch((error) => {
+      console.error("Error loading cabinet configuration:", error);
+    });
+}
+
+// Show the number of players on multiplayer cabinets
+function showPlayers() {
+  const playersLabel = document.getElementById("players-label");
+  if (appState.maxSeats <= 1) {
+    playersLabel.classList.add("hidden");
+    return;
+  }
+  playersLabel.classList.remove("hidden");
+  playersLabel.textContent = `▲ ▼ PLAYERS: ${appState.players}`;
+}
+
+// Tell which player should insert a coin
+function showSeatPayment() {
+  if (appState.players <= 1) return;
+
+  const paymentText = document.getElementById("payment-text");
+  const player = appState.seatsPaid + 1;
+  const freePlay = appState.seatsPaid === 0 && appState.voucher && appState.voucher.price === 0;
+  paymentText.textContent = freePlay
+    ? `PLAYER ${player}: PRESS ENTER FOR FREE PLAY`
+    : `PLAYER ${player}: PRESS 'P' TO INSERT COIN`;
+}
+
+// List the seats that ran out of time while the game goes on. Such a player
+// presses their number then 'P' to buy more time.
+function showExpiredSeats() {
+  if (appState.currentState !== STATE.GAME_ACTIVE) return;
+
+  const statusText = document.getElementById("status-text");
+  if (appState.expiredSeats.length === 0) {
+    statusText.textContent = ["GAME IN PROGRESS...", ...deviceStatus()].join(" - ");
+    return;
+  }
+
+  const players = appState.expiredSeats.map((seat) => `PLAYER ${seat + 1}`).join(", ");
+  statusText.textContent = `${players} OUT OF TIME - PRESS YOUR PLAYER NUMBER THEN 'P' TO ADD ${appState.timeValue} MINUTES`;
+}
+
+// Handle keyboard input while the game runs, for seat extensions
+// Keep track of the rumble motors, LEDs and sensors used by the game
+function updateDevice(event) {
+  switch (event.kind) {
+    case "rumble": {
+      const motors = appState.rumble[event.port] || [0, 0];
+      motors[event.id] = event.value;
+      if (motors[0] === 0 && motors[1] === 0) {
+        delete appState.rumble[event.port];
+      } else {
+        appState.rumble[event.port] = motors;
+      }
+      break;
+    }
+
+    case "led":
+      if (event.value === 0) {
+        delete appState.leds[event.id];
+      } else {
+        appState.leds[event.id] = event.value;
+      }
+      break;
+
+    case "sensor":
+      appState.illuminance = event.value < 0 ? null : event.value;
+      break;
+  }
+}
+
+// Describe the devices in use for the status bar
+function deviceStatus() {
+  const status = [];
+  const rumbling = Object.keys(appState.rumble).map((port) => `P${parseInt(port) + 1}`);
+  if (rumbling.length > 0) {
+    status.push(`RUMBLE ${rumbling.join(" ")}`);
+  }
+  const leds = Object.keys(appState.leds).map((led) => parseInt(led) + 1);
+  if (leds.length > 0) {
+    status.push(`LED ${leds.join(" ")} ON`);
+  }
+  if (appState.illuminance !== null) {
+    status.push(`SUN ${Math.round(appState.illuminance)} LUX (+/-)`);
+  }
+  return status;
+}
+
+function handleGameActiveKeys(event) {
+  if (appState.illuminance !== null && (event.key === "+" || event.key === "-")) {
+    const step = event.key === "+" ? 100 : -100;
+    sendMessage("setIlluminance", Math.max(0, appState.illuminance + step));
+    return;
+  }
+
+  if (appState.expiredSeats.length === 0) return;
+
+  const seat = parseInt(event.key) - 1;
+  if (appState.expiredSeats.includes(seat)) {
+    appState.extendSeat = seat;
+    return;
+  }
+
+  if ((event.key === "p" || event.key === "P") && appState.extendSeat !== null) {
+    sendMessage("seatPayment", {
+      seat: appState.extendSeat,
+      minutes: appState.timeValue,
+    });
+    appState.extendSeat = null;
+  }
+}
+
 // Make sure the browser is in fullscreen mode
 function requestFullscreen() {
   const element = document.documentElement;
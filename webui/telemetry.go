@@ -0,0 +1,48 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/libretro/ludo/telemetry"
+)
+
+// handleTelemetry returns the analytics of the last days for the dashboard,
+// globally and day by day
+func (s *Server) handleTelemetry(w http.ResponseWriter, r *http.Request) {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		days = 7
+	}
+
+	events, err := s.session.Telemetry().Events(days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type Day struct {
+		Day     string            `json:"day"`
+		Summary telemetry.Summary `json:"summary"`
+	}
+
+	byDay := map[string][]telemetry.Event{}
+	for _, e := range events {
+		day := e.Time.Format("2006-01-02")
+		byDay[day] = append(byDay[day], e)
+	}
+	daily := []Day{}
+	for d := days - 1; d >= 0; d-- {
+		day := time.Now().AddDate(0, 0, -d).Format("2006-01-02")
+		daily = append(daily, Day{Day: day, Summary: telemetry.Summarize(byDay[day])})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":    days,
+		"summary": telemetry.Summarize(events),
+		"daily":   daily,
+	})
+}
@@ -154,103 +154,83 @@ func (c *Client) readPump() {
 	}
 }
 
-// handleMessage processes incoming messages from clients
+// handleMessage forwards the choices of the players to the session
 func (c *Client) handleMessage(msg Message) {
+	server := c.hub.server
+
 	switch msg.Type {
 	case "selectGame":
-		if c.hub.server.GetState() == StateSelectGame {
-			if gameName, ok := msg.Payload.(string); ok {
-				log.Printf("Game selected: %s", gameName)
-				c.hub.server.SetState(StateTimeSelect)
-			}
+		if gameName, ok := msg.Payload.(string); ok {
+			server.session.SelectGame(gameName)
 		}
 
 	case "selectTime":
-		log.Printf("Received selectTime in state: %v", c.hub.server.GetState())
-		if c.hub.server.GetState() == StateTimeSelect {
-			log.Printf("Transitioning from TimeSelect to Payment")
-			c.hub.server.SetState(StatePayment)
-		} else if c.hub.server.GetState() == StateExtendTime {
-			// When in timeout extension mode, move to payment confirmation step
-			log.Printf("Transitioning from ExtendTime to ExtendPayment")
-			c.hub.server.SetState(StateExtendPayment)
-			// Add a slight delay to ensure state is fully updated
-			time.Sleep(100 * time.Millisecond)
-			log.Printf("State after transition: %v", c.hub.server.GetState())
+		timeData := struct {
+			Minutes int `json:"minutes"`
+			Players int `json:"players"`
+		}{}
+		if err := decodePayload(msg, &timeData); err != nil {
+			log.Printf("Error parsing time selection: %v", err)
+			return
 		}
+		server.session.SelectTime(timeData.Minutes, timeData.Players)
 
-	case "payment":
-		log.Printf("Received payment message in state: %v", c.hub.server.GetState())
-
-		// Parse payload regardless of state
-		paymentData := struct {
-			GameName string `json:"gameName"`
-			Minutes  int    `json:"minutes"`
+	case "voucher":
+		voucherData := struct {
+			Code string `json:"code"`
 		}{}
-
-		payloadBytes, _ := json.Marshal(msg.Payload)
-		if err := json.Unmarshal(payloadBytes, &paymentData); err != nil {
-			log.Printf("Error parsing payment data: %v", err)
+		if err := decodePayload(msg, &voucherData); err != nil {
+			log.Printf("Error parsing voucher data: %v", err)
 			return
 		}
+		server.session.EnterVoucher(voucherData.Code)
 
-		log.Printf("Payment data parsed: Game: %s, Minutes: %d", paymentData.GameName, paymentData.Minutes)
-
-		// Expanded state handling for payment
-		currentState := c.hub.server.GetState()
-		if currentState == StatePayment {
-			// Initial game launch
-			log.Printf("Processing initial game payment")
-			c.hub.server.LaunchGame(paymentData.GameName, paymentData.Minutes)
-		} else if currentState == StateExtendPayment || currentState == StateExtendTime {
-			// Accept payment in either ExtendTime or ExtendPayment state
-			log.Printf("Processing time extension payment in state: %v", currentState)
-
-			// Convert minutes to seconds (multiply by 2 for testing)
-			newDurationSecs := paymentData.Minutes * 2
-
-			// Send signals in the correct order with error handling
-			log.Printf("Sending resume signal...")
-			select {
-			case c.hub.server.resumeChan <- true:
-				log.Println("Resume signal sent successfully")
-			case <-time.After(500 * time.Millisecond):
-				log.Println("Warning: Resume channel is full or blocked")
-			}
-
-			// Small delay to ensure resume signal is processed
-			time.Sleep(100 * time.Millisecond)
-
-			log.Printf("Sending new timer duration: %d seconds", newDurationSecs)
-			select {
-			case c.hub.server.timerChan <- newDurationSecs:
-				log.Println("Timer duration sent successfully")
-			case <-time.After(500 * time.Millisecond):
-				log.Println("Warning: Timer channel is full or blocked")
-			}
+	case "skipVoucher":
+		server.session.EnterVoucher("")
 
-			// Wait a bit more to ensure signals are processed
-			time.Sleep(200 * time.Millisecond)
+	case "cancel":
+		// The player went back to the game list before paying
+		server.session.Cancel()
 
-			// Use wmctrl to maximize the game window
-			log.Printf("Maximizing game window using wmctrl...")
-			c.hub.server.maximizeGame()
+	case "payment":
+		log.Printf("Received payment message in state: %v", server.GetState())
+		if server.GetState() == StatePayment {
+			server.showLoading()
+		}
+		server.session.Pay()
+
+	case "seatPayment":
+		// One player of a multiplayer session buys more time, the others
+		// keep playing meanwhile
+		seatData := struct {
+			Seat    int `json:"seat"`
+			Minutes int `json:"minutes"`
+		}{}
+		if err := decodePayload(msg, &seatData); err != nil {
+			log.Printf("Error parsing seat payment data: %v", err)
+			return
+		}
+		server.session.ExtendSeat(seatData.Seat, seatData.Minutes)
 
-			// Set state to GameActive as the game should be running
-			log.Printf("Setting state to GameActive")
-			c.hub.server.SetState(StateGameActive)
-		} else {
-			log.Printf("Ignoring payment message in unsupported state: %v", currentState)
+	case "setIlluminance":
+		// The solar sensor of the cartridge sees what the player chose
+		if lux, ok := msg.Payload.(float64); ok {
+			server.session.SetIlluminance(float32(lux))
 		}
 
 	case "quit":
 		// Handle player choosing to quit the game
-		if c.hub.server.GetState() == StateExtendTime {
-			log.Println("Player chose to quit game")
-			// Just go back to game selection state
-			c.hub.server.SetState(StateSelectGame)
-		}
+		server.session.Quit()
+	}
+}
+
+// decodePayload unmarshals the payload of a message into v
+func decodePayload(msg Message, v interface{}) error {
+	payloadBytes, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return err
 	}
+	return json.Unmarshal(payloadBytes, v)
 }
 
 // writePump pumps messages from the hub to the websocket connection